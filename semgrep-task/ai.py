@@ -0,0 +1,174 @@
+import os
+import re
+import urllib.error
+
+from catalog import find_rule, fixture_example
+from publishers import request_json
+from triage import code_context
+from autofix import unified_diff
+
+# Masked before anything is sent to the provider when ai.redact is on
+SECRET_PATTERNS = [
+    re.compile(r'-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?(-----END [A-Z ]*PRIVATE KEY-----|$)'),
+    re.compile(r'\b(sk_live|sk_test|rk_live|ghp|gho|ghs|github_pat|xox[abpr])_?[A-Za-z0-9_\-]{8,}'),
+    re.compile(r'\bAKIA[0-9A-Z]{16}\b'),
+    re.compile(r'\beyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+'),  # JWT
+]
+# The value of password = "...", apiKey: '...' and the like, keeping the name
+SECRET_ASSIGNMENT = re.compile(r'(?i)((?:password|passwd|secret|token|api_?key|credential)\w*\s*:?=\s*)(["\'`]?)[^\s"\'`,;]+\2')
+STRING_LITERAL = re.compile(r'"(?:[^"\\\n]|\\.)*"|\'(?:[^\'\\\n]|\\.)*\'|`[^`]*`')
+
+EXPLAIN_PROMPT = ("You explain static analysis findings to developers. Answer in plain English with two short "
+                  "sections: 'Explanation:' (why the code is a problem, in two or three sentences) and "
+                  "'Remediation:' (a sketch of the change, with a small code snippet when it helps).")
+SUGGEST_PROMPT = ("You fix static analysis findings. You get numbered lines of a source file; reply with only those "
+                  "lines rewritten to fix the finding, without line numbers, in one fenced code block. Keep the "
+                  "indentation and change nothing unrelated to the finding.")
+
+
+def redact(text, strings=False):
+    for pattern in SECRET_PATTERNS:
+        text = pattern.sub('[REDACTED]', text)
+    text = SECRET_ASSIGNMENT.sub(r'\1\2[REDACTED]\2', text)
+    return STRING_LITERAL.sub('"[REDACTED]"', text) if strings else text
+
+
+def openai_chat(messages, options):
+    """Chat completion against the OpenAI API or any server speaking it (ai.url, e.g. a vLLM or LiteLLM proxy)."""
+    key = os.environ.get(options.get('api_key_env') or 'OPENAI_API_KEY')
+    url = (options.get('url') or 'https://api.openai.com/v1').rstrip('/') + '/chat/completions'
+    headers = {'Authorization': f"Bearer {key}"} if key else {}
+    res = request_json('POST', url, headers, {'model': options.get('model') or 'gpt-4o-mini',
+                                              'messages': messages, 'temperature': 0})
+    return res['choices'][0]['message']['content']
+
+
+def azure_chat(messages, options):
+    """Azure OpenAI: ai.url is the resource endpoint and ai.model the deployment name."""
+    if not options.get('url') or not options.get('model'):
+        raise ValueError("the azure provider needs ai.url (resource endpoint) and ai.model (deployment)")
+    key = os.environ.get(options.get('api_key_env') or 'AZURE_OPENAI_API_KEY', '')
+    url = (f"{options['url'].rstrip('/')}/openai/deployments/{options['model']}/chat/completions"
+           f"?api-version={options.get('api_version') or '2024-06-01'}")
+    res = request_json('POST', url, {'api-key': key}, {'messages': messages, 'temperature': 0})
+    return res['choices'][0]['message']['content']
+
+
+def ollama_chat(messages, options):
+    """A local Ollama server (default http://localhost:11434); nothing leaves the machine."""
+    url = (options.get('url') or 'http://localhost:11434').rstrip('/') + '/api/chat'
+    res = request_json('POST', url, None, {'model': options.get('model') or 'llama3.1', 'messages': messages,
+                                           'stream': False, 'options': {'temperature': 0}})
+    return res['message']['content']
+
+
+# ai.provider -> callable(messages, options) -> reply text
+PROVIDERS = {
+    'openai': openai_chat,
+    'azure': azure_chat,
+    'ollama': ollama_chat,
+}
+
+
+def split_reply(text):
+    """(explanation, remediation) out of a reply following EXPLAIN_PROMPT's two sections."""
+    parts = re.split(r'(?im)^\W*remediation\W*:\s*', text, maxsplit=1)
+    explanation = re.sub(r'(?i)^\W*explanation\W*:\s*', '', parts[0].strip()).strip()
+    return explanation, parts[1].strip() if len(parts) > 1 else ''
+
+
+def code_block(text):
+    """Lines of the first fenced code block of a reply, or of the whole reply without one."""
+    match = re.search(r'```[^\n]*\n(.*?)```', text, re.DOTALL)
+    return (match.group(1) if match else text).rstrip('\n').splitlines()
+
+
+class Assistant:
+    """Asks the ai.provider about findings: explanations (scan --explain-ai) and candidate patches for
+    findings without an autofix (scan --suggest-fixes).
+
+    Only what the ai config allows leaves the machine: the rule, its rationale and the message, plus
+    ai.context_lines of code around the finding when ai.send_code is on. Secrets are masked first
+    when ai.redact is on, and string literals too when it is "strings". Suggestions are unified diffs
+    stored on the finding; they are never applied, not even by --fix.
+    """
+
+    def __init__(self, options, rules, code_dir, explanations=False, suggestions=False):
+        self.options = options
+        self.rules = rules
+        self.code_dir = code_dir
+        self.chat = PROVIDERS[options['provider']]
+        self.explanations = explanations
+        self.suggestions = suggestions and options['send_code']  # a patch can't be written without the code
+        self.remaining = options['max_findings']
+        self.failed = False
+
+    def wanted(self, finding):
+        categories = self.options['suggest_categories']
+        return not finding.get('Fix') and (not categories or finding.get('Category') in categories)
+
+    def ask(self, system, text):
+        """The provider's reply, or None once the budget is spent or a request failed."""
+        if self.failed or self.remaining is not None and self.remaining <= 0:
+            return None
+        try:
+            reply = self.chat([{'role': 'system', 'content': system}, {'role': 'user', 'content': text}],
+                              self.options)
+        except (urllib.error.URLError, OSError, KeyError, IndexError, TypeError, ValueError) as e:
+            print(f"⚠️ AI requests stopped: {self.options['provider']} request failed ({e})")
+            self.failed = True
+            return None
+        if self.remaining is not None:
+            self.remaining -= 1
+        return reply or ''
+
+    def describe(self, finding):
+        rule = find_rule(self.rules, finding['Rule ID']) or {}
+        example = fixture_example(self.code_dir, rule.get('metadata', {}).get('rule')) or {}
+        lines = [f"Rule: {finding['Rule ID']} ({finding['Severity']}, {finding.get('Category') or 'uncategorized'})"]
+        if example:
+            lines.append(f"Rule rationale: {example['title']}. {example['why']}")
+        lines.append(f"Finding: {finding['Message']}")
+        return lines
+
+    def masked(self, text):
+        return redact(text, strings=self.options['redact'] == 'strings') if self.options['redact'] else text
+
+    def explain(self, finding, base_dir):
+        lines = self.describe(finding)
+        if self.options['send_code']:
+            context = code_context(base_dir, finding, self.options['context_lines'])
+            if context:
+                lines.append(f"Code ({finding['File']}, the finding is on line {finding['Line']}):")
+                lines += [f"{n:>5}{'>' if n == finding['Line'] else ' '} {line}" for n, line in context]
+        reply = self.ask(EXPLAIN_PROMPT, self.masked('\n'.join(lines)))
+        if reply is not None:
+            finding['Explanation'], finding['Remediation'] = split_reply(reply)
+
+    def suggest(self, finding, base_dir, file_path):
+        context = code_context(base_dir, finding, self.options['context_lines'])
+        code = '\n'.join(f"{n:>5}  {line}" for n, line in context)
+        if not context or self.masked(code) != code:
+            return  # the reply would have to restore what was masked
+        text = '\n'.join(self.describe(finding) + [f"Lines {context[0][0]}-{context[-1][0]} of {finding['File']}:",
+                                                   code])
+        reply = self.ask(SUGGEST_PROMPT, self.masked(text))
+        if not reply:
+            return
+        source = file_path.read_bytes()
+        lines = source.decode('utf-8', errors='replace').splitlines(keepends=True)
+        first, last = context[0][0] - 1, context[-1][0]
+        replacement = ''.join(line + '\n' for line in code_block(reply))
+        if not lines[last - 1].endswith('\n'):
+            replacement = replacement[:-1]  # the file has no trailing newline
+        after = ''.join(lines[:first]) + replacement + ''.join(lines[last:])
+        diff = unified_diff(source, after.encode('utf-8'), finding['Path'])
+        if diff:
+            finding['Suggestion'] = diff
+
+    def review(self, findings, base_dir, file_path):
+        for finding in findings:
+            if self.explanations:
+                self.explain(finding, base_dir)
+            if self.suggestions and self.wanted(finding):
+                self.suggest(finding, base_dir, file_path)
@@ -1,9 +1,56 @@
 import os
 import re
+import sys
 import json
+import hashlib
+import functools
+import tempfile
 import subprocess
 import pandas as pd
 from pathlib import Path
+from publishers import PUBLISHERS, repo_root, repo_path, fingerprint, fingerprint_findings
+from notifiers import NOTIFIERS
+from hooks import (install_hook, staged_files, pushed_files, visible_files, staged_snapshot, pushed_snapshot,
+                   head_snapshot, range_ends, HOOK_TEMPLATES)
+from lsp import serve_lsp
+from server import serve_http
+from metrics import METRICS
+from tracing import setup_tracing, span
+from profiling import PROFILER, PROFILE_MODES
+from uploads import upload_reports, git_value
+from formats import FORMATS, render
+from codeowners import CodeOwners
+from gomodules import GoModules
+from blame import annotate
+from autofix import apply_fixes, preview_fixes, ask_hunk, computed_fix, fixes_patch, CLOSE_ERROR_MODES
+from vulncheck import vulnerabilities, vuln_message, SCAN_LEVELS, RULE_ID as VULN_RULE
+from licenses import license_problems, require_line, RULE_ID as LICENSE_RULE
+from cvss import score_findings
+from shellcheck import LEVELS as SHELL_LEVELS
+from frontends import default_frontends
+from coupling import import_graph, coupling_problems, RULES as COUPLING_RULES
+from commitlint import commit_problems, COMMIT_PATH, RULES as COMMIT_RULES
+from secret_scan import scan_secrets, history_secrets, SEVERITIES as SECRET_SEVERITIES
+from triage import load_decisions, apply_suppressions, triage_tui, mark, render_decisions, STATUSES as TRIAGE_STATUSES
+from ai import Assistant, PROVIDERS
+from catalog import (load_rules, builtin_checks, read_rule_file, find_rule, explain, rule_tags, rules_table,
+                     write_docs)
+from scaffold import init_config, PROFILES
+from sbom import build_sbom, SBOM_FORMATS
+from stats import codebase_stats, render_stats, low_maintainability, function_metrics, LANGUAGES, MAINTAINABILITY_RULE
+from deprecated import Deprecations, RULE_ID as DEPRECATED_RULE
+from warmcache import WarmCache
+from coverage import read_profile, risky_functions, RULE_ID as COVERAGE_RULE
+from todos import todo_findings, RULE_ID as TODO_RULE
+from history import (history_file, record_scan, previous_findings, recent_scans, finding_history, render_scans, trend_counts,
+                     render_trends)
+from completion import completion_script, flatten_keys, SHELLS
+from validation import validate_config
+from filters import FilterError, parse_filter
+from gate import (SEVERITY_RANK, GateError, severity_name, report_filter, gate_conditions, evaluate_gate,
+                  load_baseline, classify, fixed_findings)
+from config import (DEFAULT_CONFIG, ConfigTree, find_config, load_config, set_overrides, rule_enabled,
+                    severity_override, is_excluded, is_vendored, anchor_excludes, SYMLINK_POLICIES)
 
 # --- Constants for Header Validation ---
 SUPPORTED_EXTENSIONS = {
@@ -18,20 +65,70 @@ REQUIRED_FIELDS = {
     'modified': r'(?i)(modified\s*by|modified|changes?)\s*:\s*(.+)',
 }
 
+# Like git, a NUL byte in the first 8000 bytes marks a file as binary
+BINARY_SNIFF_BYTES = 8000
+
+# Finding fields used by publishers and the --format outputs only, never written to the Excel report
+INTERNAL_FIELDS = {'Range', 'Fingerprint'}
+
+
+def finding_order(finding):
+    """Sort key that keeps reports identical between runs over the same code."""
+    return (finding['Path'], finding['Line'], finding['Rule ID'], finding['Message'])
+
+
+# Semgrep output is cached per (file content, rule file content) so unchanged files are not re-scanned
+CACHE_DIR = Path(os.environ.get('AUTO_REVIEW_CACHE', Path.home() / '.cache' / 'auto-review'))
+
 class CodeReviewer:
-    def __init__(self, target_path):
+    def __init__(self, target_path, publishers=None, notifiers=None, files=None, excel=True, cache=True,
+                 upload=None, config=None, fix=None, suppress=True,
+                 report=None, max_findings=None, keep=None, previous=None, assistant=None, vulncheck=False,
+                 triage_store=None, warm=None, origin=None):
         self.script_dir = Path(__file__).parent.resolve()
         self.rules_dir = self.script_dir / "rules"
         self.common_rules = self.rules_dir / "common-rules.yml"
         self.target_path = Path(target_path).resolve()
+        self.base_dir = self.target_path if self.target_path.is_dir() else self.target_path.parent
+        self.publishers = publishers or []
+        self.notifiers = notifiers or []
+        self.files = files
+        self.excel = excel
+        self.cache = cache
+        self.upload = upload
+        self.reports = []
+        self.owners = CodeOwners.load(repo_root(str(self.base_dir)))
+        self.config = config or load_config(find_config(self.target_path))
+        self.configs = ConfigTree(self.config, self.base_dir)
+        self.fix = fix  # None, 'apply', 'dry-run' (collect diffs) or 'interactive' (ask per edit)
+        self.diffs = []
+        self.report = report  # predicate for the findings to report (--severity, --only-rules...)
+        self.hidden = []      # findings left out of the reports, still seen by the quality gate
+        self.keep = keep      # predicate dropping findings from reports and gate alike (--filter)
+        self.previous = previous  # fingerprint -> finding of the baseline or last recorded scan, for Status
+        self.max_findings = max_findings  # per rule, the rest is only counted
+        self.vulncheck = vulncheck  # also run govulncheck on the discovered Go modules
+        self.assistant = assistant  # ai.Assistant adding LLM explanations and fix suggestions (--explain-ai...)
+        self.rule_counts = {}
+        self.skipped = []  # (path, reason) of discovered files that were not scanned
+        self.explain_exclusions = False  # also list excluded and ignored paths in skipped (scan --dry-run)
+        self.rule_catalog = None  # rules with their metadata, loaded when a finding needs it (CVSS)
+        self.modules = GoModules({})  # discovered with the files, findings are tagged with their Go module
+        self.coverage = {}  # Go file -> blocks of the coverage.profile, read once the modules are known
+        self.deprecations = None  # deprecated symbols of the imported packages, read as files need them
+        self.warm = warm  # WarmCache of serve --daemon, shared by its scans
+        self.origin = origin  # the document a temporary copy of an editor buffer (the one file scanned) holds
+        # fingerprint -> triage decision, from the suppressions file and the history store
+        self.suppressions = load_decisions(self.config, self.base_dir, triage_store) if suppress else {}
+        self.triaged = {}  # status -> findings left out by their triage decision
         
         self.results = []
-        self.rule_map = {
-            '.js': self.rules_dir / 'javascript-rules.yml',
-            '.py': self.rules_dir / 'python-rules.yml',
-            '.go': self.rules_dir / 'go-rules.yml',
-            '.java': self.rules_dir / 'java-rules.yml'
-        }
+        self.all_results = []
+        self.frontends = default_frontends()  # what files are reviewed and how, by language or file kind
+
+    def relative_path(self, file_path):
+        """Path of the file relative to the scanned folder."""
+        return file_path.relative_to(self.base_dir).as_posix()
 
     def extract_header(self, file_path):
         """Extracts the first 50 lines to check for header fields."""
@@ -58,44 +155,268 @@ class CodeReviewer:
             self.results.append({
                 "Timestamp": pd.Timestamp.now().strftime('%Y-%m-%d %H:%M:%S'),
                 "File": file_path.name,
+                "Path": self.relative_path(file_path),
                 "Line": 1,
                 "Rule ID": "HEADER-CHECK",
                 "Severity": "ERROR",
+                "Category": "documentation",
                 "Message": f"Missing mandatory fields: {', '.join(missing_fields)}"
             })
 
-    def review_file(self, file_path):
-        """Runs Semgrep security scans on the file."""
-        specific_rule = self.rule_map.get(file_path.suffix)
-        active_rules = [r for r in [specific_rule, self.common_rules] if r and r.exists()]
+    def check_secrets(self, file_path):
+        """Known credential formats and high-entropy string literals (the secrets section of the config)."""
+        options = self.configs.for_path(file_path)['secrets']
+        if not options['enabled']:
+            return
+        text = file_path.read_text(encoding='utf-8', errors='ignore')
+        for line, rule_id, severity, message in scan_secrets(text, options):
+            self.results.append({
+                "Timestamp": pd.Timestamp.now().strftime('%Y-%m-%d %H:%M:%S'),
+                "File": file_path.name,
+                "Path": self.relative_path(file_path),
+                "Line": line,
+                "Rule ID": rule_id,
+                "Severity": severity,
+                "Category": "security",
+                "Message": message
+            })
+
+    def check_maintainability(self, file_path):
+        """Functions whose maintainability index is below the config's metrics.min_maintainability."""
+        minimum = self.configs.for_path(file_path)['metrics']['min_maintainability']
+        if not minimum or file_path.suffix not in LANGUAGES:
+            return
+        text = file_path.read_text(encoding='utf-8', errors='ignore')
+        for line, message, complexity in low_maintainability(text, LANGUAGES[file_path.suffix], minimum):
+            self.results.append({
+                "Timestamp": pd.Timestamp.now().strftime('%Y-%m-%d %H:%M:%S'),
+                "File": file_path.name,
+                "Path": self.relative_path(file_path),
+                "Line": line,
+                "Rule ID": MAINTAINABILITY_RULE,
+                "Severity": "WARNING",
+                "Category": "code-quality",
+                "Message": message,
+                "Complexity": complexity
+            })
+
+    def check_coverage(self, file_path):
+        """Go functions of the coverage profile whose CRAP score, complexity against missing tests, is above
+        coverage.max_crap."""
+        maximum = self.configs.for_path(file_path)['coverage']['max_crap']
+        blocks = self.coverage.get(file_path.resolve())
+        if maximum is None or not blocks or file_path.suffix != '.go':
+            return
+        functions = function_metrics(file_path.read_text(encoding='utf-8', errors='ignore'), 'go')
+        for f in risky_functions(functions, blocks):
+            if f['crap'] <= maximum:
+                break
+            self.results.append({
+                "Timestamp": pd.Timestamp.now().strftime('%Y-%m-%d %H:%M:%S'),
+                "File": file_path.name,
+                "Path": self.relative_path(file_path),
+                "Line": f['line'],
+                "Rule ID": COVERAGE_RULE,
+                "Severity": "WARNING",
+                "Category": "reliability",
+                "Message": f"{f['name']} has a CRAP score of {f['crap']}: complexity {f['complexity']} with "
+                           f"{f['coverage']}% of its statements run by tests, above {maximum}. Add tests "
+                           f"before changing it",
+                "Complexity": f['complexity']
+            })
+
+    def check_deprecated(self, file_path):
+        """Uses of packages and symbols whose doc comment says "Deprecated:", with the suggested replacement."""
+        if self.deprecations is None or file_path.suffix != '.go' or \
+                not self.configs.for_path(file_path)['deprecations']['enabled']:
+            return
+        text = file_path.read_text(encoding='utf-8', errors='ignore')
+        for line, message in self.deprecations.uses(file_path, text):
+            self.results.append({
+                "Timestamp": pd.Timestamp.now().strftime('%Y-%m-%d %H:%M:%S'),
+                "File": file_path.name,
+                "Path": self.relative_path(file_path),
+                "Line": line,
+                "Rule ID": DEPRECATED_RULE,
+                "Severity": "WARNING",
+                "Category": "best-practice",
+                "Message": message
+            })
+
+    def check_todos(self, file_path):
+        """TODO, FIXME and HACK comments with their age, escalated when stale or not tracked in an issue."""
+        options = self.configs.for_path(file_path)['todos']
+        if not options['enabled']:
+            return
+        text = file_path.read_text(encoding='utf-8', errors='ignore')
+        for line, severity, message, fields in todo_findings(file_path, text, options):
+            self.results.append({
+                "Timestamp": pd.Timestamp.now().strftime('%Y-%m-%d %H:%M:%S'),
+                "File": file_path.name,
+                "Path": self.relative_path(file_path),
+                "Line": line,
+                "Rule ID": TODO_RULE,
+                "Severity": severity,
+                "Category": "code-quality",
+                "Message": message,
+                **fields,
+            })
+
+    def check_history_secrets(self):
+        """Secrets added by the last secrets.history_depth commits and removed since, by the commit that added
+        them, grouped by path; the working-tree scan reports the ones still there."""
+        def options_of(path):
+            file_path = self.base_dir / path
+            options = self.configs.for_path(file_path)['secrets']
+            return options if options['enabled'] and not self.excluded(file_path) else None
 
-        for rule_file in active_rules:
-            print(f"Scanning: {file_path.name} with {rule_file.name}")
-            cmd = ["semgrep", "--config", str(rule_file), "--json", str(file_path)]
-            res = subprocess.run(cmd, capture_output=True, text=True, encoding='utf-8')
+        def still_present(path, text):
+            file_path = self.base_dir / path
+            if not file_path.is_file():
+                return False
+            lines = file_path.read_text(encoding='utf-8', errors='ignore').splitlines()
+            return text.strip() in (line.strip() for line in lines)
+
+        depth = self.config['secrets']['history_depth']
+        found = history_secrets(self.base_dir, depth, options_of, still_present)
+        if found is None:
+            print(f"⚠️ Git history not scanned for secrets: {self.base_dir} is not a git repository")
+            return {}
+        by_file = {}
+        for sha, author, date, path, line, rule_id, severity, message in found:
+            by_file.setdefault(self.base_dir / path, []).append({
+                "Timestamp": pd.Timestamp.now().strftime('%Y-%m-%d %H:%M:%S'),
+                "File": Path(path).name,
+                "Path": path,
+                "Line": line,
+                "Rule ID": rule_id,
+                "Severity": severity,
+                "Category": "security",
+                "Message": message,
+                "Author": author,
+                "Commit": sha[:12],
+                "Introduced": date,
+            })
+        return by_file
+
+    def analyze(self, file_path, frontend):
+        """The checks the file's frontend does itself, without Semgrep (Go import policies, manifests...)."""
+        text = file_path.read_text(encoding='utf-8', errors='ignore')
+        config = self.configs.for_path(file_path)
+        for line, rule_id, severity, category, message, extra in frontend.analyze(file_path, text, config,
+                                                                                   self.base_dir):
+            self.results.append({
+                "Timestamp": pd.Timestamp.now().strftime('%Y-%m-%d %H:%M:%S'),
+                "File": file_path.name,
+                "Path": self.relative_path(file_path),
+                "Line": line,
+                "Rule ID": rule_id,
+                "Severity": severity,
+                "Category": category,
+                "Message": message,
+                **extra,
+            })
+
+    def run_semgrep(self, file_path, rule_file):
+        """Returns Semgrep's JSON output for one file and rule file, served from the cache when possible."""
+        if self.warm and self.cache:
+            return self.warm.semgrep_output(file_path, rule_file, lambda: self.cached_semgrep(file_path, rule_file),
+                                            self.origin)
+        return self.cached_semgrep(file_path, rule_file)
+
+    def cached_semgrep(self, file_path, rule_file):
+        """Semgrep's output from the on-disk cache, keyed by the file and rule file contents, or a new run."""
+        key = hashlib.sha256(file_path.read_bytes() + b'\0' + rule_file.read_bytes()).hexdigest()
+        cache_file = CACHE_DIR / f"{key}.json"
+        if self.cache and cache_file.exists():
+            METRICS.inc('auto_review_cache_requests_total', result='hit')
+            print(f"Cached: {file_path.name} with {rule_file.name}")
+            return cache_file.read_text(encoding='utf-8')
+        if self.cache:
+            METRICS.inc('auto_review_cache_requests_total', result='miss')
+
+        print(f"Scanning: {file_path.name} with {rule_file.name}")
+        cmd = ["semgrep", "--config", str(rule_file), "--json", str(file_path)]
+        res = subprocess.run(cmd, capture_output=True, text=True, encoding='utf-8')
+        if self.cache and res.returncode in (0, 1) and res.stdout:
+            CACHE_DIR.mkdir(parents=True, exist_ok=True)
+            cache_file.write_text(res.stdout, encoding='utf-8')
+        return res.stdout
+
+    def frontend_of(self, file_path):
+        """The frontend reviewing the file, None for files the tool does not review."""
+        config = self.configs.for_path(file_path)
+        return next((f for f in self.frontends if f.handles(file_path, config, self.base_dir)), None)
+
+    def rule_files(self, file_path, frontend):
+        """Semgrep rule files run on the file: its frontend's, the common ones and the config's custom ones."""
+        specific_rule = self.rules_dir / frontend.rules_file if frontend.rules_file else None
+        common_rules = self.common_rules if frontend.common else None
+        custom_rules = [Path(r) for r in self.configs.for_path(file_path)['rules']['custom']]
+        return [r for r in [specific_rule, common_rules] + custom_rules if r and r.exists()]
+
+    def review_file(self, file_path, frontend):
+        """Runs Semgrep security scans on the file."""
+        for rule_file in self.rule_files(file_path, frontend):
+            with span('analyzer', rules=rule_file.name, file=self.relative_path(file_path)):
+                output = self.run_semgrep(file_path, rule_file)
             
-            if res.stdout:
+            if output:
                 try:
-                    data = json.loads(res.stdout)
+                    data = json.loads(output)
                     for finding in data.get('results', []):
-                        self.results.append({
+                        entry = {
                             "Timestamp": pd.Timestamp.now().strftime('%Y-%m-%d %H:%M:%S'),
                             "File": file_path.name,
+                            "Path": self.relative_path(file_path),
                             "Line": finding['start']['line'],
                             "Rule ID": finding['check_id'],
                             "Severity": finding['extra']['severity'],
-                            "Message": finding['extra']['message']
-                        })
+                            "Category": finding['extra'].get('metadata', {}).get('category', ''),
+                            "Message": finding['extra']['message'],
+                            "Fix": finding['extra'].get('fix', ''),
+                            "Range": {'start': finding['start'], 'end': finding['end']}
+                        }
+                        if finding['extra'].get('metadata', {}).get('example'):
+                            entry['Example'] = finding['extra']['metadata']['example'].strip('\n')
+                        if not entry['Fix']:
+                            entry['Fix'] = computed_fix(file_path.read_bytes(), entry,
+                                                        self.configs.for_path(file_path)['autofix'])
+                        self.results.append(entry)
                 except Exception:
                     continue
 
+    def fix_file(self, file_path):
+        """Applies the suggested fixes in place; fixed findings are no longer reported."""
+        if self.fix == 'dry-run':
+            self.diffs.append(preview_fixes(file_path, self.results, self.relative_path(file_path)))
+            return
+        applied = apply_fixes(file_path, self.results, ask_hunk if self.fix == 'interactive' else None)
+        if applied:
+            print(f"🔧 Fixed {len(applied)} finding(s) in {self.relative_path(file_path)}")
+            self.results = [f for f in self.results if all(f is not a for a in applied)]
+
+    def cap_findings(self):
+        """Keeps the first max_findings findings of each rule for the reports."""
+        kept = []
+        for finding in self.results:
+            seen = self.rule_counts.get(finding['Rule ID'], 0)
+            self.rule_counts[finding['Rule ID']] = seen + 1
+            (kept if seen < self.max_findings else self.hidden).append(finding)
+        self.results = kept
+
+    def overflow(self):
+        """rule id -> findings not reported because of max_findings"""
+        return {rule: count - self.max_findings for rule, count in sorted(self.rule_counts.items())
+                if count > self.max_findings}
+
     def export_file_report(self, file_path):
         """Saves findings to Excel, keeping only the latest timestamp and forcing column order."""
         if not self.results:
             return
         
         report_name = f"{file_path.stem}.xlsx"
-        new_df = pd.DataFrame(self.results)
+        new_df = pd.DataFrame([{k: v for k, v in r.items() if k not in INTERNAL_FIELDS} for r in self.results])
 
         if os.path.exists(report_name):
             existing_df = pd.read_excel(report_name)
@@ -113,24 +434,989 @@ class CodeReviewer:
         final_df = final_df[cols]
         
         final_df.to_excel(report_name, index=False)
+        if report_name not in self.reports:
+            self.reports.append(report_name)
+
+    def discover_files(self):
+        """Yields the files to review, either the explicit list (e.g. staged files) or a full walk."""
+        for file_path in self.candidate_files():
+            reason = self.skip_reason(file_path)
+            if reason:
+                self.skipped.append((self.relative_path(file_path), reason))
+            else:
+                yield file_path
+
+    def candidate_files(self):
+        if self.files is not None:
+            yield from (f for f in self.files if self.frontend_of(f) and not self.excluded(f))
+            return
+        visible = visible_files(self.base_dir) if self.config['gitignore'] else None
+        visible_dirs = {d for f in visible for d in f.parents} if visible is not None else None
+        policy = self.config['symlinks']
+        walked, linked = {self.target_path}, []  # real folders walked so far; files reached through a symlink
+        seen = set()
+        for root, dirs, files in os.walk(self.target_path, followlinks=policy != 'skip'):
+            # Prune ignored folders (build output, node_modules...) instead of walking into them
+            kept = []
+            for d in dirs:
+                folder = Path(root) / d
+                if self.excluded(folder) or not self.follow_link(folder, policy):
+                    continue
+                real = folder.resolve()
+                if folder.is_symlink() and (real in walked or real in folder.parents):
+                    self.skipped.append((self.relative_path(folder), "symlink loop or already scanned folder"))
+                elif visible_dirs is None or real in visible_dirs or not self.inside(real):
+                    walked.add(real)
+                    kept.append(d)
+                elif self.explain_exclusions and d != '.git':
+                    self.skipped.append((self.relative_path(folder), "ignored by git"))
+            dirs[:] = sorted(kept)
+            for file in sorted(files):
+                file_path = Path(root) / file
+                if not self.frontend_of(file_path) or self.excluded(file_path):
+                    continue
+                if not self.follow_link(file_path, policy):
+                    continue
+                real = file_path.resolve()
+                if visible is not None and real not in visible and self.inside(real):
+                    if self.explain_exclusions:
+                        self.skipped.append((self.relative_path(file_path), "ignored by git"))
+                    continue
+                if real != file_path:
+                    linked.append(file_path)
+                elif real not in seen:
+                    seen.add(real)
+                    yield file_path
+        # A file reached both directly and through a symlink is reported at its real path
+        for file_path in linked:
+            if file_path.resolve() not in seen:
+                seen.add(file_path.resolve())
+                yield file_path
+
+    def inside(self, real_path):
+        try:
+            real_path.relative_to(self.base_dir)
+            return True
+        except ValueError:
+            return False
+
+    def follow_link(self, path, policy):
+        """Whether a walked path is scanned under the symlinks policy; plain files and folders always are."""
+        if not path.is_symlink():
+            return True
+        if policy == 'skip':
+            reason = "symlink (symlinks: skip)"
+        elif not path.exists():
+            reason = "broken symlink"
+        elif policy == 'root' and not self.inside(path.resolve()):
+            reason = "symlink outside the scanned folder (symlinks: root)"
+        else:
+            return True
+        self.skipped.append((self.relative_path(path), reason))
+        return False
+
+    def skip_reason(self, file_path):
+        """Why a discovered file is not scanned (too large, binary), or None."""
+        limit = self.configs.for_path(file_path)['max_file_size']
+        size = file_path.stat().st_size
+        if limit and size > limit:
+            return f"larger than {limit} bytes ({size} bytes)"
+        with open(file_path, 'rb') as f:
+            if b'\0' in f.read(BINARY_SNIFF_BYTES):
+                return "binary content"
+        return None
+
+    def excluded(self, path):
+        config = self.configs.for_path(path)
+        reason = "excluded by config" if is_excluded(config, path) else \
+            "vendored dependency" if not config['vendor'] and is_vendored(path, self.base_dir) else None
+        if reason and self.explain_exclusions:
+            self.skipped.append((self.relative_path(path), reason))
+        return reason is not None
+
+    def plan(self):
+        """What a scan would do, without running Semgrep: per file, the config and the rules that apply."""
+        self.explain_exclusions = True
+        rules_of = {}
+        entries = []
+        for file_path in self.discover_files():
+            config = self.configs.for_path(file_path)
+            rules = [('HEADER-CHECK', 'ERROR')] if file_path.suffix in SUPPORTED_EXTENSIONS else []
+            if config['secrets']['enabled']:
+                rules += list(SECRET_SEVERITIES.items())
+            if config['metrics']['min_maintainability'] and file_path.suffix in LANGUAGES:
+                rules.append((MAINTAINABILITY_RULE, 'WARNING'))
+            if config['deprecations']['enabled'] and file_path.suffix == '.go':
+                rules.append((DEPRECATED_RULE, 'WARNING'))
+            if config['todos']['enabled']:
+                rules.append((TODO_RULE, 'INFO'))
+            frontend = self.frontend_of(file_path)
+            rules += frontend.native_rules(file_path, config, self.base_dir)
+            for rule_file in self.rule_files(file_path, frontend):
+                if rule_file not in rules_of:
+                    rules_of[rule_file] = read_rule_file(rule_file, 'custom')
+                rules += [(r['id'], r['severity']) for r in rules_of[rule_file]]
+            active, filtered, disabled = [], [], []
+            for rule_id, severity in rules:
+                if not rule_enabled(config, rule_id):
+                    disabled.append(rule_id)
+                    continue
+                severity = severity_override(config, rule_id, severity)
+                reported = not self.report or self.report({'Rule ID': rule_id, 'Severity': severity})
+                (active if reported else filtered).append((rule_id, severity))
+            entries.append({'path': self.relative_path(file_path), 'config': config['layers'], 'rules': active,
+                            'gate_only': filtered, 'disabled': disabled})
+        return entries
+
+    def apply_config(self, file_path):
+        """Drops disabled rules and applies severity overrides from the file's folder config."""
+        config = self.configs.for_path(file_path)
+        kept = []
+        for finding in self.results:
+            if rule_enabled(config, finding['Rule ID']):
+                finding['Severity'] = severity_override(config, finding['Rule ID'], finding['Severity'])
+                kept.append(finding)
+        self.results = kept
+
+    def review_files(self):
+        """Yields each file's findings as soon as that file has been reviewed."""
+        with span('discover_files', path=str(self.target_path)):
+            files = list(self.discover_files())
+            self.modules = GoModules.discover(self.base_dir, self.excluded)
+        if len(self.modules.modules) > 1:
+            print(f"📦 {len(self.modules.modules)} Go modules: {', '.join(sorted(self.modules.modules.values()))}")
+        profile = self.config['coverage']['profile']
+        if profile and Path(profile).is_file():
+            self.coverage = read_profile(profile, self.modules)
+        elif profile:
+            print(f"⚠️ Coverage profile {profile} not found, no COVERAGE-RISK findings")
+        if self.config['deprecations']['enabled']:
+            self.deprecations = Deprecations(self.modules, self.config['deprecations']['command'],
+                                             *((self.warm.packages, self.warm.environments) if self.warm else ()))
+        for file_path in files:
+            self.results = [] # Reset for each file's individual report
+            frontend = self.frontend_of(file_path)
+            with span('review_file', file=self.relative_path(file_path), frontend=frontend.name):
+                self.check_header(file_path)
+                self.check_secrets(file_path)
+                self.check_maintainability(file_path)
+                self.check_coverage(file_path)
+                self.check_deprecated(file_path)
+                self.check_todos(file_path)
+                self.analyze(file_path, frontend)
+                self.review_file(file_path, frontend)
+            yield self.finish(file_path)
+        # Checks of the Go modules rather than of single files
+        by_file = {}
+        if self.vulncheck:
+            with span('vulncheck', modules=len(self.modules.modules)):
+                by_file = self.check_vulns()
+        if self.config['licenses']['enabled']:
+            with span('licenses', modules=len(self.modules.modules)):
+                for file_path, findings in self.check_licenses().items():
+                    by_file.setdefault(file_path, []).extend(findings)
+        if self.config['metrics']['coupling']:
+            with span('coupling', modules=len(self.modules.modules)):
+                for file_path, findings in self.check_coupling(files).items():
+                    by_file.setdefault(file_path, []).extend(findings)
+        for file_path, findings in sorted(by_file.items()):
+            self.results = findings
+            yield self.finish(file_path, fixable=False)
+        if self.config['secrets']['history_depth']:
+            with span('history_secrets', depth=self.config['secrets']['history_depth']):
+                by_file = self.check_history_secrets()
+            for file_path, findings in sorted(by_file.items()):
+                self.results = findings
+                yield self.finish(file_path, fixable=False, blame=False)
+        if self.config['commits']['enabled']:
+            with span('commits', range=self.config['commits']['range']):
+                self.results = self.check_commits()
+            yield self.finish(self.base_dir / COMMIT_PATH, fixable=False, blame=False)
+
+    def finish(self, file_path, fixable=True, blame=True):
+        """Config, suppressions, fixes, annotations and report filters for one file's findings."""
+        self.apply_config(file_path)
+        if self.results:
+            try:
+                lines = file_path.read_text(encoding='utf-8', errors='ignore').splitlines()
+            except OSError:
+                lines = []  # commit messages, deleted files
+            fingerprint_findings(self.results, lines)
+        if self.config['cvss']['enabled'] and any(f.get('Category') == 'security' for f in self.results):
+            if self.rule_catalog is None:
+                custom = self.config['rules']['custom']
+                self.rule_catalog = self.warm.catalog(self.rules_dir, custom, load_rules) if self.warm else \
+                    load_rules(self.rules_dir, custom)
+            score_findings(self.results, self.rule_catalog, self.configs.for_path(file_path)['cvss'])
+        self.results = apply_suppressions(self.results, self.suppressions, self.triaged)
+        if self.fix and fixable:
+            self.fix_file(file_path)
+        if self.owners.rules:
+            for finding in self.results:
+                finding['Owner'] = ' '.join(self.owners.owners_of(repo_path(self.base_dir, finding)))
+        if self.results and self.config['blame'] and blame:
+            with span('blame', file=self.results[0]['Path']):
+                annotate(self.results, file_path)
+        if self.modules.modules:
+            module = self.modules.module_of(file_path) or ''
+            for finding in self.results:
+                finding['Module'] = module
+        if self.previous is not None:
+            classify(self.results, self.previous)
+        if self.keep:
+            self.results = [f for f in self.results if self.keep(f)]
+        if self.report:
+            self.hidden += [f for f in self.results if not self.report(f)]
+            self.results = [f for f in self.results if self.report(f)]
+        if self.max_findings:
+            self.cap_findings()
+        self.results.sort(key=finding_order)
+        if self.assistant and self.results:
+            with span('ai', file=self.results[0]['Path']):
+                self.assistant.review(self.results, self.base_dir, file_path)
+        if self.excel:
+            self.export_file_report(file_path)
+        return self.results
+
+    def check_vulns(self):
+        """govulncheck findings of every discovered Go module, by the file they are reported in: the call
+        site in this module for reachable symbols, else the module's go.mod."""
+        options = self.config['vulncheck']
+        by_file = {}
+        # When scanning a subfolder of a module, govulncheck runs from the scanned folder
+        for folder in sorted({f if self.inside(f) else self.base_dir for f in self.modules.modules}):
+            entries = vulnerabilities(folder, options)
+            if entries is None:
+                print(f"⚠️ Vulnerability check skipped: {options['command']} not found or unreadable output")
+                return by_file
+            for entry in entries:
+                file_path = Path(entry['file']).resolve() if entry['file'] else folder / 'go.mod'
+                if not self.inside(file_path):
+                    continue
+                by_file.setdefault(file_path, []).append({
+                    "Timestamp": pd.Timestamp.now().strftime('%Y-%m-%d %H:%M:%S'),
+                    "File": file_path.name,
+                    "Path": self.relative_path(file_path),
+                    "Line": entry['line'] if entry['file'] else 1,
+                    "Rule ID": VULN_RULE,
+                    "Severity": SCAN_LEVELS[entry['level']],
+                    "Category": "security",
+                    "Message": vuln_message(entry),
+                    **({"CVSS Vector": entry['cvss']} if entry['cvss'] else {}),
+                })
+        return by_file
+
+    def check_licenses(self):
+        """Dependencies of each Go module under the scanned folder whose license is not allowed, at its go.mod."""
+        by_file = {}
+        for folder in sorted(f for f in self.modules.modules if self.inside(f)):
+            go_mod = folder / 'go.mod'
+            options = self.configs.for_path(go_mod)['licenses']
+            for path, severity, message in license_problems(folder, options):
+                by_file.setdefault(go_mod, []).append({
+                    "Timestamp": pd.Timestamp.now().strftime('%Y-%m-%d %H:%M:%S'),
+                    "File": go_mod.name,
+                    "Path": self.relative_path(go_mod),
+                    "Line": require_line(go_mod, path),
+                    "Rule ID": LICENSE_RULE,
+                    "Severity": severity,
+                    "Category": "compliance",
+                    "Message": message,
+                })
+        return by_file
+
+    def check_coupling(self, files):
+        """Import cycles and unstable packages in the import graph of the scanned Go files, at the imports."""
+        by_file = {}
+        graph = import_graph(files, self.modules)
+        for file_path, line, rule_id, message in coupling_problems(graph, self.config['metrics']['max_instability']):
+            severity, category = COUPLING_RULES[rule_id]
+            by_file.setdefault(file_path, []).append({
+                "Timestamp": pd.Timestamp.now().strftime('%Y-%m-%d %H:%M:%S'),
+                "File": file_path.name,
+                "Path": self.relative_path(file_path),
+                "Line": line,
+                "Rule ID": rule_id,
+                "Severity": severity,
+                "Category": category,
+                "Message": message,
+            })
+        return by_file
+
+    def check_commits(self):
+        """Commit message findings for the commits of commits.range (or --push-range), by the commit's author."""
+        options = self.config['commits']
+        problems = commit_problems(self.base_dir, options)
+        if problems is None:
+            print(f"⚠️ Commit messages not linted: can't read the commit range {options['range']}")
+            return []
+        findings = []
+        for sha, author, line, rule_id, message in problems:
+            severity, category = COMMIT_RULES[rule_id]
+            findings.append({
+                "Timestamp": pd.Timestamp.now().strftime('%Y-%m-%d %H:%M:%S'),
+                "File": COMMIT_PATH,
+                "Path": COMMIT_PATH,
+                "Line": line,
+                "Rule ID": rule_id,
+                "Severity": severity,
+                "Category": category,
+                "Message": message,
+                "Author": author,
+                "Commit": sha[:12],
+            })
+        return findings
 
     def run(self):
         if not self.target_path.exists():
             print(f"Error: Path {self.target_path} not found.")
-            return
+            return []
 
-        for root, _, files in os.walk(self.target_path):
-            for file in files:
-                file_path = Path(root) / file
-                if file_path.suffix in self.rule_map:
-                    self.results = [] # Reset for each file's individual report
-                    self.check_header(file_path)
-                    self.review_file(file_path)
-                    self.export_file_report(file_path)
+        with span('scan', path=str(self.target_path)):
+            for results in self.review_files():
+                self.all_results.extend(results)
+            self.all_results.sort(key=finding_order)
+            if self.skipped:
+                print(f"⏭️ Skipped {len(self.skipped)} file(s):")
+                for path, reason in self.skipped:
+                    print(f"   {path}: {reason}")
+            if self.triaged:
+                print(f"🗂️ Left out by triage: "
+                      + ', '.join(f"{count} {status}" for status, count in sorted(self.triaged.items())))
+            for rule, count in (self.overflow() if self.max_findings else {}).items():
+                print(f"⚠️ {count} more finding(s) of {rule} not reported (--max-findings {self.max_findings})")
+        return self.all_results
+
+    def publish(self, failed):
+        """Sends the findings to the publishers and notifiers, with the failed conditions of the quality gate
+        so they show the same result as the exit code. Publishers only resolve the threads and issues of
+        findings that are gone when the scan saw every finding: not after a scan of some files (--staged,
+        --push-range), --filter, a report filter or --max-findings left some out."""
+        resolve = self.files is None and not self.keep and not self.hidden
+        if self.publishers and not resolve:
+            print("⚠️ Partial or filtered scan: publishers won't resolve the findings missing from it")
+        for name in self.publishers:
+            with span(f'publish.{name}', findings=len(self.all_results)):
+                PUBLISHERS[name](self.all_results, self.base_dir, failed, resolve)
+        for name in self.notifiers:
+            with span(f'notify.{name}', findings=len(self.all_results)):
+                NOTIFIERS[name](self.all_results, self.base_dir, failed)
+
+
+def review_single_file(path, origin=None, warm=None):
+    """Reviews one file without reports or integrations, as used by the server modes. origin is where the file
+    really is when path is a temporary copy of an editor buffer: its .codereview.yaml files apply, nested ones
+    included, and nothing is reported when they exclude it."""
+    path, origin = Path(path), Path(origin or path).resolve()
+    config = ConfigTree(load_config(find_config(origin)), origin.parent).for_path(origin)
+    if is_excluded(config, origin):
+        return []
+    return CodeReviewer(path, files=[path], excel=False, warm=warm, config=config,
+                        origin=origin if origin != path.resolve() else None).run()
+
+
+def plan_text(entries, skipped):
+    lines = [f"🔎 Dry run: {len(entries)} file(s) would be analyzed"]
+    for entry in entries:
+        lines.append(entry['path'])
+        lines.append(f"   config: {', '.join(entry['config']) or 'defaults'}")
+        lines.append(f"   rules ({len(entry['rules'])}): "
+                     + ', '.join(f"{rule_id} [{severity}]" for rule_id, severity in entry['rules']))
+        if entry['disabled']:
+            lines.append(f"   disabled by config ({len(entry['disabled'])}): {', '.join(entry['disabled'])}")
+        if entry['gate_only']:
+            lines.append(f"   filtered from reports, still gated ({len(entry['gate_only'])}): "
+                         + ', '.join(rule_id for rule_id, _ in entry['gate_only']))
+    if skipped:
+        lines.append(f"⏭️ Not analyzed ({len(skipped)}):")
+        lines += [f"   {path}: {reason}" for path, reason in skipped]
+    return '\n'.join(lines)
+
+
+def review_snippet(source, filename, config=None, cache=True):
+    """Reviews a buffer that is not on disk (stdin, chat-ops); findings carry the given filename as Path."""
+    relative = Path(filename) if not Path(filename).is_absolute() else Path(Path(filename).name)
+    with tempfile.TemporaryDirectory() as tmp:
+        copy = Path(tmp) / relative
+        copy.parent.mkdir(parents=True, exist_ok=True)
+        copy.write_text(source, encoding='utf-8')
+        return CodeReviewer(tmp, files=[copy], excel=False, cache=cache, config=config).run()
+
+
+def committed_findings(path, files, config, revision='HEAD'):
+    """fingerprint -> finding of the files (relative to path) at HEAD or revision, what a --staged or
+    --push-range scan compares with."""
+    with head_snapshot(path, revision) as target:
+        if target is None:
+            return {}
+        files = [target / f for f in files if (target / f).is_file()]
+        return {fingerprint(f): f for f in CodeReviewer(target, files=files, excel=False, config=config).run()}
+
+
+def review_folder(path, warm=None):
+    """Reviews a folder without Excel reports, as used by the REST API."""
+    if not Path(path).exists():
+        raise FileNotFoundError(f"Path {path} not found")
+    return CodeReviewer(path, excel=False, warm=warm).run()
+
+
+def review_changed_files(path, files, warm=None):
+    """Reviews only the given files of a checkout (all files when None), as used by the webhook receiver;
+    returns the findings and the failed conditions of the checkout's quality gate."""
+    reviewer = CodeReviewer(path, files=files, excel=False, warm=warm)
+    findings = reviewer.run()
+    return findings, evaluate_gate(findings, gate_conditions(reviewer.config))
+
+
+def stream_review(path, files=None, warm=None):
+    """Generator of per-file findings, as used by the gRPC API."""
+    if not Path(path).exists():
+        raise FileNotFoundError(f"Path {path} not found")
+    files = [Path(path) / f for f in files] if files else None
+    return CodeReviewer(path, files=files, excel=False, warm=warm).review_files()
+
+
+COMMANDS = ['scan', 'install-hook', 'serve', 'triage', 'explain', 'rules', 'init', 'stats', 'completion', 'config', 'history', 'trends', 'sbom']
 
 if __name__ == "__main__":
     import argparse
     parser = argparse.ArgumentParser()
-    parser.add_argument("path")
-    args = parser.parse_args()
-    CodeReviewer(args.path).run()
\ No newline at end of file
+    commands = parser.add_subparsers(dest="command", required=True)
+
+    scan = commands.add_parser("scan", help="Review files and write Excel reports (default command)")
+    scan.add_argument("path", nargs="?", default=".")
+    scan.add_argument("--publish", action="append", choices=sorted(PUBLISHERS), default=[],
+                      help="Publish findings to a code host (can be repeated)")
+    scan.add_argument("--notify", action="append", choices=sorted(NOTIFIERS), default=[],
+                      help="Send a scan summary to a chat or mail channel (can be repeated)")
+    scan.add_argument("--stdin", action="store_true", help="Review source read from stdin instead of files")
+    scan.add_argument("--filename", metavar="NAME",
+                      help="With --stdin, file name (and extension) the source is reported under, e.g. pkg/foo.go")
+    scan.add_argument("--staged", action="store_true",
+                      help="Only review the staged content of the files staged for commit, against HEAD")
+    scan.add_argument("--push-range", nargs="?", const="@{upstream}..HEAD", metavar="RANGE",
+                      help="Only review the files changed in a commit range, as of its last commit, against its "
+                           "first (defaults to @{upstream}..HEAD)")
+    scan.add_argument("--no-cache", action="store_true", help="Ignore and don't update the result cache")
+    scan.add_argument("--fail-on", choices=list(SEVERITY_RANK), type=str.upper,
+                      help="Exit with code 1 when findings at or above this severity exist")
+    scan.add_argument("--severity", type=severity_name, metavar="LEVEL",
+                      help="Only report findings at or above LEVEL (error/warning/info or high/medium/low)")
+    scan.add_argument("--only-rules", action="append", default=[], metavar="IDS",
+                      help="Only report these comma-separated rule ids (repeatable)")
+    scan.add_argument("--skip-rules", action="append", default=[], metavar="IDS",
+                      help="Don't report these comma-separated rule ids (repeatable)")
+    scan.add_argument("--filter", action="append", default=[], metavar="EXPR",
+                      help='Only keep findings matching EXPR, for reports and gate, e.g. '
+                           '\'severity >= high && path !~ "_test.go"\' (repeatable, all must match)')
+    scan.add_argument("--blame", action="store_true",
+                      help="Attach the author, commit and date that introduced each finding's line (git blame)")
+    scan.add_argument("--history", nargs="?", const="", metavar="FILE",
+                      help="Record the findings in a SQLite history (default .codereview-history.db)")
+    scan.add_argument("--depth", type=int, metavar="N",
+                      help="Also look for secrets added and later removed in the last N commits of the git "
+                           "history (secrets.history_depth)")
+    scan.add_argument("--max-findings", type=int, metavar="N",
+                      help="Report at most N findings per rule and only count the rest")
+    scan.add_argument("--vulncheck", action="store_true",
+                      help="Also report vulnerable dependencies of the Go modules with govulncheck")
+    scan.add_argument("--coverage", metavar="FILE",
+                      help="Go coverage profile (go test -coverprofile): report complex functions tests "
+                           "barely run (coverage.profile)")
+    scan.add_argument("--licenses", action="store_true",
+                      help="Also report Go dependencies whose license is not in licenses.allow")
+    scan.add_argument("--lint-commits", action="store_true",
+                      help="Also check the commit messages of --push-range (or commits.range): conventional "
+                           "format, subject length, issue references")
+    scan.add_argument("--explain-ai", action="store_true",
+                      help="Attach an LLM-written explanation and remediation to reported findings "
+                           "(needs ai.provider in .codereview.yaml)")
+    scan.add_argument("--suggest-fixes", action="store_true",
+                      help="Ask the ai.provider for a candidate patch for findings without an autofix; "
+                           "reported as suggestions, never applied")
+    scan.add_argument("--fix", action="store_true", help="Apply the rules' suggested fixes in place")
+    scan.add_argument("--dry-run", action="store_true",
+                      help="List the files that would be analyzed with their config and rules; "
+                           "with --fix, print the fixes as a unified diff instead")
+    scan.add_argument("--interactive", action="store_true", help="With --fix, ask before applying each fix")
+    scan.add_argument("--gate", action="append", default=[], metavar="CONDITION",
+                      help="Fail when a condition holds, e.g. 'new ERROR > 0' or 'total WARNING+ > 10' (repeatable)")
+    scan.add_argument("--baseline", metavar="FILE",
+                      help="Findings JSON of a previous run; only findings missing from it count as 'new'")
+    scan.add_argument("--no-excel", action="store_true", help="Skip writing the Excel reports")
+    scan.add_argument("--format", choices=sorted(FORMATS),
+                      help="Also write findings in this format, to stdout or --output")
+    scan.add_argument("--output", metavar="FILE", help="File for --format output (default: stdout)")
+    scan.add_argument("--config", metavar="FILE", help="Project config (default: nearest .codereview.yaml)")
+    scan.add_argument("--no-config", action="store_true", help="Ignore .codereview.yaml")
+    scan.add_argument("--exclude", action="append", default=[], metavar="GLOB",
+                      help="Skip files or folders matching GLOB (repeatable, relative to the current folder)")
+    scan.add_argument("--no-gitignore", action="store_true", help="Also scan files ignored by git")
+    scan.add_argument("--max-file-size", type=int, metavar="BYTES",
+                      help="Skip files larger than BYTES (default 1 MiB, 0 for no limit)")
+    scan.add_argument("--symlinks", choices=SYMLINK_POLICIES,
+                      help="Symlinked files and folders: skip them, follow those pointing inside the scanned "
+                           "folder (default) or follow all")
+    scan.add_argument("--include-vendor", action="store_true",
+                      help="Also scan vendored dependencies (vendor/, third_party/, node_modules/...)")
+    scan.add_argument("--profile", choices=PROFILE_MODES,
+                      help="Write a cpu (cProfile), mem (tracemalloc) or trace (Chrome trace JSON) profile of the scan,"
+                           " plus a per-span timing breakdown")
+    scan.add_argument("--patch", nargs="?", const="fixes.patch", metavar="FILE",
+                      help="Write the suggested fixes as one patch for git apply (default fixes.patch)")
+    scan.add_argument("--upload", metavar="URL",
+                      help="Upload the reports, --output, --patch and profile files to s3:// or gs://, e.g. "
+                           "s3://bucket/{repo}/{branch}/{commit}")
+
+    hook = commands.add_parser("install-hook", help="Install a git hook that reviews staged or pushed files")
+    hook.add_argument("path", nargs="?", default=".", help="Folder the hook scans (defaults to the repository root)")
+    hook.add_argument("--severity", choices=list(SEVERITY_RANK), type=str.upper, default="ERROR",
+                      help="Block commits with findings at or above this severity")
+    hook.add_argument("--hook", choices=sorted(HOOK_TEMPLATES), default="pre-commit",
+                      help="pre-commit scans staged files, pre-push scans the commits being pushed")
+    hook.add_argument("--force", action="store_true", help="Replace an existing hook")
+
+    serve = commands.add_parser("serve", help="Run as a long-lived server")
+    serve.add_argument("--lsp", action="store_true", help="Language Server Protocol over stdio, for editors")
+    serve.add_argument("--http", metavar="ADDR",
+                       help="REST API on host:port, e.g. :8080 (127.0.0.1) or 0.0.0.0:8080 (all interfaces)")
+    serve.add_argument("--grpc", metavar="ADDR",
+                       help="gRPC API on host:port, e.g. :50051 (127.0.0.1) (needs grpcio and the generated stubs)")
+    serve.add_argument("--root", action="append", metavar="DIR",
+                       help="Folder the REST and gRPC APIs may scan and triage, repeatable (default: the current directory)")
+    serve.add_argument("--tls-cert", metavar="FILE", help="PEM certificate chain the gRPC API serves TLS with")
+    serve.add_argument("--tls-key", metavar="FILE", help="PEM private key of --tls-cert")
+    serve.add_argument("--daemon", action="store_true",
+                       help="Keep Semgrep results, rules and parsed Go packages in memory between scans")
+
+    triage = commands.add_parser("triage", help="Page through findings and mark them accepted-risk, false-positive "
+                                                "or fix-later")
+    triage.add_argument("path", nargs="?", default=".")
+    triage.add_argument("--findings", metavar="FILE", help="Findings JSON of an earlier scan instead of scanning again")
+    triage.add_argument("--db", metavar="FILE", help="History store the decisions are saved to (default: from the config)")
+    triage.add_argument("--mark", nargs=2, metavar=("FP", "STATUS"),
+                        help=f"Mark a recorded finding (fingerprint or prefix) without the UI: {', '.join(TRIAGE_STATUSES)}")
+    triage.add_argument("--clear", metavar="FP", help="Drop the decision on a finding")
+    triage.add_argument("--reason", help="Why, saved with --mark")
+    triage.add_argument("--list", action="store_true", help="Print the decisions")
+    triage.add_argument("--json", action="store_true", help="Print the decisions as JSON, with --list")
+
+    explain_cmd = commands.add_parser("explain", help="Describe a rule with its rationale and examples")
+    explain_cmd.add_argument("rule_id", help="Rule id, e.g. go-rule-3-avoid-panic")
+
+    rules_cmd = commands.add_parser("rules", help="Inspect the rules a scan runs")
+    rules_commands = rules_cmd.add_subparsers(dest="rules_command", required=True)
+    rules_list = rules_commands.add_parser("list", help="List builtin and custom rules")
+    rules_list.add_argument("--tag", action="append", default=[], type=str.lower,
+                            help="Only rules with this severity, category, language, source or tag (repeatable)")
+    rules_list.add_argument("--config", metavar="FILE", help="Project config whose custom rules are listed too")
+    rules_list.add_argument("--json", action="store_true", help="Print the rules as JSON")
+    rules_docs = rules_commands.add_parser("docs", help="Render every rule as a Markdown page")
+    rules_docs.add_argument("-o", "--output", default="docs/rules", metavar="DIR", help="Output folder")
+    rules_docs.add_argument("--config", metavar="FILE", help="Project config whose custom rules are documented too")
+
+    init = commands.add_parser("init", help="Inspect the repository and write a starter .codereview.yaml")
+    init.add_argument("path", nargs="?", default=".")
+    init.add_argument("--profile", choices=list(PROFILES), help="Rule profile (asked interactively otherwise)")
+    init.add_argument("--yes", action="store_true", help="Don't ask, use the recommended profile")
+    init.add_argument("--force", action="store_true", help="Replace an existing .codereview.yaml")
+
+    stats_cmd = commands.add_parser("stats", help="Summarize codebase health without listing findings")
+    stats_cmd.add_argument("path", nargs="?", default=".")
+    stats_cmd.add_argument("--json", action="store_true", help="Print the metrics as JSON")
+    stats_cmd.add_argument("--coverage", metavar="FILE",
+                           help="Go coverage profile (go test -coverprofile) to rank functions by CRAP score")
+
+    completion = commands.add_parser("completion", help="Print a shell completion script")
+    completion.add_argument("shell", choices=SHELLS)
+
+    config_cmd = commands.add_parser("config", help="Check or show the project configuration")
+    config_commands = config_cmd.add_subparsers(dest="config_command", required=True)
+    config_validate = config_commands.add_parser("validate", help="Report mistakes in .codereview.yaml and custom rules")
+    config_validate.add_argument("file", nargs="?", help="Config file (default: nearest .codereview.yaml)")
+    config_show = config_commands.add_parser("show", help="Print the effective configuration, or one key of it")
+    config_show.add_argument("key", nargs="?", help="Dotted key, e.g. output.format")
+    config_show.add_argument("--file", help="Config file (default: nearest .codereview.yaml)")
+
+    history_cmd = commands.add_parser("history", help="Query the findings history recorded by scan --history")
+    history_cmd.add_argument("path", nargs="?", default=".")
+    history_cmd.add_argument("--db", metavar="FILE", help="History database (default: from the config)")
+    history_cmd.add_argument("--limit", type=int, default=20, help="Number of scans listed")
+    history_cmd.add_argument("--fingerprint", metavar="FP", help="Scans a finding appeared in (prefix is enough)")
+    history_cmd.add_argument("--json", action="store_true", help="Print the rows as JSON")
+
+    trends = commands.add_parser("trends", help="Chart findings by severity or rule over the recorded scans")
+    trends.add_argument("path", nargs="?", default=".")
+    trends.add_argument("--db", metavar="FILE", help="History database (default: from the config)")
+    trends.add_argument("--last", type=int, default=10, metavar="N", help="Number of scans charted")
+    trends.add_argument("--by", choices=["severity", "rule"], default="severity")
+    trends.add_argument("--json", action="store_true", help="Print the counts as JSON")
+
+    sbom_cmd = commands.add_parser("sbom", help="Write a CycloneDX or SPDX bill of materials of the Go modules")
+    sbom_cmd.add_argument("path", nargs="?", default=".")
+    sbom_cmd.add_argument("--format", choices=SBOM_FORMATS, default="cyclonedx")
+    sbom_cmd.add_argument("--output", metavar="FILE", help="File for the SBOM (default: stdout)")
+
+    # Keep `auto-review.py <path>` working by defaulting to the scan command
+    argv = sys.argv[1:]
+    if argv and argv[0] not in COMMANDS and argv[0] not in ('-h', '--help'):
+        argv = ['scan'] + argv
+    args = parser.parse_args(argv)
+    setup_tracing()
+
+    if args.command == "install-hook":
+        sys.exit(0 if install_hook(args.path, hook=args.hook, severity=args.severity, force=args.force) else 1)
+
+    if args.command == "serve":
+        warm = WarmCache() if args.daemon else None
+        if args.lsp:
+            serve_lsp(functools.partial(review_single_file, warm=warm))
+        elif args.http:
+            serve_http(args.http, functools.partial(review_folder, warm=warm),
+                       functools.partial(review_changed_files, warm=warm), args.root or ['.'])
+        elif args.grpc:
+            if bool(args.tls_cert) != bool(args.tls_key):
+                parser.error("--tls-cert and --tls-key go together")
+            try:
+                from grpc_server import serve_grpc  # grpcio is optional, only load it when asked
+            except ImportError as e:
+                sys.exit(f"Error: the gRPC API needs grpcio and the stubs generated from proto/codereview.proto ({e}):\n"
+                         f"  pip install grpcio grpcio-tools\n"
+                         f"  cd {Path(__file__).parent.resolve()} && python -m grpc_tools.protoc -I proto "
+                         f"--python_out=. --grpc_python_out=. proto/codereview.proto")
+            serve_grpc(args.grpc, functools.partial(stream_review, warm=warm), args.root or ['.'],
+                       args.tls_cert, args.tls_key)
+        else:
+            parser.error("serve needs a mode: --lsp, --http ADDR or --grpc ADDR")
+        sys.exit(0)
+
+    if args.command == "config":
+        config_file = args.file or find_config('.')
+        if args.config_command == "validate":
+            if not config_file:
+                sys.exit("Error: no .codereview.yaml found")
+            problems = validate_config(config_file, Path(__file__).parent.resolve() / "rules", {
+                'output.format': FORMATS, 'output.publish': PUBLISHERS, 'output.notify': NOTIFIERS,
+                'symlinks': SYMLINK_POLICIES, 'autofix.close_errors': CLOSE_ERROR_MODES, 'ai.provider': PROVIDERS,
+                'vulncheck.scan': SCAN_LEVELS, 'shellcheck.severity': SHELL_LEVELS})
+            for path, line, message in problems:
+                print(f"{path}:{line}: {message}")
+            print(f"❌ {len(problems)} problem(s) in {config_file}" if problems else f"✅ {config_file} is valid")
+            sys.exit(1 if problems else 0)
+        value = load_config(config_file)
+        for part in (args.key.split('.') if args.key else []):
+            if not isinstance(value, dict) or part not in value:
+                sys.exit(f"Error: unknown config key {args.key}")
+            value = value[part]
+        print(json.dumps(value, indent=2, default=str))
+        sys.exit(0)
+
+    if args.command == "completion":
+        rules = load_rules(Path(__file__).parent.resolve() / "rules") + builtin_checks()
+        rule_ids = sorted(r['id'] for r in rules)
+        print(completion_script(args.shell, parser, {
+            'rule_id': rule_ids, 'only_rules': rule_ids, 'skip_rules': rule_ids,
+            'tag': sorted(set().union(*(rule_tags(r) for r in rules))),
+            'key': flatten_keys(DEFAULT_CONFIG),
+        }), end='')
+        sys.exit(0)
+
+    if args.command == "stats":
+        real_stdout, sys.stdout = sys.stdout, sys.stderr  # scan progress stays out of the summary
+        config = load_config(find_config(args.path))
+        if args.coverage:
+            config['coverage']['profile'] = str(Path(args.coverage).resolve())
+        reviewer = CodeReviewer(args.path, excel=False, config=config)
+        files = list(reviewer.discover_files())
+        summary = codebase_stats(reviewer.run(), files, reviewer.base_dir, reviewer.config['debt'], reviewer.modules,
+                                 reviewer.coverage)
+        real_stdout.write((json.dumps(summary, indent=2) if args.json else render_stats(summary)) + "\n")
+        sys.exit(0)
+
+    if args.command == "sbom":
+        reviewer = CodeReviewer(args.path, excel=False)
+        document, graph = build_sbom(reviewer.base_dir, args.format, reviewer.excluded)
+        if not graph:
+            sys.exit(f"Error: no go.mod found in {reviewer.base_dir}")
+        text = json.dumps(document, indent=2)
+        if args.output:
+            Path(args.output).write_text(text + "\n", encoding='utf-8')
+            print(f"🧾 Wrote {args.format} SBOM of {len(graph)} module(s) and "
+                  f"{len({(p, v) for _, deps in graph for p, v, _ in deps})} dependencies to {args.output}")
+        else:
+            print(text)
+        sys.exit(0)
+
+    if args.command in ("history", "trends"):
+        base_dir = Path(args.path).resolve()
+        db = Path(args.db) if args.db else history_file(load_config(find_config(base_dir)), base_dir)
+        if not db.is_file():
+            sys.exit(f"Error: no history at {db}, record one with scan --history")
+
+    if args.command == "trends":
+        scans = trend_counts(db, args.last, args.by)
+        print(json.dumps(scans, indent=2) if args.json else render_trends(scans, args.by))
+        sys.exit(0)
+
+    if args.command == "history":
+        rows = finding_history(db, args.fingerprint) if args.fingerprint else recent_scans(db, args.limit)
+        if args.json:
+            print(json.dumps(rows, indent=2))
+        elif args.fingerprint:
+            for row in rows:
+                print(f"scan {row['scan_id']} {row['started']} {row['commit_sha'][:10]} {row['branch']}: "
+                      f"{row['path']}:{row['line']} [{row['severity']}] {row['rule_id']}")
+        else:
+            print(render_scans(rows))
+        sys.exit(0)
+
+    if args.command == "init":
+        info_rules = [r['id'] for r in load_rules(Path(__file__).parent.resolve() / "rules") if r['severity'] == 'INFO']
+        sys.exit(0 if init_config(args.path, args.profile, args.yes, args.force, info_rules) else 1)
+
+    if args.command == "rules":
+        config = load_config(args.config or find_config('.'))
+        rules = load_rules(Path(__file__).parent.resolve() / "rules", config['rules']['custom']) + builtin_checks()
+        if args.rules_command == "docs":
+            count = write_docs(rules, Path(__file__).parent.resolve() / "code", args.output)
+            print(f"📚 Wrote {count} rule page(s) to {args.output}")
+            sys.exit(0)
+        rules = [r for r in rules if set(args.tag) <= rule_tags(r)]
+        print(json.dumps(rules, indent=2) if args.json else rules_table(rules))
+        sys.exit(0)
+
+    if args.command == "explain":
+        script_dir = Path(__file__).parent.resolve()
+        rules = load_rules(script_dir / "rules", load_config(find_config('.'))['rules']['custom']) + builtin_checks()
+        rule = find_rule(rules, args.rule_id)
+        if rule is None:
+            sys.exit(f"Error: unknown rule {args.rule_id}")
+        print(explain(rule, script_dir / "code"))
+        sys.exit(0)
+
+    if args.command == "triage":
+        base_dir = Path(args.path).resolve()
+        base_dir = base_dir if base_dir.is_dir() else base_dir.parent
+        config = load_config(find_config(base_dir))
+        db = Path(args.db) if args.db else history_file(config, base_dir)
+        author = git_value(["config", "user.email"], base_dir, None)
+        if args.list:
+            decisions = load_decisions(config, base_dir, db)
+            print(json.dumps(decisions, indent=2, sort_keys=True) if args.json else render_decisions(decisions))
+            sys.exit(0)
+        if args.mark or args.clear:
+            if not db.is_file():
+                sys.exit(f"Error: no history at {db}, record the findings with scan --history first")
+            try:
+                key, entry = mark(db, *(args.mark or (args.clear, None)), reason=args.reason, author=author)
+            except (KeyError, ValueError) as e:
+                sys.exit(f"Error: {e.args[0]}")
+            if entry:
+                print(f"🗂️ Marked {key[:12]} {entry['status']}: {entry['path']} {entry['rule']}")
+            else:
+                print(f"🗂️ Cleared the decision on {key[:12]}")
+            sys.exit(0)
+        reviewer = CodeReviewer(args.path, excel=False, suppress=False, config=config)
+        if args.findings:
+            findings = json.loads(Path(args.findings).read_text(encoding='utf-8'))
+        else:
+            findings = reviewer.run()
+        triage_tui(findings, reviewer.base_dir, load_decisions(config, base_dir, db), db, author)
+        sys.exit(0)
+
+    # Flags win over .codereview.yaml, which wins over the built-in defaults
+    config = load_config(None if args.no_config else args.config or find_config(args.path))
+    config['nested'] = config['nested'] and not args.no_config
+    config['exclude'] += anchor_excludes(args.exclude, Path.cwd())
+    flags = {
+        'gitignore': False if args.no_gitignore else None,
+        'vendor': args.include_vendor or None,
+        'blame': args.blame or None,
+        'vulncheck.enabled': args.vulncheck or None,
+        'licenses.enabled': args.licenses or None,
+        'commits.enabled': False if args.stdin else args.lint_commits or None,
+        'commits.range': args.push_range,
+        'secrets.history_depth': 0 if args.stdin else args.depth,
+        'coverage.profile': str(Path(args.coverage).resolve()) if args.coverage else None,
+        'symlinks': args.symlinks,
+        'max_file_size': args.max_file_size,
+    }
+    set_overrides(config, {key: value for key, value in flags.items() if value is not None})
+    output = config['output']
+    args.publish = args.publish or output['publish']
+    args.notify = args.notify or output['notify']
+    args.format = args.format or output['format']
+    args.output = args.output or output['file']
+    args.no_excel = args.no_excel or not output['excel']
+    try:
+        conditions = gate_conditions(config, args.fail_on, args.gate)
+    except GateError as e:
+        parser.error(str(e))
+    baseline_file = config['gate']['baseline'] and Path(config['root'] or '.') / config['gate']['baseline']
+    baseline = load_baseline(args.baseline or baseline_file)
+    for name, known in [(n, PUBLISHERS) for n in args.publish] + [(n, NOTIFIERS) for n in args.notify] + \
+            [(args.format, FORMATS)] * bool(args.format):
+        if name not in known:
+            parser.error(f"unknown integration or format in config: {name}")
+
+    fix_mode = None
+    if args.interactive and not args.fix:
+        parser.error("--interactive needs --fix")
+    if args.dry_run and args.stdin:
+        parser.error("--dry-run can't be combined with --stdin")
+    if args.stdin and not args.filename:
+        parser.error("--stdin needs --filename so the language can be picked")
+    if args.stdin and (args.fix or args.patch):
+        parser.error("--fix and --patch can't be combined with --stdin")
+    if args.staged and (args.fix or args.patch):
+        parser.error("--fix and --patch can't be combined with --staged, which scans a copy of the index")
+    if args.push_range and (args.fix or args.patch):
+        parser.error("--fix and --patch can't be combined with --push-range, which scans a checkout of its last commit")
+    if args.stdin and args.lint_commits:
+        parser.error("--lint-commits can't be combined with --stdin")
+    if args.stdin and (args.explain_ai or args.suggest_fixes):
+        parser.error("--explain-ai and --suggest-fixes can't be combined with --stdin")
+    assistant = None
+    if args.explain_ai or args.suggest_fixes:
+        if not config['ai']['provider']:
+            parser.error("--explain-ai and --suggest-fixes need ai.provider in .codereview.yaml")
+        if config['ai']['provider'] not in PROVIDERS:
+            parser.error(f"unknown ai.provider in config: {config['ai']['provider']}")
+        if args.suggest_fixes and not config['ai']['send_code']:
+            parser.error("--suggest-fixes needs ai.send_code")
+        script_dir = Path(__file__).parent.resolve()
+        assistant = Assistant(config['ai'], load_rules(script_dir / "rules", config['rules']['custom']),
+                              script_dir / "code", explanations=args.explain_ai, suggestions=args.suggest_fixes)
+    if args.fix:
+        fix_mode = 'dry-run' if args.dry_run else 'interactive' if args.interactive else 'apply'
+
+    real_stdout = sys.stdout
+    if (args.format and not args.output) or fix_mode == 'dry-run':
+        sys.stdout = sys.stderr  # keep stdout for the formatted findings or the diff only
+
+    only = [r for ids in args.only_rules for r in ids.split(',') if r]
+    skip = [r for ids in args.skip_rules for r in ids.split(',') if r]
+    report = report_filter(args.severity, only, skip) if args.severity or only or skip else None
+    try:
+        predicates = [parse_filter(expression) for expression in args.filter]
+    except FilterError as e:
+        parser.error(str(e))
+    keep = (lambda f: all(p(f) for p in predicates)) if predicates else None
+
+    if args.dry_run and not args.fix:
+        files = staged_files(args.path) if args.staged else \
+            pushed_files(args.path, args.push_range) if args.push_range else None
+        reviewer = CodeReviewer(args.path, files=files, excel=False, config=config, report=report)
+        print(plan_text(reviewer.plan(), reviewer.skipped))
+        sys.exit(0)
+
+    target = Path(args.path).resolve()
+    scan_dir = target if target.is_dir() else target.parent
+    db = None
+    if (args.history is not None or config['history']) and not args.stdin:
+        db = Path(args.history) if args.history else history_file(config, scan_dir)
+    # Status (new, existing, fixed) is computed against the baseline, else the branch's last recorded scan
+    previous = baseline if baseline is not None else previous_findings(db, scan_dir) if db else None
+
+    if args.profile:
+        PROFILER.start(args.profile)
+
+    artifacts = []  # files the run writes, for --upload
+    if args.stdin:
+        gated = review_snippet(sys.stdin.read(), args.filename, config=config, cache=not args.no_cache)
+        if previous is not None:
+            classify(gated, previous)
+        gated = [f for f in gated if not keep or keep(f)]
+        findings = [f for f in gated if not report or report(f)]
+        decisions = {}
+    else:
+        files = None
+        scan_path = args.path
+        if args.staged:
+            # What is being committed rather than the working tree, gated on what it adds to HEAD's findings
+            scan_path, files = staged_snapshot(args.path)
+            if baseline is None:
+                previous = committed_findings(args.path, [f.relative_to(scan_path) for f in files], config)
+        elif args.push_range:
+            # The pushed commit rather than the working tree, gated on what it adds to the remote's findings
+            scan_path, files = pushed_snapshot(args.path, args.push_range)
+            if baseline is None:
+                previous = committed_findings(args.path, [f.relative_to(scan_path) for f in files], config,
+                                              range_ends(args.push_range)[0])
+        if previous and (files is not None or not target.is_dir()):
+            # A partial scan can't tell whether findings of the files it didn't see are fixed
+            base = Path(scan_path).resolve()
+            scanned = {f.relative_to(base).as_posix() for f in files} if files is not None else {target.name}
+            previous = {key: f for key, f in previous.items() if f['Path'] in scanned}
+        reviewer = CodeReviewer(scan_path, publishers=args.publish, notifiers=args.notify,
+                                files=files, excel=not args.no_excel, cache=not args.no_cache,
+                                upload=args.upload, config=config, fix=fix_mode, report=report,
+                                max_findings=args.max_findings or output['max_findings'], keep=keep,
+                                previous=previous, assistant=assistant,
+                                vulncheck=config['vulncheck']['enabled'], triage_store=db)
+        findings = reviewer.run()
+        gated = findings + reviewer.hidden
+        decisions = reviewer.suppressions
+        real_stdout.write(''.join(reviewer.diffs))
+        if args.patch:
+            patch, count = fixes_patch(reviewer.base_dir, findings, lambda f: repo_path(reviewer.base_dir, f))
+            Path(args.patch).write_text(patch, encoding='utf-8')
+            artifacts.append(args.patch)
+            print(f"🩹 Wrote {count} suggested fix(es) to {args.patch}")
+        if args.suggest_fixes:
+            print(f"💡 {sum(bool(f.get('Suggestion')) for f in findings)} AI fix suggestion(s), not applied")
+        if db:
+            # --staged scans a snapshot of the index, recorded for the repository itself
+            scope = 'staged' if args.staged else 'push-range' if args.push_range else \
+                'full' if target.is_dir() else 'file'
+            scan_id = record_scan(db, gated, scan_dir, scope)
+            print(f"🗃️ Recorded {'' if scope == 'full' else scope + ' '}scan #{scan_id} ({len(gated)} finding(s))"
+                  f" in {db}")
+
+    if args.profile:
+        written = PROFILER.stop()
+        artifacts += written
+        sys.stderr.write(PROFILER.breakdown() + f"\n📝 Wrote {', '.join(written)}\n")
+
+    fixed = []
+    if previous is not None:
+        # A finding triaged away since the reference run is not fixed
+        fixed = [f for f in apply_suppressions(fixed_findings(gated, previous), decisions) if not keep or keep(f)]
+        counts = {status: sum(f['Status'] == status for f in gated) for status in ('new', 'existing')}
+        reference = 'the baseline' if baseline is not None else 'HEAD' if args.staged else \
+            range_ends(args.push_range)[0] if args.push_range else 'the last recorded scan'
+        print(f"🆕 {counts['new']} new, {counts['existing']} existing, {len(fixed)} fixed since {reference}")
+
+    if args.format:
+        # --stdin findings keep the --filename they were given
+        document = render(findings + [f for f in fixed if not report or report(f)], args.format,
+                          None if args.stdin else reviewer.base_dir)
+        if args.output:
+            Path(args.output).write_text(document, encoding='utf-8')
+            print(f"📝 Wrote {args.format} findings to {args.output}")
+            artifacts.append(args.output)
+        else:
+            real_stdout.write(document + "\n")
+
+    uploaded = True
+    if args.upload:
+        artifacts = (reviewer.reports if not args.stdin else []) + artifacts
+        if artifacts:
+            with span('upload', reports=len(artifacts)):
+                uploaded = upload_reports(artifacts, args.upload, scan_dir)
+        else:
+            print("⚠️ Nothing to upload: the run wrote no Excel report, --output file, --patch or profile")
+
+    failed = evaluate_gate(gated, conditions, previous, fixed)
+    if not args.stdin:
+        reviewer.publish(failed)
+    for condition, count, matching in failed:
+        print(f"❌ Quality gate failed: {condition['text']} (found {count})")
+        for f in matching:
+            print(f"   {f['Path']}:{f['Line']} [{f['Severity']}] {f['Rule ID']}: {f['Message']}")
+    if not uploaded:
+        print(f"❌ Upload to {args.upload} failed")
+    if failed or not uploaded:
+        sys.exit(1)
+    if conditions:
+        print(f"✅ Quality gate passed ({len(conditions)} condition(s))")
\ No newline at end of file
@@ -0,0 +1,372 @@
+import re
+import difflib
+import shutil
+import subprocess
+from pathlib import Path
+
+from config import rule_matches
+
+GO_NUMERIC = {'int', 'int8', 'int16', 'int32', 'int64', 'uint', 'uint8', 'uint16', 'uint32', 'uint64', 'uintptr',
+              'byte', 'rune', 'float32', 'float64', 'complex64', 'complex128', 'time.Duration'}
+# autofix.close_errors: what the `defer x.Close()` added by --fix does with Close's error
+CLOSE_ERROR_MODES = ['ignore', 'log']
+
+GO_NILABLE = ('*', '[]', 'map[', 'chan ', 'chan<-', '<-chan', 'func', 'interface', 'any', 'error', 'unsafe.Pointer')
+
+
+def split_top_level(text, separator=','):
+    parts, depth, current = [], 0, ''
+    for char in text:
+        if char in '([{':
+            depth += 1
+        elif char in ')]}':
+            depth -= 1
+        if char == separator and depth == 0:
+            parts.append(current.strip())
+            current = ''
+        else:
+            current += char
+    return parts + [current.strip()] if current.strip() else parts
+
+
+def skip_group(text):
+    """The text after the balanced (...) group text starts with."""
+    depth = 0
+    for i, char in enumerate(text):
+        if char == '(':
+            depth += 1
+        elif char == ')':
+            depth -= 1
+        if depth == 0:
+            return text[i + 1:].strip()
+    return ''
+
+
+def go_result_types(header):
+    """Result types of a one-line Go func header (function, method or literal), or None if it can't be read."""
+    rest = header[header.index('func') + 4:].strip()
+    if not rest.startswith('('):
+        rest = rest[rest.find('('):]            # func Name(params)
+    rest = skip_group(rest)
+    if re.match(r'^\w+(\[[^\]]*\])?\s*\(', rest):  # that was the receiver: skip the name and the params
+        rest = skip_group(rest[rest.index('('):])
+    rest = rest.rsplit('{', 1)[0].strip()
+    entries = split_top_level(rest[1:-1]) if rest.startswith('(') else [rest] if rest else []
+    named = [e.split(None, 1) for e in entries]
+    if any(len(n) == 2 and re.match(r'^[A-Za-z_]\w*$', n[0]) and n[0] not in ('map', 'chan', 'func') for n in named):
+        types, pending = [], 0
+        for parts in named:
+            if len(parts) == 1:  # "a, b int": a takes b's type
+                pending += 1
+                continue
+            types += [parts[1]] * (pending + 1)
+            pending = 0
+        return types if not pending else None
+    return entries
+
+
+def go_zero_value(type_name, source):
+    if type_name == 'string':
+        return '""'
+    if type_name == 'bool':
+        return 'false'
+    if type_name in GO_NUMERIC:
+        return '0'
+    if type_name.startswith(GO_NILABLE):
+        return 'nil'
+    if re.match(r'^\[\d+\]', type_name):
+        return type_name + '{}'
+    if re.search(rf'^type\s+{re.escape(type_name)}\s+struct\b', source, re.MULTILINE):
+        return type_name + '{}'
+    return f"*new({type_name})"  # named type from elsewhere, or a type parameter: valid whatever it is
+
+
+def enclosing_header(lines, index, indent):
+    """(line number, text) of the one-line func header around line index, indented less than indent."""
+    for number in range(index - 1, -1, -1):
+        line = lines[number]
+        leading = line[:len(line) - len(line.lstrip())]
+        if re.search(r'\bfunc\b', line) and line.rstrip().endswith('{') and len(leading) < len(indent):
+            return number, line
+    return None, None
+
+
+def discarded_error_fix(source, finding, options):
+    """`_ = err` -> `if err != nil { return <zero values>, err }` following the enclosing function's results."""
+    text = source.decode('utf-8', errors='replace')
+    lines = text.splitlines()
+    index = finding['Line'] - 1
+    statement = lines[index]
+    match = re.match(r'^(\s*)_\s*=\s*(\w+)\s*$', statement)
+    if not match:
+        return '', None
+    indent, err = match.groups()
+    _, header = enclosing_header(lines, index, indent)
+    types = go_result_types(header) if header else None
+    if not types or types[-1] != 'error':
+        return '', None  # only functions returning an error can pass it on
+    values = [go_zero_value(t, text) for t in types[:-1]] + [err]
+    return f"if {err} != nil {{\n{indent}\treturn {', '.join(values)}\n{indent}}}", None
+
+
+def type_assertion_fix(source, finding, options):
+    """`v := x.(T)` -> `v, ok := x.(T)` and `if !ok { return <zero values>, fmt.Errorf(...) }` in functions
+    returning an error. ok gets another name when the function already has one; elsewhere there is no fix,
+    as turning the panic into a zero value would hide the failure."""
+    text = source.decode('utf-8', errors='replace')
+    lines = text.splitlines()
+    index = finding['Line'] - 1
+    match = re.match(r'^(\s*)(\w+)\s*:=\s*(.+)\.\((.+)\)\s*$', lines[index])
+    if not match or finding['Range']['start']['line'] != finding['Range']['end']['line']:
+        return '', None  # e.g. the init statement of an if
+    indent, value, expr, type_name = match.groups()
+    number, header = enclosing_header(lines, index, indent)
+    types = go_result_types(header) if header else None
+    if not types or types[-1] != 'error':
+        return '', None
+    start = sum(len(line) + 1 for line in lines[:number])
+    body = text[start:block_end(text, start + header.rstrip().rfind('{'))]  # not interface{} of a parameter
+    ok = next(name for name in ['ok'] + [f"ok{i}" for i in range(2, 100)] if not re.search(rf'\b{name}\b', body))
+    if re.match(r'^[\w.]+$', expr):
+        failure = f'fmt.Errorf("{expr}: got %T, want {type_name}", {expr})'
+    else:
+        failure = f'fmt.Errorf("unexpected type, want {type_name}")'
+    values = [go_zero_value(t, text) for t in types[:-1]] + [failure]
+    return (f"{value}, {ok} := {expr}.({type_name})\n{indent}if !{ok} {{\n"
+            f"{indent}\treturn {', '.join(values)}\n{indent}}}"), None
+
+
+def block_end(text, start):
+    """Index just past the } closing the block whose { is the first one at or after start."""
+    depth, i, opened = 0, start, False
+    while i < len(text):
+        char = text[i]
+        if char in '"`\'':
+            end = text.find(char, i + 1)
+            while char != '`' and end > 0 and text[end - 1] == '\\':
+                end = text.find(char, end + 1)
+            if end < 0:
+                return -1
+            i = end
+        elif char == '{':
+            depth, opened = depth + 1, True
+        elif char == '}':
+            depth -= 1
+            if opened and depth == 0:
+                return i + 1
+        i += 1
+    return -1
+
+
+def hands_off(code, resource):
+    """Whether code gives resource to an owner that closes it, the shapes go-rule-7 leaves alone: a struct
+    literal or a field, a returned constructor call or its returned result, a goroutine."""
+    value = rf'\s*{re.escape(resource)}\s*'  # the resource itself, not one of its fields or methods
+    if re.search(rf'[\w\]]\{{[^{{}}]*[{{,:]{value}[,}}]', code):  # Store{db: db}, gofmt'd without the space of a block
+        return True
+    if re.search(rf'\w\.\w+\s*={value}(?:$|[;}}])', code, re.MULTILINE):
+        return True
+    call = rf'[\w.]+\((?:[^()\n]*,)?{value}[,)]'
+    if re.search(rf'\breturn\s+{call}', code):
+        return True
+    for owner in re.findall(rf'(\w+)\s*:?=\s*{call}', code):
+        if re.search(rf'\breturn\s+{re.escape(owner)}\b', code):
+            return True
+    for go in re.finditer(r'\bgo\s+', code):
+        rest = code[go.end():]
+        if rest.startswith('func'):  # a closure closing it, or called with it
+            end = block_end(rest, rest.find(')') + 1)
+            if end > 0 and (re.search(rf'\b{re.escape(resource)}\.Close\(\)', rest[:end])
+                            or re.match(rf'\((?:[^()\n]*,)?{value}[,)]', rest[end:])):
+                return True
+        elif re.match(call, rest):
+            return True
+    return False
+
+
+def unclosed_resource_fix(source, finding, options):
+    """Adds `defer x.Close()` after the `if err != nil {...}` check that follows the open call.
+
+    The fix covers the assignment and its check, so the finding's Range is widened to their end.
+    """
+    text = source.decode('utf-8', errors='replace')
+    start = len(source[:finding['Range']['start']['offset']].decode('utf-8', errors='replace'))
+    end = len(source[:finding['Range']['end']['offset']].decode('utf-8', errors='replace'))
+    match = re.match(r'(\w+)\s*,\s*(\w+)\s*:?=', text[start:end])
+    check = re.compile(r'\s*if\s+(\w+)\s*!=\s*nil\s*\{').match(text, end)
+    if not match or not check or check.group(1) != match.group(2):
+        return '', None  # Close is only safe once the open call is known to have succeeded
+    after = block_end(text, check.start())
+    if after < 0:
+        return '', None
+    resource = match.group(1)
+    line_start = text.rfind('\n', 0, start) + 1
+    indent = re.match(r'\s*', text[line_start:start]).group(0)
+    lines = text.splitlines()
+    number, header = enclosing_header(lines, finding['Line'] - 1, indent)
+    if header is not None:
+        brace = sum(len(line) + 1 for line in lines[:number]) + header.rstrip().rfind('{')
+        if hands_off(text[after:block_end(text, brace)], resource):
+            return '', None  # closing it here would break the owner
+    if options.get('close_errors') == 'log':
+        close = (f"defer func() {{\n{indent}\tif cerr := {resource}.Close(); cerr != nil {{\n"
+                 f"{indent}\t\tlog.Printf(\"closing {resource}: %v\", cerr)\n{indent}\t}}\n{indent}}}()")
+    else:
+        close = f"defer {resource}.Close()"
+    return text[start:after] + f"\n{indent}{close}", len(text[:after].encode('utf-8'))
+
+
+def error_string(message):
+    """A Go error message without "error:" prefixes and trailing punctuation, starting in lowercase
+    unless its first word is an acronym."""
+    message = re.sub(r'^(?:(?i:error|err)\s*:\s*)+', '', message)
+    message = re.sub(r'(?:[.!?:\s]|\\n)+$', '', message)
+    if re.match(r'^[A-Z][a-z]', message):
+        message = message[0].lower() + message[1:]
+    return message
+
+
+def error_string_fix(source, finding, options):
+    """Rewrites the message literal of errors.New / fmt.Errorf following the error string conventions."""
+    start, end = finding['Range']['start']['offset'], finding['Range']['end']['offset']
+    call = source[start:end].decode('utf-8', errors='replace')
+    match = re.search(r'"((?:\\.|[^"\\])*)"', call)
+    if not match:
+        return '', None
+    message = error_string(match.group(1))
+    if not message or message == match.group(1):
+        return '', None
+    return call[:match.start(1)] + message + call[match.end(1):], None
+
+
+# Fixes that depend on the surrounding code, computed here when the Semgrep rule has no fix template.
+# A fixer returns the fix and, when it rewrites more than the match, the byte offset where it ends.
+COMPUTED_FIXES = {
+    'go-rule-21-unchecked-type-assertion': type_assertion_fix,
+    'go-rule-23-discarded-error': discarded_error_fix,
+    'go-rule-7-unclosed-resource': unclosed_resource_fix,
+    'go-rule-33-error-string-style': error_string_fix,
+}
+
+
+def computed_fix(source, finding, options):
+    """The fix for findings of COMPUTED_FIXES rules; widens the finding's Range when the fix covers more code."""
+    for rule_id, fixer in COMPUTED_FIXES.items():
+        if rule_matches(finding['Rule ID'], rule_id):
+            fix, end = fixer(source, finding, options)
+            if fix and end is not None:
+                line_start = source.rfind(b'\n', 0, end) + 1
+                finding['Range']['end'] = {'line': source[:end].count(b'\n') + 1,
+                                           'col': len(source[line_start:end].decode('utf-8', errors='replace')) + 1,
+                                           'offset': end}
+            return fix
+    return ''
+
+
+def fix_edits(findings):
+    """(start, end, replacement, finding) byte edits in file order, dropping findings that overlap an earlier fix."""
+    edits = []
+    for f in sorted((f for f in findings if f.get('Fix') and f.get('Range')),
+                    key=lambda f: f['Range']['start']['offset']):
+        start, end = f['Range']['start']['offset'], f['Range']['end']['offset']
+        if edits and start < edits[-1][1]:
+            continue
+        edits.append((start, end, f['Fix'], f))
+    return edits
+
+
+# Standard packages the computed fixes call, imported when a fix uses one the file doesn't import yet
+FIX_IMPORTS = ['fmt', 'log']
+
+
+def add_imports(source, fixes):
+    """Go source with an import of each FIX_IMPORTS package one of the fixes calls and the file lacks. Nothing
+    is added when the file binds the name to another package, which the fix then calls instead."""
+    text = source.decode('utf-8', errors='replace')
+    block = re.search(r'^import\s*\((.*?)^\)', text, re.MULTILINE | re.DOTALL)
+    specs = re.findall(r'^\s*(\w+|\.)?\s*"([^"]+)"', block.group(1), re.MULTILINE) if block else []
+    specs += re.findall(r'^import\s+(\w+|\.)?\s*"([^"]+)"', text, re.MULTILINE)
+    names = {alias or path.rsplit('/', 1)[-1] for alias, path in specs}
+    for package in FIX_IMPORTS:
+        if package in names or not any(re.search(rf'\b{package}\.\w', fix) for fix in fixes):
+            continue
+        if block:
+            at = block.start(1)
+            text = text[:at] + f'\n\t"{package}"' + text[at:]
+        else:
+            clause = re.search(r'^package\s+\w+[^\n]*\n', text, re.MULTILINE)
+            if not clause:
+                continue
+            text = text[:clause.end()] + f'\nimport "{package}"\n' + text[clause.end():]
+        block = re.search(r'^import\s*\((.*?)^\)', text, re.MULTILINE | re.DOTALL)
+    return text.encode('utf-8')
+
+
+def gofmt(source):
+    """Formats Go source with gofmt (go/format); returns it unchanged when gofmt is missing or fails."""
+    if not shutil.which('gofmt'):
+        return source
+    res = subprocess.run(['gofmt'], input=source, capture_output=True)
+    return res.stdout if res.returncode == 0 else source
+
+
+def apply_edits(source, edits):
+    for start, end, replacement, _ in reversed(edits):
+        source = source[:start] + replacement.encode('utf-8') + source[end:]
+    return source
+
+
+def unified_diff(before, after, name, context=3):
+    return ''.join(difflib.unified_diff(
+        before.decode('utf-8', errors='replace').splitlines(keepends=True),
+        after.decode('utf-8', errors='replace').splitlines(keepends=True),
+        fromfile=f"a/{name}", tofile=f"b/{name}", n=context))
+
+
+def fixed_source(file_path, findings, accept=None):
+    """The file's original and fixed content, and the findings whose fix was applied.
+
+    accept(hunk, finding) is asked about every edit (with a one-edit diff) when given, e.g. by --interactive.
+    """
+    source = Path(file_path).read_bytes()
+    edits = fix_edits(findings)
+    if accept:
+        name = Path(file_path).name
+        edits = [e for e in edits if accept(unified_diff(source, apply_edits(source, [e]), name, 1), e[3])]
+    fixed = apply_edits(source, edits)
+    if edits and Path(file_path).suffix == '.go':
+        fixed = gofmt(add_imports(fixed, [fix for _, _, fix, _ in edits]))
+    return source, fixed, [finding for *_, finding in edits]
+
+
+def apply_fixes(file_path, findings, accept=None):
+    """Rewrites the file in place; returns the findings that were fixed."""
+    source, fixed, applied = fixed_source(file_path, findings, accept)
+    if fixed != source:
+        Path(file_path).write_bytes(fixed)
+    return applied
+
+
+def preview_fixes(file_path, findings, name):
+    """Unified diff of the fixes for one file, without touching it."""
+    source, fixed, _ = fixed_source(file_path, findings)
+    return unified_diff(source, fixed, name)
+
+
+def fixes_patch(base_dir, findings, name_of):
+    """One patch with the fixes of every file, named by name_of(finding) (e.g. repo-relative for git apply)."""
+    by_path = {}
+    for f in findings:
+        if f.get('Fix') and f.get('Range'):
+            by_path.setdefault(f['Path'], []).append(f)
+    return ''.join(preview_fixes(Path(base_dir) / path, group, name_of(group[0]))
+                   for path, group in sorted(by_path.items())), sum(len(g) for g in by_path.values())
+
+
+def ask_hunk(hunk, finding):
+    """Prompt used by --fix --interactive: y applies the edit, anything else skips it."""
+    print(f"\n{finding['Rule ID']}: {finding['Message']}\n{hunk}")
+    try:
+        return input("Apply this fix? [y/N] ").strip().lower() in ('y', 'yes')
+    except EOFError:
+        return False
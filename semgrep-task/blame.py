@@ -0,0 +1,41 @@
+import datetime
+import subprocess
+from pathlib import Path
+
+UNCOMMITTED = '0' * 40
+
+
+def blame_lines(file_path):
+    """line number -> {author, email, commit, date} of the commit that last changed it, or {} outside git."""
+    file_path = Path(file_path)
+    res = subprocess.run(["git", "blame", "--line-porcelain", "--", file_path.name], cwd=file_path.parent,
+                         capture_output=True, text=True, encoding='utf-8', errors='replace')
+    if res.returncode != 0:
+        return {}
+    lines, current = {}, {}
+    for row in res.stdout.splitlines():
+        head, _, value = row.partition(' ')
+        if row.startswith('\t'):
+            continue
+        if len(head) == 40 and value:
+            current = {'commit': head}
+            lines[int(value.split()[1])] = current
+        elif head == 'author':
+            current['author'] = value
+        elif head == 'author-mail':
+            current['email'] = value.strip('<>')
+        elif head == 'author-time':
+            current['date'] = datetime.datetime.fromtimestamp(int(value)).strftime('%Y-%m-%d')
+    return lines
+
+
+def annotate(findings, file_path):
+    """Adds the introducing Author, Commit and Introduced date to the findings of one file."""
+    lines = blame_lines(file_path)
+    for finding in findings:
+        entry = lines.get(finding['Line'])
+        if not entry or entry['commit'] == UNCOMMITTED:
+            finding.update({'Author': '', 'Commit': '', 'Introduced': ''})
+            continue
+        finding.update({'Author': f"{entry.get('author', '')} <{entry.get('email', '')}>",
+                        'Commit': entry['commit'][:12], 'Introduced': entry.get('date', '')})
@@ -0,0 +1,234 @@
+import re
+import textwrap
+from pathlib import Path
+
+from autofix import COMPUTED_FIXES
+from config import rule_matches
+from secret_scan import PROVIDER_TOKENS, ENTROPY_RULE, SEVERITIES as SECRET_SEVERITIES
+from vulncheck import RULE_ID as VULN_RULE
+from licenses import RULE_ID as LICENSE_RULE
+from imports import RULE_ID as IMPORT_RULE
+from structlayout import RULE_ID as LAYOUT_RULE
+from tabletests import RULE_ID as TABLE_RULE
+from naming import RULES as NAMING_RULES
+from todos import RULE_ID as TODO_RULE
+from commented import RULE_ID as COMMENTED_RULE
+from flagparams import RULE_ID as FLAG_RULE
+from structfields import RULE_ID as FIELDS_RULE
+from recovery import RULE_ID as RECOVERY_RULE
+from deprecated import RULE_ID as DEPRECATED_RULE
+from stats import MAINTAINABILITY_RULE
+from coupling import RULES as COUPLING_RULES
+from coverage import RULE_ID as COVERAGE_RULE
+from kubernetes import RULES as K8S_RULES
+from terraform import RULES as TF_RULES
+from commitlint import RULES as COMMIT_RULES
+
+# metadata.rule prefix ("Go Rule 3") -> fixture holding the "RULE 3" BAD/GOOD examples
+FIXTURES = {'Go': 'test.go', 'Java': 'test.java', 'JS': 'test.js', 'Python': 'test.py'}
+
+CWE_URL = "https://cwe.mitre.org/data/definitions/{}.html"
+
+SOURCE_LANGUAGES = ['go', 'python', 'javascript', 'java']
+# Checks auto-review does itself rather than with a rule file: id -> (highest severity, category, languages,
+# what it reports). Shellcheck codes (SC2086) come from shellcheck and are not listed.
+BUILTIN_CHECKS = {
+    'HEADER-CHECK': ('ERROR', 'documentation', SOURCE_LANGUAGES + ['typescript'],
+                     "Source file header without its purpose, author or date"),
+    **{rule_id: (SECRET_SEVERITIES[rule_id], 'security', [], f"Hardcoded {name}")
+       for rule_id, name, _ in PROVIDER_TOKENS},
+    ENTROPY_RULE: (SECRET_SEVERITIES[ENTROPY_RULE], 'security', [],
+                   "High-entropy string literal that looks like a secret (secrets section)"),
+    MAINTAINABILITY_RULE: ('WARNING', 'code-quality', SOURCE_LANGUAGES,
+                           "Function whose maintainability index is below metrics.min_maintainability"),
+    COVERAGE_RULE: ('WARNING', 'reliability', ['go'],
+                    "Go function whose CRAP score, complexity against test coverage, is above coverage.max_crap"),
+    DEPRECATED_RULE: ('WARNING', 'best-practice', ['go'], "Use of a package or symbol documented as Deprecated"),
+    TODO_RULE: ('WARNING', 'code-quality', [], "TODO, FIXME or HACK comment, a warning once stale or untracked"),
+    VULN_RULE: ('ERROR', 'security', ['go'], "Known vulnerability in a Go dependency, by govulncheck"),
+    LICENSE_RULE: ('ERROR', 'compliance', ['go'], "Go dependency whose license the licenses section doesn't allow"),
+    IMPORT_RULE: ('ERROR', 'architecture', ['go'], "Import forbidden by an imports policy of the config"),
+    **{rule_id: (severity, category, ['go'], "Go naming convention (naming section)")
+       for rule_id, (severity, category) in NAMING_RULES.items()},
+    RECOVERY_RULE: ('WARNING', 'reliability', ['go'], "HTTP handler package without panic recovery"),
+    FLAG_RULE: ('INFO', 'code-quality', ['go'], "Bool parameter that switches the function's behavior"),
+    FIELDS_RULE: ('INFO', 'code-quality', ['go'], "Struct with more fields than struct_fields.max_fields"),
+    LAYOUT_RULE: ('INFO', 'performance', ['go'], "Struct whose field order wastes memory on padding"),
+    COMMENTED_RULE: ('INFO', 'code-quality', ['go'], "Commented-out code"),
+    TABLE_RULE: ('INFO', 'code-quality', ['go'], "Repeated test checks that could be a table-driven test"),
+    **{rule_id: (severity, category, ['go'], "Import cycle or unstable package in the Go import graph")
+       for rule_id, (severity, category) in COUPLING_RULES.items()},
+    **{rule_id: (severity, category, ['yaml'], "Kubernetes manifest problem")
+       for rule_id, (severity, category) in K8S_RULES.items()},
+    **{rule_id: (severity, category, ['hcl'], "Terraform configuration problem")
+       for rule_id, (severity, category) in TF_RULES.items()},
+    **{rule_id: (severity, category, [], "Commit message not following the commits section")
+       for rule_id, (severity, category) in COMMIT_RULES.items()},
+}
+
+
+def load_rules(rules_dir, custom=()):
+    """Every builtin rule, then the rules of the config's custom rule files, with the file each comes from."""
+    sources = [(f, 'builtin') for f in sorted(Path(rules_dir).glob('*.yml'))] + [(Path(f), 'custom') for f in custom]
+    return [rule for rule_file, origin in sources for rule in read_rule_file(rule_file, origin)]
+
+
+def builtin_checks():
+    """BUILTIN_CHECKS in the shape of load_rules' rules, for `rules list` and `explain`."""
+    return [{'id': rule_id, 'severity': severity, 'message': message, 'languages': languages, 'category': category,
+             'metadata': {}, 'fix': False, 'source': 'builtin', 'origin': 'builtin'}
+            for rule_id, (severity, category, languages, message) in BUILTIN_CHECKS.items()]
+
+
+def read_rule_file(rule_file, origin):
+    import yaml  # rule files are YAML, like .codereview.yaml
+    rules = []
+    for rule in (yaml.safe_load(Path(rule_file).read_text(encoding='utf-8')) or {}).get('rules', []):
+        metadata = rule.get('metadata') or {}
+        rules.append({
+            'id': rule['id'],
+            'severity': rule.get('severity', 'INFO'),
+            'message': rule.get('message', ''),
+            'languages': rule.get('languages', []),
+            'category': metadata.get('category', ''),
+            'metadata': metadata,
+            'fix': bool(rule.get('fix') or rule.get('fix-regex')) or rule['id'] in COMPUTED_FIXES,
+            'source': Path(rule_file).name,
+            'origin': origin,
+        })
+    return rules
+
+
+def rule_tags(rule):
+    """Values `rules list --tag` matches: severity, category, languages, origin and metadata tags or CWE."""
+    metadata = rule['metadata']
+    tags = {rule['severity'], rule['category'], rule['origin'], *rule['languages'], *(metadata.get('tags') or [])}
+    tags.update(re.findall(r'CWE-\d+', str(metadata.get('cwe', ''))))
+    return {str(t).lower() for t in tags if t}
+
+
+def rules_table(rules):
+    rows = [('ID', 'SEVERITY', 'CATEGORY', 'LANGUAGES', 'SOURCE')]
+    rows += [(r['id'], r['severity'], r['category'], ','.join(r['languages']), rule_source(r)) for r in rules]
+    widths = [max(len(row[i]) for row in rows) for i in range(4)]
+    return '\n'.join('  '.join(cell.ljust(width) for cell, width in zip(row, widths)) + '  ' + row[4]
+                     for row in rows)
+
+
+def rule_source(rule):
+    """builtin:go-rules.yml, custom:team.yml, or builtin for the checks without a rule file."""
+    return rule['origin'] if rule['source'] == rule['origin'] else f"{rule['origin']}:{rule['source']}"
+
+
+def find_rule(rules, rule_id):
+    """Looks a rule up by id, also accepting Semgrep's prefixed check ids (rules.go-rule-3-avoid-panic)."""
+    return next((r for r in rules if rule_matches(rule_id, r['id'])), None)
+
+
+def fixture_example(code_dir, label):
+    """Title, rationale and BAD/GOOD code of the `RULE N` section of the fixture matching metadata.rule."""
+    match = re.match(r'(\w+) Rule (\d+)$', label or '')
+    if not match or match.group(1) not in FIXTURES:
+        return None
+    fixture = Path(code_dir) / FIXTURES[match.group(1)]
+    if not fixture.is_file():
+        return None
+    lines = fixture.read_text(encoding='utf-8', errors='ignore').splitlines()
+    heading = re.compile(rf'^\s*(?://|#)\s*RULE {match.group(2)}:\s*(.*)$')
+    start = next((i for i, line in enumerate(lines) if heading.match(line)), None)
+    if start is None:
+        return None
+
+    example = {'title': heading.match(lines[start]).group(1).strip(), 'why': '', 'bad': [], 'good': []}
+    section, indent = None, 0
+    for line in lines[start + 1:]:
+        comment = re.sub(r'^\s*(?://|#)\s?', '', line) if re.match(r'^\s*(?://|#)', line) else None
+        if comment is not None and comment.startswith('RULE '):
+            break
+        if comment is not None and comment.startswith('Why:'):
+            example['why'] = comment[4:].strip()
+        elif comment is not None and comment.startswith(('BAD:', 'GOOD:')):
+            section = 'bad' if comment.startswith('BAD:') else 'good'
+            indent = len(line) - len(line.lstrip())
+        elif comment is not None and len(line) - len(line.lstrip()) <= indent:
+            if section == 'good':
+                break  # a comment at the markers' level ("// Helper functions") ends the example
+        elif section:
+            example[section].append(line)
+    for section in ('bad', 'good'):
+        example[section] = textwrap.dedent('\n'.join(example[section])).strip('\n')
+    return example
+
+
+def explain(rule, code_dir):
+    """Terminal description of a rule: message, severity, rationale, examples and links."""
+    metadata = rule['metadata']
+    example = fixture_example(code_dir, metadata.get('rule'))
+    lines = [f"{rule['id']}" + (f" — {example['title']}" if example else ''), '']
+    lines.append(rule['message'])
+    if example and example['why']:
+        lines += ['', f"Why: {example['why']}"]
+    lines += ['', f"Severity:  {rule['severity']}", f"Category:  {rule['category'] or '-'}",
+              f"Languages: {', '.join(rule['languages']) or 'any'}", f"Autofix:   {'yes' if rule['fix'] else 'no'}",
+              f"Source:    {rule['origin']}" + (f" ({rule['source']})" if rule['source'] != rule['origin'] else '')]
+    if example and example['bad']:
+        lines += ['', '✗ Bad:', example['bad']]
+    if example and example['good']:
+        lines += ['', '✓ Good:', example['good']]
+
+    links = rule_links(rule)
+    if links:
+        lines += ['', 'Links:'] + [f"  {link}" for link in links]
+    return '\n'.join(lines)
+
+
+def rule_links(rule):
+    links = list(rule['metadata'].get('references') or [])
+    for cwe in re.findall(r'CWE-(\d+)', str(rule['metadata'].get('cwe', ''))):
+        links.append(CWE_URL.format(cwe))
+    return links
+
+
+# Semgrep language -> Markdown code fence language
+FENCE = {'javascript': 'js', 'typescript': 'ts', 'python': 'python', 'go': 'go', 'java': 'java'}
+
+
+def rule_markdown(rule, code_dir):
+    """One Markdown page per rule, from the same rule files and fixtures the scan uses."""
+    example = fixture_example(code_dir, rule['metadata'].get('rule'))
+    fence = FENCE.get(rule['languages'][0], '') if rule['languages'] else ''
+    lines = [f"# {rule['id']}", '']
+    if example:
+        lines += [f"**{example['title']}**", '']
+    lines += [rule['message'], '']
+    if example and example['why']:
+        lines += [f"> Why: {example['why']}", '']
+    lines += ['| | |', '|---|---|', f"| Severity | `{rule['severity']}` |",
+              f"| Category | {rule['category'] or '-'} |",
+              f"| Languages | {', '.join(rule['languages']) or 'any'} |",
+              f"| Autofix | {'yes' if rule['fix'] else 'no'} |",
+              f"| Source | {rule['origin']}" + (f" (`{rule['source']}`)" if rule['source'] != rule['origin'] else '') + " |", '']
+    for title, key in (('Bad', 'bad'), ('Good', 'good')):
+        if example and example[key]:
+            lines += [f"## {title}", '', f"```{fence}", example[key], '```', '']
+    links = rule_links(rule)
+    if links:
+        lines += ['## Links', ''] + [f"- <{link}>" for link in links] + ['']
+    lines += [f"Run `auto-review.py explain {rule['id']}` for the same page in a terminal.", '']
+    return '\n'.join(lines)
+
+
+def write_docs(rules, code_dir, out_dir):
+    """Writes <rule-id>.md for every rule plus a README.md index grouped by rule file; returns the page count."""
+    out_dir = Path(out_dir)
+    out_dir.mkdir(parents=True, exist_ok=True)
+    index = ['# Rules', '', f"{len(rules)} rules, generated by `auto-review.py rules docs`.", '']
+    for source in sorted({r['source'] for r in rules}):
+        index += [f"## {source}", '', '| Rule | Severity | Category | Message |', '|---|---|---|---|']
+        for rule in (r for r in rules if r['source'] == source):
+            (out_dir / f"{rule['id']}.md").write_text(rule_markdown(rule, code_dir), encoding='utf-8')
+            message = rule['message'].replace('|', '\\|')
+            index.append(f"| [{rule['id']}]({rule['id']}.md) | {rule['severity']} | {rule['category']} | {message} |")
+        index.append('')
+    (out_dir / 'README.md').write_text('\n'.join(index), encoding='utf-8')
+    return len(rules)
@@ -1,5 +1,5 @@
 /*
- * Purpose: Comprehensive test file for Golang coding rules - demonstrates all 20 rules
+ * Purpose: Comprehensive test file for Golang coding rules - demonstrates all 39 rules
  * Author: Harsh Patil
  * Date: 2025-12-18
  * Modified By: N/A
@@ -8,6 +8,7 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"database/sql"
@@ -16,8 +17,15 @@ import (
 	"log"
 	"math/big"
 	mathrand "math/rand"
+	"net/http"
 	"os"
+	"sort"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/sync/errgroup"
 )
 
 // ==========================================
@@ -137,6 +145,7 @@ func NewService(config string, db *sql.DB) *Service {
 // ==========================================
 // RULE 7: Always Close Resources
 // Why: Unclosed resources cause leaks
+// Note: auto-review --fix adds the defer after the error check
 // ==========================================
 
 // BAD: Not closing file
@@ -159,6 +168,23 @@ func goodDeferClose() error {
 	return nil
 }
 
+// GOOD: Handing the connection to the struct that closes it
+type userStore struct {
+	db *sql.DB
+}
+
+func goodOpenStore(dsn string) (*userStore, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &userStore{db: db}, nil
+}
+
+func (s *userStore) Close() error {
+	return s.db.Close()
+}
+
 // ==========================================
 // RULE 8: Do Not Use defer Inside Loops
 // Why: Defers inside loops can exhaust resources
@@ -434,6 +460,504 @@ func goodParameterizedQuery(db *sql.DB, userID string) error {
 // Note: Run gofmt -w *.go
 // ==========================================
 
+// ==========================================
+// RULE 21: Use Comma-Ok Type Assertions
+// Why: A failed single-value assertion panics
+// Note: auto-review --fix rewrites the BAD form in functions returning an error
+// ==========================================
+
+// BAD: Assertion panics when value is not a string
+func badTypeAssertion(value interface{}) (string, error) {
+	name := value.(string)
+	return name, nil
+}
+
+// GOOD: Checking the assertion
+func goodTypeAssertion(value interface{}) (string, error) {
+	name, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("unexpected type %T", value)
+	}
+	return name, nil
+}
+
+// ==========================================
+// RULE 22: Wrap Errors With %w
+// Why: %v loses the error chain for errors.Is / errors.As
+// Note: auto-review --fix rewrites the BAD form
+// ==========================================
+
+// BAD: Formatting the error with %v
+func badErrorWrap(name string) error {
+	err := saveFile(name)
+	if err != nil {
+		return fmt.Errorf("saving %s: %v", name, err)
+	}
+	return nil
+}
+
+// BAD: Error values named like errors are wrapped too
+func badErrorWrapNamed(name string) error {
+	var saveErr error = saveFile(name)
+	if saveErr != nil {
+		return fmt.Errorf("saving %s (attempt %d): %v", name, 1, saveErr)
+	}
+	return nil
+}
+
+// GOOD: Wrapping the error with %w
+func goodErrorWrap(name string) error {
+	err := saveFile(name)
+	if err != nil {
+		return fmt.Errorf("saving %s: %w", name, err)
+	}
+	return nil
+}
+
+// ==========================================
+// RULE 23: Don't Discard Errors With the Blank Identifier
+// Why: _ = err hides failures from callers
+// Note: auto-review --fix returns the error, with zero values for the other results
+// ==========================================
+
+// BAD: Discarding the error
+func badDiscardedError(name string) (string, error) {
+	data, err := processData()
+	_ = err
+	return data + name, nil
+}
+
+// GOOD: Returning the error
+func goodDiscardedError(name string) (string, error) {
+	data, err := processData()
+	if err != nil {
+		return "", err
+	}
+	return data + name, nil
+}
+
+// ==========================================
+// RULE 24: Keep sync.WaitGroup Counts Balanced
+// Why: Wait returns too early or blocks forever when Add and Done don't match
+// ==========================================
+
+// BAD: Add inside the goroutine, Wait can run first
+func badWaitGroupAdd(names []string) {
+	var wg sync.WaitGroup
+	for _, name := range names {
+		go func(name string) {
+			wg.Add(1)
+			defer wg.Done()
+			saveFile(name)
+		}(name)
+	}
+	wg.Wait()
+}
+
+// BAD: The early return skips Done, Wait blocks forever
+func badWaitGroupDone(names []string) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		if len(names) == 0 {
+			return
+		}
+		saveFile(names[0])
+		wg.Done()
+	}()
+	wg.Wait()
+}
+
+// GOOD: Add before go, Done deferred first
+func goodWaitGroup(names []string) {
+	var wg sync.WaitGroup
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			saveFile(name)
+		}(name)
+	}
+	wg.Wait()
+}
+
+// ==========================================
+// RULE 25: Use errgroup for Goroutines That Return Errors
+// Why: Hand-rolled error channels and WaitGroups leak goroutines and lose cancellation
+// ==========================================
+
+// BAD: Collecting errors on a channel by hand
+func badFanOut(names []string) error {
+	var wg sync.WaitGroup
+	errs := make(chan error, len(names))
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if err := saveFile(name); err != nil {
+				errs <- err
+			}
+		}(name)
+	}
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+// GOOD: errgroup waits, returns the first error and cancels the rest
+func goodFanOut(ctx context.Context, names []string) error {
+	g, ctx := errgroup.WithContext(ctx)
+	for _, name := range names {
+		g.Go(func() error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return saveFile(name)
+		})
+	}
+	return g.Wait()
+}
+
+// ==========================================
+// RULE 26: Recover From Panics in Goroutines
+// Why: A panic in a goroutine takes down the whole process
+// ==========================================
+
+// BAD: The assertion panics in the goroutine and crashes the program
+func badGoroutinePanic(values []interface{}) {
+	for _, value := range values {
+		go func(value interface{}) {
+			name := value.(string)
+			log.Println(name)
+		}(value)
+	}
+}
+
+// GOOD: The goroutine recovers and logs the panic
+func goodGoroutinePanic(values []interface{}) {
+	for _, value := range values {
+		go func(value interface{}) {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("worker panicked: %v", r)
+				}
+			}()
+			name := value.(string)
+			log.Println(name)
+		}(value)
+	}
+}
+
+// ==========================================
+// RULE 27: Back Off Between Retries
+// Why: Clients retrying in lockstep overload a service that is recovering
+// ==========================================
+
+// BAD: Retrying every second, whatever happens
+func badRetry(db *sql.DB) error {
+	var err error
+	for attempt := 0; attempt < 5; attempt++ {
+		err = db.Ping()
+		if err == nil {
+			return nil
+		}
+		time.Sleep(time.Second)
+	}
+	return err
+}
+
+// GOOD: Exponential backoff with jitter, stopping on cancellation
+func goodRetry(ctx context.Context, db *sql.DB) error {
+	delay := 100 * time.Millisecond
+	var err error
+	for attempt := 0; attempt < 5; attempt++ {
+		err = db.PingContext(ctx)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		time.Sleep(delay/2 + time.Duration(mathrand.Int63n(int64(delay/2))))
+		delay *= 2
+	}
+	return err
+}
+
+// ==========================================
+// RULE 28: Propagate the Request Deadline to Outbound Calls
+// Why: Calls without the request's context keep running after the client gave up
+// ==========================================
+
+// BAD: The lookup ignores the request's deadline and cancellation
+func badLookupHandler(w http.ResponseWriter, r *http.Request) {
+	resp, err := http.Get("https://inventory.internal/items")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	w.WriteHeader(resp.StatusCode)
+}
+
+// GOOD: The outbound request inherits the incoming one's context
+func goodLookupHandler(w http.ResponseWriter, r *http.Request) {
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, "https://inventory.internal/items", nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	w.WriteHeader(resp.StatusCode)
+}
+
+// ==========================================
+// RULE 29: Use sync.Pool Objects Safely
+// Why: Unreset or retained pooled objects leak data between callers
+// ==========================================
+
+var bufferPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// BAD: No Reset, and the returned bytes belong to the pooled buffer
+func badRender(name string) []byte {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	defer bufferPool.Put(buf)
+	buf.WriteString("hello " + name)
+	return buf.Bytes()
+}
+
+// GOOD: Reset after Get, copy out before Put
+func goodRender(name string) []byte {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer bufferPool.Put(buf)
+	buf.WriteString("hello " + name)
+	return append([]byte(nil), buf.Bytes()...)
+}
+
+// ==========================================
+// RULE 30: Assign append's Result Back to the Slice It Grows
+// Why: A dropped or shared append result silently loses elements
+// ==========================================
+
+// BAD: Both paths append to base, the second overwrites the first
+func badAppend(base []string) ([]string, []string) {
+	base = append(make([]string, 0, 4), base...)
+	admins := append(base, "admin")
+	guests := append(base, "guest")
+	return admins, guests
+}
+
+// GOOD: Each owner gets its own copy
+func goodAppend(base []string) ([]string, []string) {
+	admins := append(append([]string(nil), base...), "admin")
+	guests := append(append([]string(nil), base...), "guest")
+	return admins, guests
+}
+
+// ==========================================
+// RULE 31: Don't Share Backing Arrays Across API Boundaries
+// Why: Callers holding a sub-slice see (and cause) later writes to the buffer
+// ==========================================
+
+type Journal struct {
+	entries []string
+}
+
+// BAD: Callers get a window on the internal buffer
+func (j *Journal) Recent(n int) []string {
+	return j.entries[len(j.entries)-n:]
+}
+
+// GOOD: Callers get their own copy
+func (j *Journal) RecentCopy(n int) []string {
+	return append([]string(nil), j.entries[len(j.entries)-n:]...)
+}
+
+// ==========================================
+// RULE 32: Don't Depend on Map Iteration Order
+// Why: Go randomizes map iteration, so the output changes between runs
+// ==========================================
+
+// BAD: The header order changes on every run
+func badHeaderLine(headers map[string]string) string {
+	var parts []string
+	for name, value := range headers {
+		parts = append(parts, name+": "+value)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// GOOD: Sorting the keys first
+func goodHeaderLine(headers map[string]string) string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, name+": "+headers[name])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// GOOD: fmt.Sprintf only formats a value, filling a map doesn't depend on the order
+func goodHeaderValues(headers map[string]string) map[string]string {
+	values := make(map[string]string, len(headers))
+	for name, value := range headers {
+		values[name] = fmt.Sprintf("%s: %s", name, value)
+	}
+	return values
+}
+
+// ==========================================
+// RULE 33: Follow the Go Error String Conventions
+// Why: Error strings get wrapped into longer messages, where capitals and periods read wrong
+// ==========================================
+
+// BAD: "parse config: Error: Invalid port." once wrapped
+func badPortError(port int) error {
+	if port < 0 {
+		return errors.New("Error: Invalid port.")
+	}
+	return fmt.Errorf("Port %d is out of range.", port)
+}
+
+// GOOD: Lowercase, no prefix, no trailing punctuation
+func goodPortError(port int) error {
+	if port < 0 {
+		return errors.New("invalid port")
+	}
+	return fmt.Errorf("port %d is out of range", port)
+}
+
+// ==========================================
+// RULE 34: Never Allow Credentials for Any Origin
+// Why: Any website could send requests with the user's cookies and read the responses
+// ==========================================
+
+var trustedOrigins = map[string]bool{"https://app.example.com": true}
+
+// BAD: Every origin gets credentialed access
+func badCORS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", r.Header.Get("Origin"))
+	w.Header().Set("Access-Control-Allow-Credentials", "true")
+}
+
+// GOOD: Only the trusted origins are echoed back
+func goodCORS(w http.ResponseWriter, r *http.Request) {
+	if origin := r.Header.Get("Origin"); trustedOrigins[origin] {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Add("Vary", "Origin")
+	}
+}
+
+// ==========================================
+// RULE 35: Avoid Wildcard CORS
+// Why: Any website can read the responses from its visitors' browsers
+// ==========================================
+
+// BAD: Every origin may read the internal API
+func badWildcardCORS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+}
+
+// GOOD: A fixed origin
+func goodFixedCORS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "https://app.example.com")
+}
+
+// ==========================================
+// RULE 36: Verify JWT Signatures
+// Why: The claims of an unverified token are whatever the client wrote
+// ==========================================
+
+// BAD: Anyone can send a token for any user
+func badTokenSubject(tokenString string) (string, error) {
+	token, _, err := jwt.NewParser().ParseUnverified(tokenString, jwt.MapClaims{})
+	if err != nil {
+		return "", err
+	}
+	return token.Claims.GetSubject()
+}
+
+// GOOD: The signature is checked against the key
+func goodTokenSubject(tokenString string, key []byte) (string, error) {
+	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
+		return key, nil
+	}, jwt.WithValidMethods([]string{"HS256"}))
+	if err != nil {
+		return "", err
+	}
+	return token.Claims.GetSubject()
+}
+
+// ==========================================
+// RULE 37: Never Accept the "none" JWT Algorithm
+// Why: A token without a signature can be forged by anyone
+// ==========================================
+
+// BAD: Unsigned tokens are accepted
+func badNoneToken(tokenString string) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
+		return jwt.UnsafeAllowNoneSignatureType, nil
+	})
+}
+
+// GOOD: Only the algorithm the service signs with
+func goodPinnedToken(tokenString string, key []byte) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
+		return key, nil
+	}, jwt.WithValidMethods([]string{"HS256"}))
+}
+
+// GOOD: Rejecting unsigned tokens in the key function
+func goodRejectNoneToken(tokenString string, key []byte) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
+		if t.Method == jwt.SigningMethodNone {
+			return nil, errors.New("unsigned token")
+		}
+		return key, nil
+	})
+}
+
+// ==========================================
+// RULE 38: Don't Hardcode JWT Signing Keys
+// Why: Whoever reads the source can mint tokens, and the key can't be rotated
+// ==========================================
+
+// BAD: The signing key ships with the code
+func badSignToken(claims jwt.MapClaims) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("change-me-signing-key"))
+}
+
+// GOOD: The key comes from the environment
+func goodSignToken(claims jwt.MapClaims) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(os.Getenv("JWT_SIGNING_KEY")))
+}
+
+// ==========================================
+// RULE 39: Issue and Check JWT Expiry
+// Why: A token without an expiry stays valid forever once leaked
+// ==========================================
+
+// BAD: The session token never expires
+func badSessionToken(userID string, key []byte) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": userID}).SignedString(key)
+}
+
+// GOOD: Short-lived, with the expiry in the claims
+func goodSessionToken(userID string, key []byte) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": userID,
+		"exp": time.Now().Add(15 * time.Minute).Unix(),
+	}).SignedString(key)
+}
+
 // Helper functions
 func processData() (string, error) {
 	return "data", nil
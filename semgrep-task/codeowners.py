@@ -0,0 +1,68 @@
+import re
+from pathlib import Path
+
+CODEOWNERS_LOCATIONS = ['.github/CODEOWNERS', 'CODEOWNERS', 'docs/CODEOWNERS']
+
+
+def pattern_to_regex(pattern):
+    """Translates a CODEOWNERS (gitignore-style) pattern into a regex over repo-relative paths."""
+    anchored = pattern.startswith('/') or '/' in pattern.rstrip('/')
+    directory = pattern.endswith('/')
+    # GitHub: "docs/*" owns docs/getting-started.md but not docs/build-app/troubleshooting.md
+    one_level = pattern.endswith('/*') and not pattern.endswith('**/*')
+    pattern = pattern.strip('/')
+
+    regex = ''
+    i = 0
+    while i < len(pattern):
+        if pattern.startswith('**/', i):
+            regex += '(?:.*/)?'
+            i += 3
+        elif pattern.startswith('**', i):
+            regex += '.*'
+            i += 2
+        elif pattern[i] == '*':
+            regex += '[^/]*'
+            i += 1
+        elif pattern[i] == '?':
+            regex += '[^/]'
+            i += 1
+        else:
+            regex += re.escape(pattern[i])
+            i += 1
+
+    prefix = '^' if anchored else '^(?:.*/)?'
+    # A match on a directory also owns everything below it
+    suffix = '$' if one_level else '/.*$' if directory else '(?:/.*)?$'
+    return re.compile(prefix + regex + suffix)
+
+
+class CodeOwners:
+    def __init__(self, rules):
+        self.rules = rules  # [(regex, [owners])] in file order
+
+    @classmethod
+    def load(cls, repo_root):
+        for location in CODEOWNERS_LOCATIONS:
+            path = Path(repo_root) / location
+            if path.is_file():
+                return cls.parse(path.read_text(encoding='utf-8', errors='ignore'))
+        return cls([])
+
+    @classmethod
+    def parse(cls, text):
+        rules = []
+        for line in text.splitlines():
+            line = line.split('#', 1)[0].strip()
+            if not line:
+                continue
+            pattern, *owners = line.split()
+            rules.append((pattern_to_regex(pattern), owners))
+        return cls(rules)
+
+    def owners_of(self, path):
+        """Owners of a repo-relative path; the last matching line wins, like on GitHub."""
+        for regex, owners in reversed(self.rules):
+            if regex.match(path):
+                return owners
+        return []
@@ -0,0 +1,95 @@
+import re
+import shlex
+import shutil
+import subprocess
+
+RULE_ID = 'COMMENTED-OUT-CODE'
+
+# Lines that read like Go statements or declarations rather than prose
+CODE_LINE = re.compile(r'''^(?:
+    (?:if|for|switch|select|return|defer|go|func|var|const|type|import|package|case|default|else|break|continue)\b
+  | [})\]]+[,;)]*\s*(?:else\b.*)?$                 # closing a block or a call
+  | .*[{(\[,]$                                      # opening one
+  | [\w.\[\]*,\s]+(?::=|[-+*/|&^]?=(?!=)|\+\+|--)   # assignment, :=, ++
+  | [\w.\[\]]+\(.*\)$                               # a call on its own
+  | "[^"]*"$|`.*                                    # import paths, raw strings
+)''', re.X)
+# Comment lines that are never code: directives and example outputs
+DIRECTIVE = re.compile(r'^(?:go:|\+build|nolint|lint:|Output:|Unordered output:)')
+
+
+def comment_blocks(text):
+    """(first line, last line, contents) of the runs of consecutive // comment lines, the // and one space
+    removed from each."""
+    blocks, current = [], None
+    for number, line in enumerate(text.splitlines(), start=1):
+        stripped = line.strip()
+        if stripped.startswith('//') and not stripped.startswith('///'):
+            content = stripped[2:]
+            content = content[1:] if content.startswith(' ') else content
+            if current and current[1] == number - 1:
+                current[1] = number
+                current[2].append(content)
+            else:
+                current = [number, number, [content]]
+                blocks.append(current)
+        else:
+            current = None
+    return blocks
+
+
+def is_code(line):
+    return CODE_LINE.match(line.strip()) is not None and not re.search(r'[.?!:]$', line.strip())
+
+
+def code_span(lines):
+    """(first, last) indexes of the code-like lines of a block, a comment introducing the code aside;
+    None without any."""
+    code = [i for i, line in enumerate(lines) if is_code(line)]
+    return (code[0], code[-1]) if code else None
+
+
+def looks_like_code(lines, min_lines):
+    """Whether comment lines are mostly Go code: enough code-like lines, hardly any prose and balanced
+    brackets."""
+    body = [l.strip() for l in lines if l.strip()]
+    if len(body) < min_lines or any(DIRECTIVE.match(l) for l in body):
+        return False
+    code = sum(1 for l in body if is_code(l))
+    if code < min_lines or code < 0.8 * len(body):
+        return False
+    joined = re.sub(r'"(?:\\.|[^"\\])*"|`[^`]*`|\'(?:\\.|[^\'\\])*\'', '""', '\n'.join(body))
+    return all(joined.count(a) == joined.count(b) for a, b in ('{}', '()', '[]'))
+
+
+def parses(lines, command):
+    """Whether gofmt accepts the lines as declarations or as a function body; None without gofmt."""
+    gofmt = shlex.split(command)
+    if not shutil.which(gofmt[0]):
+        return None
+    code = '\n'.join(lines)
+    for source in (f"package p\n{code}\n", f"package p\nfunc _() {{\n{code}\n}}\n"):
+        res = subprocess.run(gofmt + ['-e'], input=source, capture_output=True, text=True, encoding='utf-8')
+        if res.returncode == 0:
+            return True
+    return False
+
+
+def commented_out_code(text, options):
+    """(line, message) of the blocks of at least commented_code.min_lines commented-out Go code lines. Blocks
+    are picked by how their lines read and confirmed with gofmt when it is installed, so prose and doc
+    comment examples with explanations around them are left alone."""
+    found = []
+    for first, last, lines in comment_blocks(text):
+        span = code_span(lines)
+        if span is None:
+            continue
+        first, last, lines = first + span[0], first + span[1], lines[span[0]:span[1] + 1]
+        if not looks_like_code(lines, options['min_lines']):
+            continue
+        if parses(lines, options['gofmt']) is False:
+            continue
+        found.append((first, f"Lines {first}-{last} are commented-out code ({last - first + 1} lines). Delete "
+                             f"them: git keeps the old version, and commented code goes stale without the "
+                             f"compiler noticing"))
+    return found
@@ -0,0 +1,66 @@
+import re
+import subprocess
+
+# rule id -> (severity, category)
+RULES = {
+    'COMMIT-FORMAT': ('WARNING', 'commit-hygiene'),
+    'COMMIT-SUBJECT-LENGTH': ('WARNING', 'commit-hygiene'),
+    'COMMIT-ISSUE-REF': ('WARNING', 'commit-hygiene'),
+}
+RULE_IDS = list(RULES)
+
+# Findings on commit messages are reported under this path, as Gerrit does
+COMMIT_PATH = 'COMMIT_MSG'
+
+CONVENTIONAL = re.compile(r'^(?P<type>\w+)(\((?P<scope>[^()]+)\))?(?P<breaking>!)?: \S')
+# Messages git writes itself, and fixups that are squashed before merging
+GENERATED = re.compile(r'^(Merge (branch|pull request|remote-tracking branch|tag) |Revert "|(fixup|squash|amend)! )')
+
+
+def range_commits(repo_dir, commit_range):
+    """(sha, author, message) of the non-merge commits of a range, oldest first; None when git can't read it."""
+    res = subprocess.run(['git', 'log', '--no-merges', '--reverse', '--format=%H%x00%an <%ae>%x00%B%x1e',
+                          commit_range], cwd=repo_dir, capture_output=True, text=True, encoding='utf-8',
+                         errors='replace')
+    if res.returncode != 0:
+        return None
+    commits = []
+    for entry in res.stdout.split('\x1e'):
+        if entry.strip():
+            sha, author, message = entry.strip('\n').split('\0', 2)
+            commits.append((sha, author, message.strip('\n')))
+    return commits
+
+
+def message_problems(message, options):
+    """(line, rule id, message) of one commit message against the commits options."""
+    lines = message.splitlines() or ['']
+    subject = lines[0]
+    if GENERATED.match(subject):
+        return []
+    problems = []
+    if options['conventional']:
+        match = CONVENTIONAL.match(subject)
+        if not match:
+            problems.append((1, 'COMMIT-FORMAT', "Subject is not a conventional commit: use "
+                                                 "\"type(scope): description\", e.g. \"fix(api): handle empty body\""))
+        elif options['types'] and match.group('type') not in options['types']:
+            problems.append((1, 'COMMIT-FORMAT', f"Unknown commit type {match.group('type')!r} "
+                                                 f"(expected one of: {', '.join(options['types'])})"))
+    if options['max_subject'] and len(subject) > options['max_subject']:
+        problems.append((1, 'COMMIT-SUBJECT-LENGTH', f"Subject is {len(subject)} characters long, "
+                                                      f"keep it to {options['max_subject']}"))
+    if options['issue_pattern'] and not re.search(options['issue_pattern'], message):
+        problems.append((1, 'COMMIT-ISSUE-REF', f"No issue reference (matching {options['issue_pattern']}) "
+                                                f"in the message"))
+    return problems
+
+
+def commit_problems(repo_dir, options):
+    """(sha, author, line, rule id, message) for the commits of options['range']; None when the range is
+    not readable (no upstream, unknown revision...)."""
+    commits = range_commits(repo_dir, options['range'])
+    if commits is None:
+        return None
+    return [(sha, author, line, rule_id, f"{sha[:12]} \"{message.splitlines()[0] if message else ''}\": {text}")
+            for sha, author, message in commits for line, rule_id, text in message_problems(message, options)]
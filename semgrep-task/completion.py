@@ -0,0 +1,122 @@
+import json
+import argparse
+
+PROGRAMS = ['auto-review', 'auto-review.py']
+
+SHELLS = ['bash', 'zsh', 'fish', 'powershell']
+
+
+def flatten_keys(config, prefix=''):
+    """Dotted config keys (rules.disable, output.format...) for completion and validation messages."""
+    keys = []
+    for key, value in config.items():
+        keys.append(prefix + key)
+        if isinstance(value, dict) and value:
+            keys += flatten_keys(value, prefix + key + '.')
+    return keys
+
+
+def subcommands(parser):
+    action = next((a for a in parser._actions if isinstance(a, argparse._SubParsersAction)), None)
+    return action.choices if action else {}
+
+
+def completion_data(parser, dynamic):
+    """{command: {options, values: {option: words}, positional: words}} read from the argparse parser.
+
+    dynamic maps an argument's dest (rule_id, only_rules...) to extra words such as rule ids.
+    """
+    data = {}
+    for name, sub in subcommands(parser).items():
+        entry = {'options': [], 'values': {}, 'positional': []}
+        parsers = [sub] + list(subcommands(sub).values())
+        entry['positional'] += list(subcommands(sub))
+        for p in parsers:
+            for action in p._actions:
+                if isinstance(action, (argparse._HelpAction, argparse._SubParsersAction)):
+                    continue
+                words = [str(c) for c in action.choices] if action.choices else dynamic.get(action.dest, [])
+                if action.option_strings:
+                    entry['options'] += [o for o in action.option_strings if o.startswith('--')]
+                    if words:
+                        for option in action.option_strings:
+                            entry['values'][option] = words
+                else:
+                    entry['positional'] += words
+        entry['options'] = sorted(set(entry['options']))
+        data[name] = entry
+    return data
+
+
+def bash_script(data):
+    lines = ['_auto_review() {',
+             '    local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}" cmd="${COMP_WORDS[1]}"',
+             '    if [ "$COMP_CWORD" -eq 1 ]; then',
+             f'        COMPREPLY=($(compgen -W "{" ".join(data)}" -- "$cur")); return',
+             '    fi',
+             '    case "$cmd $prev" in']
+    for name, entry in data.items():
+        for option, words in entry['values'].items():
+            lines.append(f'        "{name} {option}") COMPREPLY=($(compgen -W "{" ".join(words)}" -- "$cur")); return ;;')
+    lines += ['    esac', '    local opts="" words=""', '    case "$cmd" in']
+    for name, entry in data.items():
+        lines.append(f'        {name}) opts="{" ".join(entry["options"])}"; words="{" ".join(entry["positional"])}" ;;')
+    lines += ['    esac',
+              '    if [[ "$cur" == -* ]]; then',
+              '        COMPREPLY=($(compgen -W "$opts" -- "$cur"))',
+              '    else',
+              '        COMPREPLY=($(compgen -W "$words" -- "$cur") $(compgen -f -- "$cur"))',
+              '    fi',
+              '}',
+              f'complete -F _auto_review {" ".join(PROGRAMS)}']
+    return '\n'.join(lines) + '\n'
+
+
+def zsh_script(data):
+    return 'autoload -U +X bashcompinit && bashcompinit\n' + bash_script(data)
+
+
+def fish_script(data):
+    lines = []
+    for program in PROGRAMS:
+        lines.append(f'complete -c {program} -f -n "__fish_use_subcommand" -a "{" ".join(data)}"')
+        for name, entry in data.items():
+            seen = f'-n "__fish_seen_subcommand_from {name}"'
+            if entry['positional']:
+                lines.append(f'complete -c {program} {seen} -a "{" ".join(entry["positional"])}"')
+            for option in entry['options']:
+                words = entry['values'].get(option)
+                values = f' -xa "{" ".join(words)}"' if words else ''
+                lines.append(f'complete -c {program} {seen} -l {option[2:]}{values}')
+    return '\n'.join(lines) + '\n'
+
+
+def powershell_script(data):
+    return f"""$autoReviewData = ConvertFrom-Json @'
+{json.dumps(data)}
+'@
+Register-ArgumentCompleter -Native -CommandName {', '.join(PROGRAMS)} -ScriptBlock {{
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = @($commandAst.CommandElements | ForEach-Object {{ $_.ToString() }})
+    if ($wordToComplete) {{ $words = $words[0..($words.Count - 2)] }}
+    if ($words.Count -le 1) {{
+        $candidates = $autoReviewData.PSObject.Properties.Name
+    }} else {{
+        $entry = $autoReviewData.($words[1])
+        $prev = $words[-1]
+        if ($entry -and $entry.values.$prev) {{ $candidates = $entry.values.$prev }}
+        elseif ($wordToComplete -like '-*') {{ $candidates = $entry.options }}
+        else {{ $candidates = $entry.positional }}
+    }}
+    $candidates | Where-Object {{ $_ -like "$wordToComplete*" }} | ForEach-Object {{
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }}
+}}
+"""
+
+
+RENDERERS = {'bash': bash_script, 'zsh': zsh_script, 'fish': fish_script, 'powershell': powershell_script}
+
+
+def completion_script(shell, parser, dynamic):
+    return RENDERERS[shell](completion_data(parser, dynamic))
@@ -0,0 +1,335 @@
+import re
+import copy
+import functools
+from pathlib import Path
+
+CONFIG_NAMES = ['.codereview.yaml', '.codereview.yml']
+
+SYMLINK_POLICIES = ['skip', 'root', 'follow']
+
+DEFAULT_CONFIG = {
+    'rules': {
+        'only': [],      # when set, only these rule ids are reported
+        'disable': [],   # rule ids that are never reported
+        'custom': [],    # extra Semgrep rule files, relative to the config file's folder
+    },
+    'severity': {},      # rule id -> ERROR / WARNING / INFO
+    'exclude': [],       # glob patterns, relative to the config file's folder
+    'imports': [],       # import policies: {import, paths, allow, severity, message}, globs relative to the config
+    'suppressions': None,  # older triage decisions file, default .codereview-suppressions.json (still applied)
+    'blame': False,      # attach the author, commit and date that introduced each finding (git blame)
+    'history': None,     # SQLite findings history, relative to the config; when set every scan is recorded
+    'gitignore': True,   # skip files ignored by git when the scanned folder is a repository
+    'nested': True,      # also apply .codereview.yaml files found in sub-folders
+    'max_file_size': 1024 * 1024,  # bytes, larger files are skipped (0 for no limit)
+    'symlinks': 'root',  # skip, root (follow links pointing inside the scanned folder) or follow
+    'vendor': False,     # also scan vendored dependencies (vendor/, third_party/, node_modules/...)
+    'output': {
+        'excel': True,
+        'format': None,
+        'file': None,
+        'publish': [],
+        'notify': [],
+        'max_findings': None,  # per rule, like --max-findings
+    },
+    'autofix': {
+        'close_errors': 'ignore',  # defer x.Close() added by --fix: ignore its error, or log it (adds the "log" import if missing)
+    },
+    'secrets': {
+        'enabled': True,     # scan for known token formats and high-entropy string literals
+        'entropy': 4.0,      # bits per character above which a string literal can be a secret
+        'entropy_hex': 3.0,  # the same for hex strings, whose alphabet is smaller
+        'min_length': 16,    # shorter literals are never reported
+        'proximity': 2,      # lines above a literal searched for secret-like names (token, password...)
+        'min_score': 0.5,    # 0..1, entropy excess plus keyword proximity
+        'verify': False,     # check GitHub and Slack tokens against their API before raising severity
+        'verify_command': None,  # or run this hook: token on stdin, $SECRET_RULE_ID, exit 0 live / 1 rejected
+        'history_depth': 0,  # also scan what the last N commits added, for secrets removed since (--depth)
+    },
+    'kubernetes': {
+        # folders or files whose YAML is checked as Kubernetes manifests, globs relative to the config
+        'paths': ['k8s', 'kubernetes', 'deploy', 'manifests', '*/k8s', '*/kubernetes', '*/deploy', '*/manifests'],
+    },
+    'struct_layout': {
+        'enabled': False,    # suggest a field order for Go structs that waste bytes on padding (64-bit layout)
+        'min_saving': 8,     # bytes per instance below which a struct is left alone
+        'paths': [],         # only these performance-sensitive packages, globs relative to the config; all if empty
+    },
+    'struct_fields': {
+        'enabled': True,     # flag Go structs with too many fields, a sign of a type doing several jobs
+        'max_fields': 15,
+        'exclude': ['*DTO', '*Dto', '*Request', '*Response'],  # struct names or globs never reported
+        'skip_tagged': True,  # leave out structs whose fields are mostly tagged (json, db...): data transfer types
+    },
+    'deprecations': {
+        'enabled': True,     # flag Go code using packages and symbols documented as "Deprecated:"
+        'command': 'go',     # asked for GOROOT and GOMODCACHE, to read the standard library and dependencies
+    },
+    'todos': {
+        'enabled': True,     # report TODO/FIXME/HACK comments as INFO, aged with git blame
+        'markers': ['TODO', 'FIXME', 'HACK', 'XXX'],
+        'max_age_days': 90,  # older ones become WARNING; 0 never
+        'require_issue': True,  # ones not matching issue_pattern become WARNING
+        'issue_pattern': r'#\d+|\b[A-Z][A-Z0-9]+-\d+\b|/issues/\d+',  # GitHub #123, Jira ABC-123 or an issue URL
+    },
+    'naming': {
+        'enabled': True,     # check Go names: underscores, initialism casing, package names, test functions
+        'acronyms': [],      # initialisms added to ID, URL, HTTP, JSON, API... (golint's list)
+        'exceptions': [],    # names or globs never reported, e.g. XXX_* or a package kept for compatibility
+        'max_package_length': 12,  # characters; 0 for no limit
+    },
+    'recovery': {
+        'enabled': True,     # flag Go HTTP servers whose package has no panic recovery middleware
+        'middleware': [],    # names of the project's own recovery middleware, besides the frameworks' ones
+    },
+    'flag_parameters': {
+        'enabled': True,     # flag exported Go functions taking bool parameters they branch on
+        'min_flags': 1,      # bool flags a function needs before it is reported
+    },
+    'commented_code': {
+        'enabled': True,     # flag blocks of commented-out Go code, history lives in git
+        'min_lines': 5,      # code lines in one comment block before it is reported
+        'gofmt': 'gofmt',    # confirms the block parses as Go; without it the line heuristics decide
+    },
+    'table_tests': {
+        'enabled': True,     # suggest a table-driven test for Go tests repeating an assertion block with other values
+        'min_repeats': 3,    # repetitions of the block before it is reported
+    },
+    'shellcheck': {
+        'enabled': True,     # run shellcheck on *.sh, *.bash and *.ksh scripts when it is installed
+        'command': 'shellcheck',
+        'severity': 'style', # lowest shellcheck level reported: error, warning, info or style
+    },
+    'cvss': {
+        'enabled': True,     # CVSS v3.1 base score, vector and exploitability notes on security findings
+        'vectors': {},       # rule id or CWE-n -> CVSS:3.1/AV:.../A:... vector, over the rule's and the CWE's
+    },
+    'vulncheck': {
+        'enabled': False,    # run govulncheck on every discovered Go module (like scan --vulncheck)
+        'scan': 'symbol',    # symbol: reachable vulnerable code (ERROR), package: imported (WARNING), module: required (INFO)
+        'command': 'govulncheck',
+    },
+    'licenses': {
+        'enabled': False,    # check the license of every Go dependency (like scan --licenses)
+        'allow': ['MIT', 'Apache-2.0', 'BSD-2-Clause', 'BSD-3-Clause', 'ISC', 'MPL-2.0', 'Unlicense', 'CC0-1.0'],
+        'ignore': [],        # module path globs not checked, e.g. your own example.com/*
+        'unknown': 'WARNING',  # severity of dependencies without a recognizable license, null to not report them
+    },
+    'commits': {
+        'enabled': False,    # lint the commit messages of the scanned range (like scan --lint-commits)
+        'range': '@{upstream}..HEAD',  # commits linted when scan has no --push-range
+        'conventional': True,  # subjects must be "type(scope)!: description"
+        'types': ['feat', 'fix', 'docs', 'style', 'refactor', 'perf', 'test', 'build', 'ci', 'chore', 'revert'],
+        'max_subject': 72,   # characters, 0 for no limit
+        'issue_pattern': None,  # regex every message must match, e.g. '#\d+|[A-Z]+-\d+'
+    },
+    'ai': {
+        'provider': None,    # openai, azure or ollama; --explain-ai / --suggest-fixes need it, nothing is sent otherwise
+        'model': None,       # provider default when unset; the deployment name for azure
+        'url': None,         # API base URL: OpenAI-compatible server, Azure resource endpoint or Ollama server
+        'api_version': None,  # azure only, default 2024-06-01
+        'api_key_env': None,  # environment variable holding the API key (default OPENAI_API_KEY / AZURE_OPENAI_API_KEY)
+        'send_code': True,   # include the code around the finding, else only the rule and message
+        'context_lines': 4,  # lines of code sent before and after the finding
+        'redact': True,      # mask secrets before sending; "strings" also masks every string literal
+        'max_findings': 20,  # provider requests per scan, the remaining findings are left alone
+        'suggest_categories': [],  # rule categories --suggest-fixes asks patches for (empty: all)
+    },
+    'metrics': {
+        'min_maintainability': 0,  # functions with a maintainability index (0-100) below it are reported, 0 for none
+        'coupling': False,   # check the import graph of the scanned Go packages for cycles and unstable packages
+        'max_instability': 0.8,  # imported packages with a higher Ce / (Ca + Ce) are reported, null for none
+    },
+    'coverage': {
+        'profile': None,     # go test -coverprofile output, relative to the config (like --coverage)
+        'max_crap': 30,      # Go functions whose CRAP score (complexity and missing coverage) is higher are reported
+    },
+    'debt': {
+        'severity': {'ERROR': 3, 'WARNING': 2, 'INFO': 1},  # weight per severity in the debt score
+        'effort': {},        # rule id or category -> minutes to fix one finding, over the built-in estimates
+    },
+    'gate': {
+        'fail_on': None,     # shorthand for the condition "total <SEVERITY>+ > 0"
+        'conditions': [],    # e.g. "new ERROR > 0", "total WARNING+ > 10", "new category:security > 0"
+        'baseline': None,    # findings JSON of the target branch, to tell new findings from existing ones
+    },
+}
+
+
+def find_config(start):
+    """Walks up from the scanned folder to the filesystem root looking for a config file."""
+    start = Path(start).resolve()
+    folder = start if start.is_dir() else start.parent
+    for candidate in [folder] + list(folder.parents):
+        for name in CONFIG_NAMES:
+            if (candidate / name).is_file():
+                return candidate / name
+    return None
+
+
+def merge(base, override):
+    merged = copy.deepcopy(base)
+    for key, value in (override or {}).items():
+        if isinstance(value, dict) and isinstance(merged.get(key), dict):
+            merged[key] = merge(merged[key], value)
+        else:
+            merged[key] = value
+    return merged
+
+
+def load_layer(config, path):
+    """Overlays one config file. Exclude globs are anchored to the file's folder so they can accumulate."""
+    import yaml  # only needed when a config file exists
+    with open(path, encoding='utf-8') as f:
+        layer = yaml.safe_load(f) or {}
+    root = Path(path).resolve().parent
+    excludes = config['exclude'] + anchor_excludes(layer.pop('exclude', None) or [], root)
+    # Import policies of nested configs add to the outer ones, like excludes
+    policies = config['imports'] + [{**policy, 'paths': anchor_excludes(policy.get('paths') or [], root),
+                                     'allow': anchor_excludes(policy.get('allow') or [], root)}
+                                    for policy in layer.pop('imports', None) or [] if isinstance(policy, dict)]
+    custom = (layer.get('rules') or {}).get('custom')
+    if custom:
+        layer['rules']['custom'] = [str(root / rule_file) for rule_file in custom]
+    manifests = (layer.get('kubernetes') or {}).get('paths')
+    if manifests:
+        layer['kubernetes']['paths'] = anchor_excludes(manifests, root)
+    packages = (layer.get('struct_layout') or {}).get('paths')
+    if packages:
+        layer['struct_layout']['paths'] = anchor_excludes(packages, root)
+    profile = (layer.get('coverage') or {}).get('profile')
+    if profile:
+        layer['coverage']['profile'] = str(root / profile)
+    config = merge(config, layer)
+    config['exclude'] = excludes
+    config['imports'] = policies
+    config['root'] = root
+    config['layers'] = config.get('layers', []) + [str(Path(path).resolve())]
+    return config
+
+
+def load_config(path):
+    """Defaults overlaid with the YAML file at path (if any). The file's folder is kept as 'root'
+    and the config files applied so far as 'layers'."""
+    config = copy.deepcopy(DEFAULT_CONFIG)
+    config['root'] = None
+    config['layers'] = []
+    return load_layer(config, path) if path else config
+
+
+def set_overrides(config, overrides):
+    """Sets dotted keys (secrets.history_depth) given by command-line flags. They are kept as 'overrides' and
+    set again over every nested config file, so flags win over all of them."""
+    config['overrides'] = {**config.get('overrides', {}), **overrides}
+    for key, value in overrides.items():
+        *parents, name = key.split('.')
+        section = config
+        for parent in parents:
+            section = section[parent]
+        section[name] = value
+    return config
+
+
+class ConfigTree:
+    """Per-folder configuration for nested .codereview.yaml files.
+
+    The top config (found at or above the scanned folder) is applied first, then every config file
+    between it and the file's folder, outermost first, so the deepest one wins.
+    """
+
+    def __init__(self, config, scan_root):
+        self.config = config
+        scan_root = Path(scan_root).resolve()
+        root = config['root']
+        self.top = root if root and (root == scan_root or root in scan_root.parents) else scan_root
+        self.cache = {}
+
+    def for_path(self, file_path):
+        folder = Path(file_path).resolve().parent
+        if not self.config['nested'] or (folder != self.top and self.top not in folder.parents):
+            return self.config
+        if folder not in self.cache:
+            config = self.for_path(folder) if folder != self.top else self.config
+            if folder != self.top:
+                layer = next((folder / n for n in CONFIG_NAMES if (folder / n).is_file()), None)
+                config = set_overrides(load_layer(config, layer), config.get('overrides', {})) if layer else config
+            self.cache[folder] = config
+        return self.cache[folder]
+
+
+def rule_matches(check_id, rule_id):
+    """Semgrep prefixes rule ids with the rule file's location (e.g. rules.go-rule-3-avoid-panic)."""
+    return check_id == rule_id or check_id.endswith('.' + rule_id)
+
+
+def rule_enabled(config, check_id):
+    if any(rule_matches(check_id, r) for r in config['rules']['disable']):
+        return False
+    only = config['rules']['only']
+    return not only or any(rule_matches(check_id, r) for r in only)
+
+
+def severity_override(config, check_id, severity):
+    for rule_id, override in config['severity'].items():
+        if rule_matches(check_id, rule_id):
+            return str(override).upper()
+    return severity
+
+
+@functools.lru_cache(maxsize=None)
+def glob_regex(pattern):
+    """A path glob read like .gitignore: * and ? stay within one folder name, ** spans folders."""
+    regex, i = '', 0
+    while i < len(pattern):
+        if pattern.startswith('**/', i):
+            regex, i = regex + '(?:.*/)?', i + 3
+            continue
+        if pattern.startswith('**', i):
+            regex, i = regex + '.*', i + 2
+            continue
+        char = pattern[i]
+        end = pattern.find(']', i + 2) if char == '[' else -1
+        if char == '*':
+            regex += '[^/]*'
+        elif char == '?':
+            regex += '[^/]'
+        elif end > 0:
+            members = pattern[i + 1:end]
+            regex += '[' + ('^' + members[1:] if members[0] in '!^' else members).replace('\\', '\\\\') + ']'
+            i = end
+        else:
+            regex += re.escape(char)
+        i += 1
+    return re.compile(regex + r'\Z')
+
+
+def matches_globs(file_path, patterns):
+    """Whether the path, or one of its folders, matches one of the (anchored) globs."""
+    path = Path(file_path).resolve()
+    candidates = [path.as_posix()] + [parent.as_posix() for parent in path.parents]
+    return any(glob_regex(pattern).match(c) for c in candidates for pattern in patterns)
+
+
+def is_excluded(config, file_path):
+    """A path is excluded when it, or one of its folders, matches an exclude glob."""
+    return matches_globs(file_path, config['exclude'])
+
+
+# Folders holding third-party code, skipped unless vendor is enabled
+VENDOR_DIRS = {'vendor', 'third_party', 'third-party', 'node_modules', 'bower_components', 'Godeps'}
+
+
+def is_vendored(file_path, base_dir):
+    """A path is vendored when one of its folders below the scanned folder is a dependency tree."""
+    try:
+        parts = Path(file_path).resolve().relative_to(Path(base_dir).resolve()).parts
+    except ValueError:
+        return False
+    return any(part in VENDOR_DIRS for part in parts)
+
+
+def anchor_excludes(patterns, folder):
+    """Globs made absolute from folder; as in .gitignore, one without a slash (testdata, *.pb.go) matches at any
+    depth below it."""
+    return [(Path(folder).resolve() / ('' if '/' in pattern.strip('/') else '**') / pattern.rstrip('/')).as_posix()
+            for pattern in patterns]
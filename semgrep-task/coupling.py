@@ -0,0 +1,128 @@
+from pathlib import Path
+
+from imports import go_imports
+
+# rule id -> (severity, category)
+RULES = {
+    'IMPORT-CYCLE': ('ERROR', 'architecture'),
+    'PACKAGE-INSTABILITY': ('WARNING', 'architecture'),
+}
+RULE_IDS = list(RULES)
+
+
+def package_of(file_path, modules):
+    """Import path of the package a Go file belongs to, None outside any module."""
+    file_path = Path(file_path).resolve()
+    for folder in file_path.parents:
+        if folder in modules.modules:
+            relative = file_path.parent.relative_to(folder).as_posix()
+            return modules.modules[folder] + ('' if relative == '.' else '/' + relative)
+    return None
+
+
+def import_graph(files, modules):
+    """{package: {imported package: (file, line) of its first import}} between the packages of the given Go
+    files. Test files are left out: external test packages import the package they test."""
+    packages = {}
+    for file_path in sorted(Path(f) for f in files):
+        if file_path.suffix == '.go' and not file_path.name.endswith('_test.go'):
+            package = package_of(file_path, modules)
+            if package:
+                packages.setdefault(package, []).append(file_path)
+    graph = {}
+    for package, package_files in packages.items():
+        edges = graph.setdefault(package, {})
+        for file_path in package_files:
+            text = file_path.read_text(encoding='utf-8', errors='ignore')
+            for line, path in go_imports(text):
+                if path in packages and path != package:
+                    edges.setdefault(path, (file_path, line))
+    return graph
+
+
+def coupling_metrics(graph):
+    """Afferent (imported by) and efferent (imports) coupling and instability Ce / (Ca + Ce) per package."""
+    afferent = {package: 0 for package in graph}
+    for edges in graph.values():
+        for imported in edges:
+            afferent[imported] += 1
+    metrics = {}
+    for package, edges in graph.items():
+        ca, ce = afferent[package], len(edges)
+        metrics[package] = {'afferent': ca, 'efferent': ce,
+                            'instability': round(ce / (ca + ce), 2) if ca + ce else 0.0}
+    return metrics
+
+
+def import_cycles(graph):
+    """The strongly connected components of more than one package (Tarjan's algorithm, without recursion)."""
+    index, low, on_stack, stack, clusters = {}, {}, set(), [], []
+    for root in graph:
+        if root in index:
+            continue
+        work = [(root, iter(sorted(graph[root])))]
+        index[root] = low[root] = len(index)
+        stack.append(root)
+        on_stack.add(root)
+        while work:
+            package, edges = work[-1]
+            imported = next(edges, None)
+            if imported is not None:
+                if imported not in index:
+                    index[imported] = low[imported] = len(index)
+                    stack.append(imported)
+                    on_stack.add(imported)
+                    work.append((imported, iter(sorted(graph[imported]))))
+                elif imported in on_stack:
+                    low[package] = min(low[package], index[imported])
+                continue
+            work.pop()
+            if work:
+                low[work[-1][0]] = min(low[work[-1][0]], low[package])
+            if low[package] == index[package]:
+                cluster = []
+                while True:
+                    member = stack.pop()
+                    on_stack.discard(member)
+                    cluster.append(member)
+                    if member == package:
+                        break
+                if len(cluster) > 1:
+                    clusters.append(sorted(cluster))
+    return sorted(clusters)
+
+
+def shortest_cycle(graph, package, cluster):
+    """The shortest chain of imports leading from package back to itself inside its cluster."""
+    paths, seen = [[package]], {package}
+    while paths:
+        path = paths.pop(0)
+        for imported in sorted(graph[path[-1]]):
+            if imported == package:
+                return path + [package]
+            if imported in cluster and imported not in seen:
+                seen.add(imported)
+                paths.append(path + [imported])
+    return [package, package]
+
+
+def coupling_problems(graph, max_instability):
+    """(file, line, rule id, message) of the import cycles, at the import starting each member's cycle, and of
+    depended-upon packages less stable than max_instability, at their first import of another package."""
+    problems = []
+    for cluster in import_cycles(graph):
+        for package in cluster:
+            cycle = shortest_cycle(graph, package, set(cluster))
+            file_path, line = graph[package][cycle[1]]
+            problems.append((file_path, line, 'IMPORT-CYCLE',
+                             f"Import cycle: {' -> '.join(cycle)}. Go refuses to build it; move the code they share "
+                             f"to a package they can all import"))
+    if max_instability is not None:
+        for package, m in coupling_metrics(graph).items():
+            if m['afferent'] and m['instability'] > max_instability:
+                file_path, line = min(graph[package].values())
+                problems.append((file_path, line, 'PACKAGE-INSTABILITY',
+                                 f"Package {package} has instability {m['instability']} (imported by "
+                                 f"{m['afferent']}, imports {m['efferent']} of the scanned packages), above "
+                                 f"{max_instability}. Packages others depend on should depend on little"))
+    return problems
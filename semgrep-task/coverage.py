@@ -0,0 +1,62 @@
+import re
+from pathlib import Path
+
+RULE_ID = 'COVERAGE-RISK'
+
+# file:startLine.startCol,endLine.endCol statements count
+BLOCK = re.compile(r'^(.+\.go):(\d+)\.\d+,(\d+)\.\d+ (\d+) (\d+)$')
+
+
+def read_profile(profile, modules):
+    """{local file: [(start line, end line, statements, hit count)]} of a `go test -coverprofile` file. Files
+    are named by import path and mapped to the discovered modules; absolute and relative paths also work."""
+    blocks = {}
+    roots = sorted(modules.modules.items(), key=lambda item: -len(item[1]))  # innermost module first
+    for line in Path(profile).read_text(encoding='utf-8', errors='ignore').splitlines():
+        match = BLOCK.match(line.strip())
+        if not match:
+            continue  # the "mode:" line
+        name = match.group(1)
+        local = next((folder / name[len(path) + 1:] for folder, path in roots if name.startswith(path + '/')), None)
+        if local is None:
+            local = Path(name) if Path(name).is_absolute() else Path(profile).resolve().parent / name
+        blocks.setdefault(local.resolve(), []).append(tuple(int(match.group(i)) for i in range(2, 6)))
+    return blocks
+
+
+def covered_lines(blocks):
+    """(executed lines, lines with statements) of a file's blocks; a line counts as executed when any block
+    covering it ran, as go tool cover shows it."""
+    executed, statements = set(), set()
+    for start, end, count, hits in blocks:
+        lines = range(start, end + 1)
+        if count:
+            statements.update(lines)
+            if hits:
+                executed.update(lines)
+    return executed, statements
+
+
+def function_coverage(blocks, start, end):
+    """Share (0-1) of the statements of the blocks inside lines start-end that tests ran, None without any."""
+    total = sum(count for first, last, count, _ in blocks if first >= start and last <= end)
+    if not total:
+        return None
+    return sum(count for first, last, count, hits in blocks if first >= start and last <= end and hits) / total
+
+
+def crap(complexity, coverage):
+    """CRAP score (Change Risk Anti-Patterns): complexity^2 x (1 - coverage)^3 + complexity. A fully
+    tested function scores its complexity, an untested one its complexity squared."""
+    return round(complexity ** 2 * (1 - coverage) ** 3 + complexity, 1)
+
+
+def risky_functions(functions, blocks):
+    """The functions (from stats.function_metrics) of a file with their coverage and CRAP score, riskiest
+    first; functions without statements in the profile are left out."""
+    risky = []
+    for f in functions:
+        coverage = function_coverage(blocks, f['line'], f['end'])
+        if coverage is not None:
+            risky.append(dict(f, coverage=round(coverage * 100, 1), crap=crap(f['complexity'], coverage)))
+    return sorted(risky, key=lambda f: -f['crap'])
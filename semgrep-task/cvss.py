@@ -0,0 +1,114 @@
+import math
+import re
+
+from catalog import find_rule
+
+# CVSS v3.1 base metric weights (https://www.first.org/cvss/v3.1/specification-document)
+WEIGHTS = {
+    'AV': {'N': 0.85, 'A': 0.62, 'L': 0.55, 'P': 0.2},
+    'AC': {'L': 0.77, 'H': 0.44},
+    'UI': {'N': 0.85, 'R': 0.62},
+    'C': {'H': 0.56, 'L': 0.22, 'N': 0.0},
+    'I': {'H': 0.56, 'L': 0.22, 'N': 0.0},
+    'A': {'H': 0.56, 'L': 0.22, 'N': 0.0},
+}
+PRIVILEGES = {'U': {'N': 0.85, 'L': 0.62, 'H': 0.27}, 'C': {'N': 0.85, 'L': 0.68, 'H': 0.5}}
+VECTOR = re.compile(r'^CVSS:3\.[01]/AV:[NALP]/AC:[LH]/PR:[NLH]/UI:[NR]/S:[UC]/C:[HLN]/I:[HLN]/A:[HLN]$')
+
+# Typical base vector of a weakness, used when neither the rule nor the config gives one
+CWE_VECTORS = {
+    'CWE-78': 'CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H',    # OS command injection
+    'CWE-79': 'CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:C/C:L/I:L/A:N',    # cross-site scripting
+    'CWE-89': 'CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H',    # SQL injection
+    'CWE-284': 'CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:L/I:L/A:L',   # network exposure (open security group)
+    'CWE-311': 'CVSS:3.1/AV:N/AC:H/PR:N/UI:N/S:U/C:H/I:N/A:N',   # unencrypted storage
+    'CWE-95': 'CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H',    # eval injection
+    'CWE-327': 'CVSS:3.1/AV:N/AC:H/PR:N/UI:N/S:U/C:H/I:N/A:N',   # broken or risky crypto
+    'CWE-338': 'CVSS:3.1/AV:N/AC:H/PR:N/UI:N/S:U/C:H/I:N/A:N',   # weak PRNG
+    'CWE-347': 'CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:N',   # unverified signature (forged tokens)
+    'CWE-502': 'CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H',   # unsafe deserialization
+    'CWE-601': 'CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:C/C:L/I:L/A:N',   # open redirect
+    'CWE-613': 'CVSS:3.1/AV:N/AC:H/PR:N/UI:N/S:U/C:H/I:L/A:N',   # sessions that never expire
+    'CWE-798': 'CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:N',   # hardcoded credentials
+    'CWE-942': 'CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:U/C:H/I:L/A:N',   # permissive CORS policy
+    'CWE-1321': 'CVSS:3.1/AV:N/AC:H/PR:N/UI:N/S:U/C:L/I:L/A:L',  # prototype pollution
+    'CWE-1395': 'CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:L/I:L/A:L',  # vulnerable dependency
+}
+# Weakness of the tool's own checks
+BUILTIN_CWES = {'SECRET-': 'CWE-798', 'VULN-CHECK': 'CWE-1395', 'TF-OPEN-SECURITY-GROUP': 'CWE-284',
+                'TF-UNENCRYPTED-STORAGE': 'CWE-311', 'TF-HARDCODED-CREDENTIAL': 'CWE-798'}
+
+NOTES = {
+    'AV': {'N': "reachable over the network", 'A': "reachable from the adjacent network",
+           'L': "needs local access", 'P': "needs physical access"},
+    'AC': {'L': "low attack complexity", 'H': "high attack complexity"},
+    'PR': {'N': "no privileges required", 'L': "low privileges required", 'H': "high privileges required"},
+    'UI': {'N': "no user interaction", 'R': "needs user interaction"},
+    'S': {'U': "impact stays in the component", 'C': "impact crosses into other components"},
+}
+
+
+def roundup(value):
+    """CVSS v3.1 Roundup: the smallest one-decimal number >= value, avoiding float artifacts."""
+    scaled = round(value * 100000)
+    return scaled / 100000.0 if scaled % 10000 == 0 else (math.floor(scaled / 10000) + 1) / 10.0
+
+
+def metrics(vector):
+    return dict(part.split(':') for part in vector.split('/')[1:])
+
+
+def base_score(vector):
+    m = metrics(vector)
+    iss = 1 - (1 - WEIGHTS['C'][m['C']]) * (1 - WEIGHTS['I'][m['I']]) * (1 - WEIGHTS['A'][m['A']])
+    if m['S'] == 'U':
+        impact = 6.42 * iss
+    else:
+        impact = 7.52 * (iss - 0.029) - 3.25 * (iss - 0.02) ** 15
+    exploitability = 8.22 * WEIGHTS['AV'][m['AV']] * WEIGHTS['AC'][m['AC']] * PRIVILEGES[m['S']][m['PR']] * \
+        WEIGHTS['UI'][m['UI']]
+    if impact <= 0:
+        return 0.0
+    total = impact + exploitability if m['S'] == 'U' else 1.08 * (impact + exploitability)
+    return roundup(min(total, 10))
+
+
+def rating(score):
+    return 'None' if score == 0 else 'Low' if score < 4 else 'Medium' if score < 7 else 'High' if score < 9 \
+        else 'Critical'
+
+
+def exploitability_notes(vector):
+    m = metrics(vector)
+    return ', '.join(NOTES[key][m[key]] for key in ('AV', 'AC', 'PR', 'UI', 'S')).capitalize()
+
+
+def finding_vector(finding, rules, vectors):
+    """The finding's vector: its own (e.g. from the advisory), the config's for its rule or CWE, the rule's
+    metadata.cvss, or the typical one of its CWE."""
+    if finding.get('CVSS Vector'):
+        return finding['CVSS Vector']
+    rule = find_rule(rules, finding['Rule ID']) or {}
+    metadata = rule.get('metadata', {})
+    cwes = re.findall(r'CWE-\d+', str(metadata.get('cwe', '')))
+    cwes += [cwe for prefix, cwe in BUILTIN_CWES.items() if finding['Rule ID'].startswith(prefix)]
+    for key in [finding['Rule ID'], rule.get('id')] + cwes:
+        if key and key in vectors:
+            return vectors[key]
+    if metadata.get('cvss'):
+        return metadata['cvss']
+    return next((CWE_VECTORS[cwe] for cwe in cwes if cwe in CWE_VECTORS), None)
+
+
+def score_findings(findings, rules, options):
+    """Adds CVSS, CVSS Vector and Exploitability to security findings that have a vector."""
+    for finding in findings:
+        if finding.get('Category') != 'security':
+            continue
+        vector = finding_vector(finding, rules, options['vectors'])
+        if not vector or not VECTOR.match(vector):
+            continue
+        score = base_score(vector)
+        finding['CVSS'] = score
+        finding['CVSS Vector'] = vector
+        finding['Exploitability'] = f"{rating(score)}: {exploitability_notes(vector)}"
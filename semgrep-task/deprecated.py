@@ -0,0 +1,197 @@
+import re
+import shlex
+import shutil
+import subprocess
+from pathlib import Path
+
+from structlayout import strip_comments
+from warmcache import file_signature
+
+RULE_ID = 'DEPRECATED-API'
+
+IMPORT_SPEC = re.compile(r'^\s*(?:import\s+)?(?:([\w.]+)\s+)?"([^"]+)"')
+DECLARATION = re.compile(r'^(?:func\s+(?:\(\s*(?:\w+\s+)?\*?(\w+)[^)]*\)\s*)?|(?:type|var|const)\s+)(\w+)')
+GROUP = re.compile(r'^(?:type|var|const)\s*\(\s*$')
+MEMBER = re.compile(r'^\s+(\w+)\b')
+REQUIRE = re.compile(r'^\s*(?:require\s+)?([\w.\-~/]+)\s+(v[\w.\-+]+)', re.MULTILINE)
+
+
+def deprecation_notice(comment):
+    """The "Deprecated:" paragraph of a doc comment, doc links unwrapped; None when there is none."""
+    paragraph = None
+    for line in comment:
+        text = line.strip().lstrip('/').strip()
+        if paragraph is None and text.startswith('Deprecated:'):
+            paragraph = [text[len('Deprecated:'):].strip()]
+        elif paragraph is not None:
+            if not text or text.startswith('go:'):
+                break
+            paragraph.append(text)
+    if paragraph is None:
+        return None
+    return re.sub(r'\[([\w.*]+)\]', r'\1', ' '.join(paragraph)).strip() or "no replacement given"
+
+
+def doc_comment(lines, index):
+    """The // lines right above line index."""
+    start = index
+    while start > 0 and lines[start - 1].strip().startswith('//'):
+        start -= 1
+    return lines[start:index]
+
+
+def deprecated_in_source(text):
+    """(package name, package notice, {name: notice}) of one Go file; methods are keyed Type.Method."""
+    lines = text.splitlines()
+    symbols, package_notice, package_name = {}, None, None
+    group, group_notice = False, None
+    for i, line in enumerate(lines):
+        if group:
+            if line.startswith(')'):
+                group = False
+                continue
+            member = MEMBER.match(line)
+            if member and not line.startswith(('\t\t', '        ')):
+                notice = deprecation_notice(doc_comment(lines, i)) or group_notice
+                if notice:
+                    symbols[member.group(1)] = notice
+            continue
+        if line.startswith('package ') and package_name is None:
+            package_name = line.split()[1]
+            package_notice = deprecation_notice(doc_comment(lines, i))
+        elif GROUP.match(line):
+            group, group_notice = True, deprecation_notice(doc_comment(lines, i))
+        else:
+            match = DECLARATION.match(line)
+            notice = deprecation_notice(doc_comment(lines, i)) if match else None
+            if notice:
+                symbols['.'.join(g for g in match.groups() if g)] = notice
+    return package_name, package_notice, symbols
+
+
+def go_environment(command):
+    """(GOROOT, GOMODCACHE) of the go command, None for those it doesn't tell."""
+    go = shlex.split(command)
+    if not shutil.which(go[0]):
+        return None, None
+    res = subprocess.run(go + ['env', 'GOROOT', 'GOMODCACHE'], capture_output=True, text=True)
+    if res.returncode != 0 or len(res.stdout.split('\n')) < 2:
+        return None, None
+    goroot, modcache = res.stdout.split('\n')[:2]
+    return Path(goroot) if goroot else None, Path(modcache) if modcache else None
+
+
+class Deprecations:
+    """Deprecated symbols of the packages the scanned files import: the standard library (GOROOT), the
+    module cache or vendor/ for dependencies, and the scanned modules themselves. Packages are read once; with
+    the packages and environments of a WarmCache they are kept across scans, the scanned ones re-read when
+    their files change."""
+
+    def __init__(self, modules, command='go', packages=None, environments=None):
+        self.modules = modules
+        self.packages = {} if packages is None else packages  # folder -> (signature, (name, notice, symbols))
+        self.requires = {}  # module folder -> {module path: version}
+        self.checked = set()  # folders whose package is up to date for this scan
+        environments = {} if environments is None else environments
+        if command not in environments:
+            environments[command] = go_environment(command)
+        self.goroot, self.modcache = environments[command]
+
+    def module_root(self, file_path):
+        for folder in Path(file_path).resolve().parents:
+            if folder in self.modules.modules:
+                return folder
+        return None
+
+    def required(self, module_folder):
+        if module_folder not in self.requires:
+            text = (module_folder / 'go.mod').read_text(encoding='utf-8', errors='ignore')
+            self.requires[module_folder] = dict(REQUIRE.findall(re.sub(r'//[^\n]*', '', text)))
+        return self.requires[module_folder]
+
+    def folder_of(self, import_path, file_path):
+        """The source folder of an imported package as the go command would find it, None if not on disk."""
+        for folder, path in sorted(self.modules.modules.items(), key=lambda item: -len(item[1])):
+            if import_path == path or import_path.startswith(path + '/'):
+                return folder / import_path[len(path) + 1:]
+        root = self.module_root(file_path)
+        if '.' not in import_path.split('/')[0]:
+            return self.goroot / 'src' / import_path if self.goroot else None
+        if root and (root / 'vendor' / import_path).is_dir():
+            return root / 'vendor' / import_path
+        if root is None or self.modcache is None:
+            return None
+        requires = self.required(root)
+        module = max((m for m in requires if import_path == m or import_path.startswith(m + '/')), key=len,
+                     default=None)
+        if module is None:
+            return None
+        escaped = re.sub(r'[A-Z]', lambda m: '!' + m.group(0).lower(), module)
+        return self.modcache / f"{escaped}@{requires[module]}" / import_path[len(module) + 1:]
+
+    def package(self, folder):
+        """(name, notice, {symbol: notice}) of the package in a folder, its _test.go files aside."""
+        if folder in self.checked:
+            return self.packages[folder][1]
+        self.checked.add(folder)
+        files = [f for f in sorted(folder.glob('*.go')) if not f.name.endswith('_test.go')] if folder.is_dir() else []
+        # the standard library and the module cache don't change under a running daemon
+        fixed = any(root and (root == folder or root in folder.parents) for root in (self.goroot, self.modcache))
+        signature = None if fixed else file_signature(files)
+        entry = self.packages.get(folder)
+        if entry is None or entry[0] != signature:
+            name, notice, symbols = None, None, {}
+            for go_file in files:
+                file_name, file_notice, file_symbols = deprecated_in_source(
+                    go_file.read_text(encoding='utf-8', errors='ignore'))
+                name = name or file_name
+                notice = notice or file_notice
+                symbols.update(file_symbols)
+            entry = self.packages[folder] = (signature, (name, notice, symbols))
+        return entry[1]
+
+    def uses(self, file_path, text):
+        """(line, message) of the deprecated packages imported and package-level symbols used by a Go file.
+        Methods are not followed, that needs type information."""
+        code = strip_comments(text)
+        lines = code.splitlines()
+        found = []
+        aliases = {}  # name in this file -> (import path, symbols)
+        in_block = False
+        for number, line in enumerate(text.splitlines(), start=1):
+            if re.match(r'^\s*import\s*\(', line):
+                in_block = True
+                continue
+            if in_block and line.strip().startswith(')'):
+                in_block = False
+                continue
+            spec = IMPORT_SPEC.match(line) if in_block or line.lstrip().startswith('import') else None
+            if not spec:
+                if re.match(r'^\s*(func|type|var|const)\b', line):
+                    break
+                continue
+            alias, path = spec.groups()
+            folder = self.folder_of(path, file_path)
+            if folder is None:
+                continue
+            name, notice, symbols = self.package(folder)
+            if notice:
+                found.append((number, f"Package {path} is deprecated: {notice}"))
+            if alias not in ('_', '.') and symbols:
+                aliases[alias or name or path.split('/')[-1]] = (path, symbols)
+        for number, line in enumerate(lines, start=1):
+            for match in re.finditer(r'(?<![\w.])(\w+)\.(\w+)\b', line):
+                package, symbol = match.groups()
+                if package in aliases and symbol in aliases[package][1]:
+                    found.append((number, f"{package}.{symbol} is deprecated: {aliases[package][1][symbol]}"))
+        # Deprecated symbols of the file's own package, used elsewhere in it
+        _, _, own = self.package(Path(file_path).resolve().parent)
+        own = {name: notice for name, notice in own.items() if '.' not in name}
+        group = False
+        for number, line in enumerate(lines, start=1):
+            group = (group or GROUP.match(line) is not None) and not line.startswith(')')
+            declared = DECLARATION.match(line) or (MEMBER.match(line) if group else None)
+            for match in re.finditer(r'(?<![\w.])(\w+)\b', line) if own else []:
+                if match.group(1) in own and not (declared and match.start() == declared.start(declared.lastindex)):
+                    found.append((number, f"{match.group(1)} is deprecated: {own[match.group(1)]}"))
+        return sorted(set(found))
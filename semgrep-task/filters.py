@@ -0,0 +1,133 @@
+import re
+
+from config import rule_matches
+from gate import OPERATORS, SEVERITY_RANK, severity_name
+
+# --filter field -> finding key
+FIELDS = {'severity': 'Severity', 'rule': 'Rule ID', 'path': 'Path', 'file': 'File', 'line': 'Line',
+          'category': 'Category', 'message': 'Message', 'owner': 'Owner', 'module': 'Module', 'triage': 'Triage',
+          'author': 'Author', 'commit': 'Commit', 'introduced': 'Introduced', 'status': 'Status', 'cvss': 'CVSS'}
+
+TOKEN_RE = re.compile(r'\s*(?:(\|\||&&|=~|!~|>=|<=|==|!=|>|<|!|\(|\))|"((?:[^"\\]|\\.)*)"|([^\s()!&|=<>~"]+))')
+
+
+class FilterError(ValueError):
+    pass
+
+
+def tokenize(text):
+    tokens, position = [], 0
+    text = text.rstrip()
+    while position < len(text):
+        match = TOKEN_RE.match(text, position)
+        if not match or match.end() == position:
+            raise FilterError(f"unexpected {text[position:].strip()[:10]!r} in filter {text!r}")
+        symbol, quoted, word = match.groups()
+        if symbol:
+            tokens.append(('op', symbol))
+        elif quoted is not None:
+            tokens.append(('value', re.sub(r'\\(.)', r'\1', quoted)))
+        else:
+            tokens.append(('word', word))
+        position = match.end()
+    return tokens
+
+
+class Parser:
+    """expr := and ('||' and)* ; and := unary ('&&' unary)* ; unary := '!' unary | '(' expr ')' | field op value"""
+
+    def __init__(self, text):
+        self.text = text
+        self.tokens = tokenize(text)
+        self.position = 0
+
+    def peek(self):
+        return self.tokens[self.position] if self.position < len(self.tokens) else (None, None)
+
+    def take(self, expected=None):
+        kind, value = self.peek()
+        if kind is None or (expected and value != expected):
+            raise FilterError(f"expected {expected or 'more'} in filter {self.text!r}")
+        self.position += 1
+        return kind, value
+
+    def parse(self):
+        predicate = self.expression()
+        if self.position != len(self.tokens):
+            raise FilterError(f"unexpected {self.peek()[1]!r} in filter {self.text!r}")
+        return predicate
+
+    def expression(self):
+        terms = [self.conjunction()]
+        while self.peek() == ('op', '||'):
+            self.take()
+            terms.append(self.conjunction())
+        return terms[0] if len(terms) == 1 else lambda f: any(t(f) for t in terms)
+
+    def conjunction(self):
+        terms = [self.unary()]
+        while self.peek() == ('op', '&&'):
+            self.take()
+            terms.append(self.unary())
+        return terms[0] if len(terms) == 1 else lambda f: all(t(f) for t in terms)
+
+    def unary(self):
+        if self.peek() == ('op', '!'):
+            self.take()
+            inner = self.unary()
+            return lambda f: not inner(f)
+        if self.peek() == ('op', '('):
+            self.take()
+            inner = self.expression()
+            self.take(')')
+            return inner
+        return self.comparison()
+
+    def comparison(self):
+        kind, field = self.take()
+        if kind != 'word' or field.lower() not in FIELDS:
+            raise FilterError(f"unknown field {field!r} in filter {self.text!r} (expected one of: {', '.join(FIELDS)})")
+        kind, op = self.take()
+        if kind != 'op' or op not in OPERATORS and op not in ('=~', '!~'):
+            raise FilterError(f"expected a comparison after {field!r} in filter {self.text!r}")
+        kind, value = self.take()
+        if kind == 'op':
+            raise FilterError(f"expected a value after {field} {op} in filter {self.text!r}")
+        return comparison(field.lower(), op, value)
+
+
+def comparison(field, op, value):
+    key = FIELDS[field]
+    if op in ('=~', '!~'):
+        try:
+            regex = re.compile(value)
+        except re.error as e:
+            raise FilterError(f"invalid regex {value!r}: {e}")
+        return lambda f: bool(regex.search(str(f.get(key, '')))) == (op == '=~')
+    compare = OPERATORS[op]
+    if field == 'severity':
+        try:
+            rank = SEVERITY_RANK[severity_name(value)]
+        except ValueError as e:
+            raise FilterError(str(e))
+        return lambda f: compare(SEVERITY_RANK.get(f.get(key), 0), rank)
+    if field == 'line':
+        if not value.isdigit():
+            raise FilterError(f"line must be compared with a number, not {value!r}")
+        return lambda f: compare(int(f.get(key) or 0), int(value))
+    if field == 'cvss':
+        try:
+            score = float(value)
+        except ValueError:
+            raise FilterError(f"cvss must be compared with a score, not {value!r}")
+        return lambda f: key in f and compare(float(f[key]), score)
+    if field == 'rule' and op in ('==', '!='):
+        return lambda f: rule_matches(f.get(key, ''), value) == (op == '==')
+    return lambda f: compare(str(f.get(key, '')), value)
+
+
+def parse_filter(text):
+    """Predicate over findings for a --filter expression such as
+    severity >= high && rule =~ "error-handling" && path !~ "_test.go"
+    """
+    return Parser(text).parse()
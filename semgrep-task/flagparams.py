@@ -0,0 +1,80 @@
+import re
+
+from structlayout import strip_comments, closing_brace
+
+RULE_ID = 'BOOL-FLAG-PARAMETER'
+
+# an exported function or method, up to the ( of its parameters
+EXPORTED_FUNC = re.compile(r'^func\s+(?:\(\s*(?:\w+\s+)?\*?(\w+)[^)]*\)\s*)?([A-Z]\w*)\s*(?:\[[^\]]*\])?\s*\(',
+                           re.MULTILINE)
+
+
+def closing_paren(text, start):
+    depth = 0
+    for i in range(start, len(text)):
+        if text[i] in '([{':
+            depth += 1
+        elif text[i] in ')]}':
+            depth -= 1
+            if depth == 0:
+                return i
+    return None
+
+
+def parameters(params):
+    """[(name, type)] of a Go parameter list; grouped names (a, b bool) share the type, unnamed
+    parameters are left out."""
+    entries, depth, start = [], 0, 0
+    for i, char in enumerate(params + ','):
+        if char in '([{':
+            depth += 1
+        elif char in ')]}':
+            depth -= 1
+        elif char == ',' and depth == 0:
+            entries.append(params[start:i].strip())
+            start = i + 1
+    named, pending = [], []
+    for entry in filter(None, entries):
+        parts = entry.split(None, 1)
+        if len(parts) == 1:
+            pending.append(parts[0])  # a name waiting for its type, or an unnamed parameter
+            continue
+        named += [(name, parts[1].strip()) for name in pending + [parts[0]]]
+        pending = []
+    return named if not pending else []  # only types: unnamed parameters
+
+
+def branches_on(body, name):
+    """Whether a function body takes an if or switch decision on the variable."""
+    for condition in re.finditer(r'\b(?:if|switch)\b([^{]*)\{', body):
+        if re.search(rf'(?<![\w.]){re.escape(name)}\b(?!\s*[:(]?=[^=])', condition.group(1)):
+            return True
+    return False
+
+
+def flag_parameters(text, options):
+    """(line, message) of the exported functions of a Go file with at least flag_parameters.min_flags bool
+    parameters they branch on: Render(doc, true, false) doesn't say what true and false do."""
+    code = strip_comments(text)
+    problems = []
+    for match in EXPORTED_FUNC.finditer(code):
+        receiver, name = match.groups()
+        close = closing_paren(code, match.end() - 1)
+        brace = code.find('{', close) if close is not None else -1
+        if brace < 0 or '\n' in code[close:brace]:
+            continue  # declared without a body (assembly)
+        end = closing_brace(code, brace)
+        if end is None:
+            continue
+        body = code[brace + 1:end]
+        flags = [p for p, t in parameters(code[match.end():close]) if t == 'bool' and branches_on(body, p)]
+        if len(flags) < max(options['min_flags'], 1):
+            continue
+        function = f"{receiver}.{name}" if receiver else name
+        listed = ', '.join(flags)
+        problems.append((code.count('\n', 0, match.start()) + 1,
+                         f"{function} takes bool flag parameter{'s' * (len(flags) > 1)} ({listed}) switching what "
+                         f"it does, so calls read {name}(..., true). Take an options struct "
+                         f"({name}Options{{{', '.join(f.capitalize() + ': true' for f in flags[:2])}}}) or split "
+                         f"it into functions named after each behavior"))
+    return problems
@@ -0,0 +1,125 @@
+import json
+
+from publishers import fingerprint, repo_path
+
+SARIF_LEVEL = {'ERROR': 'error', 'WARNING': 'warning', 'INFO': 'note'}
+
+# Finding Status -> SARIF result.baselineState
+SARIF_BASELINE_STATE = {'new': 'new', 'existing': 'unchanged', 'fixed': 'absent'}
+
+
+def sarif_rule(rule_id, findings):
+    """Rule descriptor; security-severity (the highest CVSS score) lets GitHub rank security alerts."""
+    scores = [f['CVSS'] for f in findings if f['Rule ID'] == rule_id and 'CVSS' in f]
+    return {'id': rule_id, 'properties': {'security-severity': f"{max(scores):.1f}"}} if scores else {'id': rule_id}
+
+
+def location(f, base_dir):
+    """Path of a finding from the repository root, as code hosts resolve the paths of reports; the path from
+    the scanned folder without base_dir."""
+    return repo_path(base_dir, f) if base_dir else f['Path']
+
+
+def to_sarif(findings, base_dir=None):
+    """Builds a SARIF 2.1.0 log with one result per finding."""
+    rule_ids = sorted({f['Rule ID'] for f in findings})
+    results = []
+    for f in findings:
+        uri = location(f, base_dir)
+        region = {'startLine': f['Line']}
+        if f.get('Range'):
+            start, end = f['Range']['start'], f['Range']['end']
+            region = {'startLine': start['line'], 'startColumn': start['col'],
+                      'endLine': end['line'], 'endColumn': end['col']}
+        result = {
+            'ruleId': f['Rule ID'],
+            'level': SARIF_LEVEL.get(f['Severity'], 'note'),
+            'message': {'text': f['Message']},
+            'locations': [{'physicalLocation': {
+                'artifactLocation': {'uri': uri},
+                'region': region,
+            }}],
+        }
+        if f.get('Fix') and f.get('Range'):
+            result['fixes'] = [{
+                'description': {'text': f"Suggested fix for {f['Rule ID']}"},
+                'artifactChanges': [{
+                    'artifactLocation': {'uri': uri},
+                    'replacements': [{'deletedRegion': region, 'insertedContent': {'text': f['Fix']}}],
+                }],
+            }]
+        if f.get('Status'):
+            result['baselineState'] = SARIF_BASELINE_STATE[f['Status']]
+        results.append(result)
+    return {
+        '$schema': 'https://json.schemastore.org/sarif-2.1.0.json',
+        'version': '2.1.0',
+        'runs': [{
+            'tool': {'driver': {'name': 'auto-review', 'rules': [sarif_rule(r, findings) for r in rule_ids]}},
+            'results': results,
+        }],
+    }
+
+
+ARCANIST_SEVERITY = {'ERROR': 'error', 'WARNING': 'warning', 'INFO': 'advice'}
+
+
+def to_arcanist(findings, base_dir=None):
+    """Lint messages in the dictionary shape of Arcanist's ArcanistLintMessage, for external JSON linters."""
+    return [{
+        'path': location(f, base_dir),
+        'line': f['Line'],
+        'char': f['Range']['start']['col'] if f.get('Range') else 1,
+        'code': f['Rule ID'][:128],
+        'severity': ARCANIST_SEVERITY.get(f['Severity'], 'advice'),
+        'name': f.get('Category') or f['Rule ID'],
+        'description': f"[{f['Status']}] {f['Message']}" if f.get('Status') else f['Message'],
+    } for f in findings if f.get('Status') != 'fixed']
+
+
+WARNINGS_NG_SEVERITY = {'ERROR': 'HIGH', 'WARNING': 'NORMAL', 'INFO': 'LOW'}
+
+
+def to_warnings_ng(findings, base_dir=None):
+    """Native issues format of the Jenkins warnings-ng plugin, read by recordIssues(tool: issues())."""
+    issues = []
+    for f in findings:
+        if f.get('Status') == 'fixed':
+            continue  # warnings-ng works out fixed issues from its own reference build
+        issue = {
+            'fileName': location(f, base_dir),
+            'lineStart': f['Line'],
+            'lineEnd': f['Line'],
+            'severity': WARNINGS_NG_SEVERITY.get(f['Severity'], 'LOW'),
+            'category': f.get('Category') or 'general',
+            'type': f['Rule ID'],
+            'message': f['Message'],
+            'fingerprint': fingerprint(f),
+            'origin': 'auto-review',
+        }
+        if f.get('Status'):
+            issue['additionalProperties'] = {'status': f['Status']}
+        if f.get('Range'):
+            issue.update(lineEnd=f['Range']['end']['line'], columnStart=f['Range']['start']['col'],
+                         columnEnd=f['Range']['end']['col'])
+        issues.append(issue)
+    return {'issues': issues}
+
+
+def plain_findings(findings, base_dir=None):
+    """The findings as they are, with Path from the scanned folder, so the output can serve as a baseline."""
+    return [{k: v for k, v in f.items() if k != 'Range'} for f in findings]
+
+
+# --format name -> callable(findings, base_dir) -> JSON-serializable document
+FORMATS = {
+    'json': plain_findings,
+    'sarif': to_sarif,
+    'arcanist': to_arcanist,
+    'warnings-ng': to_warnings_ng,
+}
+
+
+def render(findings, fmt, base_dir=None):
+    """The --format document of the findings of a scan of base_dir."""
+    return json.dumps(FORMATS[fmt](findings, base_dir), indent=2, default=str)
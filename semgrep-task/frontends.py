@@ -0,0 +1,205 @@
+import re
+
+from config import matches_globs, anchor_excludes
+from imports import import_violations, RULE_ID as IMPORT_RULE
+from structlayout import padding_problems, RULE_ID as LAYOUT_RULE
+from naming import naming_problems, RULES as NAMING_RULES
+from structfields import large_structs, RULE_ID as FIELDS_RULE
+from recovery import recovery_problem, recovers, RULE_ID as RECOVERY_RULE
+from flagparams import flag_parameters, RULE_ID as FLAG_RULE
+from commented import commented_out_code, RULE_ID as COMMENTED_RULE
+from tabletests import table_test_suggestions, RULE_ID as TABLE_RULE
+from kubernetes import manifest_problems, MANIFEST_SUFFIXES, RULES as K8S_RULES
+from terraform import terraform_problems, RULES as TF_RULES
+from hcl import HCLError
+from shellcheck import shell_findings, SHELL_SUFFIXES
+
+# Dockerfiles are named rather than suffixed: Dockerfile, Dockerfile.prod, api.Dockerfile, Containerfile
+DOCKERFILE_NAMES = re.compile(r'^(Dockerfile|Containerfile)(\.(?!(md|txt|bak|orig)$)[\w-]+)?$|\.[Dd]ockerfile$')
+
+
+def relative(file_path, base_dir):
+    try:
+        return file_path.relative_to(base_dir).as_posix()
+    except ValueError:
+        return file_path.name
+
+
+class Frontend:
+    """A kind of file the reviewer understands: which files are its own, the Semgrep rule file run on them
+    and the checks it does itself. Header and secret checks are the reviewer's, for every file.
+
+    analyze() returns (line, rule id, severity, category, message, extra fields) entries;
+    native_rules() the (rule id, severity) of those checks, for `scan --dry-run`.
+    """
+    name = ''
+    rules_file = None  # in rules/
+    common = False     # also run rules/common-rules.yml, only meant for source code
+
+    def handles(self, file_path, config, base_dir):
+        return False
+
+    def analyze(self, file_path, text, config, base_dir):
+        return []
+
+    def native_rules(self, file_path, config, base_dir):
+        return []
+
+
+class SemgrepFrontend(Frontend):
+    """Source code reviewed by Semgrep only: its language's rule file and the common rules."""
+    common = True
+
+    def __init__(self, name, suffixes, rules_file):
+        self.name = name
+        self.suffixes = suffixes
+        self.rules_file = rules_file
+
+    def handles(self, file_path, config, base_dir):
+        return file_path.suffix in self.suffixes
+
+
+class GoFrontend(SemgrepFrontend):
+    """Go: the Semgrep Go rules, plus the analyzers that read Go source themselves (import policies, naming
+    conventions, panic recovery, bool flag parameters, struct size and layout, commented-out code,
+    table-driven test suggestions...)."""
+
+    def __init__(self):
+        super().__init__('go', {'.go'}, 'go-rules.yml')
+        self.recovering = {}  # package folder -> whether one of its files recovers handler panics
+
+    def layout_checked(self, file_path, config):
+        options = config['struct_layout']
+        return options['enabled'] and (not options['paths'] or matches_globs(file_path, options['paths']))
+
+    def package_recovers(self, folder, middleware):
+        if folder not in self.recovering:
+            self.recovering[folder] = any(recovers(f.read_text(encoding='utf-8', errors='ignore'), middleware)
+                                          for f in sorted(folder.glob('*.go')) if not f.name.endswith('_test.go'))
+        return self.recovering[folder]
+
+    def flags_checked(self, file_path, config):
+        return config['flag_parameters']['enabled'] and not file_path.name.endswith('_test.go')
+
+    def table_checked(self, file_path, config):
+        return config['table_tests']['enabled'] and file_path.name.endswith('_test.go')
+
+    def analyze(self, file_path, text, config, base_dir):
+        findings = [(line, IMPORT_RULE, severity, 'architecture', message, {})
+                    for line, severity, message in import_violations(file_path, text, config['imports'])]
+        if config['naming']['enabled']:
+            findings += [(line, rule_id, severity, NAMING_RULES[rule_id][1], message, {})
+                         for line, rule_id, severity, message in naming_problems(file_path, text, config['naming'])]
+        if config['recovery']['enabled']:
+            middleware = config['recovery']['middleware']
+            problem = recovery_problem(file_path, text, config['recovery'],
+                                       lambda folder: self.package_recovers(folder, middleware))
+            findings += [(problem[0], RECOVERY_RULE, 'WARNING', 'reliability', problem[1], {})] if problem else []
+        if self.flags_checked(file_path, config):
+            findings += [(line, FLAG_RULE, 'INFO', 'code-quality', message, {})
+                         for line, message in flag_parameters(text, config['flag_parameters'])]
+        if config['struct_fields']['enabled']:
+            findings += [(line, FIELDS_RULE, 'INFO', 'code-quality', message, {})
+                         for line, message in large_structs(text, config['struct_fields'])]
+        if self.layout_checked(file_path, config):
+            findings += [(line, LAYOUT_RULE, 'INFO', 'performance', message, {})
+                         for line, message in padding_problems(text, config['struct_layout'])]
+        if config['commented_code']['enabled']:
+            findings += [(line, COMMENTED_RULE, 'INFO', 'code-quality', message, {})
+                         for line, message in commented_out_code(text, config['commented_code'])]
+        if self.table_checked(file_path, config):
+            findings += [(line, TABLE_RULE, 'INFO', 'code-quality', message, {"Example": sketch})
+                         for line, message, sketch in table_test_suggestions(text, config['table_tests'])]
+        return findings
+
+    def native_rules(self, file_path, config, base_dir):
+        return [(IMPORT_RULE, 'ERROR')] * bool(config['imports']) + \
+            [(rule_id, severity) for rule_id, (severity, _) in NAMING_RULES.items()] * config['naming']['enabled'] + \
+            [(RECOVERY_RULE, 'WARNING')] * config['recovery']['enabled'] + \
+            [(FLAG_RULE, 'INFO')] * self.flags_checked(file_path, config) + \
+            [(FIELDS_RULE, 'INFO')] * config['struct_fields']['enabled'] + \
+            [(LAYOUT_RULE, 'INFO')] * self.layout_checked(file_path, config) + \
+            [(COMMENTED_RULE, 'INFO')] * config['commented_code']['enabled'] + \
+            [(TABLE_RULE, 'INFO')] * self.table_checked(file_path, config)
+
+
+class DockerfileFrontend(Frontend):
+    name = 'dockerfile'
+    rules_file = 'dockerfile-rules.yml'
+
+    def handles(self, file_path, config, base_dir):
+        return DOCKERFILE_NAMES.search(file_path.name) is not None
+
+
+class KubernetesFrontend(Frontend):
+    """YAML files under the kubernetes.paths globs; the defaults are relative to the scanned folder."""
+    name = 'kubernetes'
+
+    def handles(self, file_path, config, base_dir):
+        return file_path.suffix in MANIFEST_SUFFIXES and \
+            matches_globs(file_path, anchor_excludes(config['kubernetes']['paths'], base_dir))
+
+    def analyze(self, file_path, text, config, base_dir):
+        return [(line, rule_id, *K8S_RULES[rule_id], message, {})
+                for line, rule_id, message in manifest_problems(text)]
+
+    def native_rules(self, file_path, config, base_dir):
+        return [(rule_id, severity) for rule_id, (severity, _) in K8S_RULES.items()]
+
+
+class TerraformFrontend(Frontend):
+    name = 'terraform'
+
+    def handles(self, file_path, config, base_dir):
+        return file_path.suffix == '.tf'
+
+    def analyze(self, file_path, text, config, base_dir):
+        try:
+            problems = terraform_problems(text)
+        except HCLError as e:
+            print(f"⚠️ Terraform checks skipped for {relative(file_path, base_dir)}: {e}")
+            return []
+        return [(line, rule_id, severity, TF_RULES[rule_id][1], message, {})
+                for line, rule_id, severity, message in problems]
+
+    def native_rules(self, file_path, config, base_dir):
+        return [(rule_id, severity) for rule_id, (severity, _) in TF_RULES.items()]
+
+
+class ShellFrontend(Frontend):
+    """Shell scripts, checked by shellcheck; without it they only get the reviewer's checks."""
+    name = 'shell'
+
+    def __init__(self):
+        self.missing = False  # warned once per scan
+
+    def handles(self, file_path, config, base_dir):
+        return file_path.suffix in SHELL_SUFFIXES and config['shellcheck']['enabled']
+
+    def analyze(self, file_path, text, config, base_dir):
+        if self.missing:
+            return []
+        findings = shell_findings(file_path, config['shellcheck'])
+        if findings is None:
+            print(f"⚠️ Shell checks skipped: {config['shellcheck']['command']} not found or unreadable output")
+            self.missing = True
+            return []
+        return [(line, rule_id, severity, category, message, {"Fix": fix, "Range": span} if fix else {})
+                for line, rule_id, severity, category, message, fix, span in findings]
+
+    def native_rules(self, file_path, config, base_dir):
+        return [('shellcheck', 'ERROR')]
+
+
+def default_frontends():
+    """The frontends of a scan, first match wins; fresh instances since some keep per-scan state."""
+    return [
+        GoFrontend(),
+        SemgrepFrontend('python', {'.py'}, 'python-rules.yml'),
+        SemgrepFrontend('javascript', {'.js'}, 'javascript-rules.yml'),
+        SemgrepFrontend('java', {'.java'}, 'java-rules.yml'),
+        DockerfileFrontend(),
+        TerraformFrontend(),
+        ShellFrontend(),
+        KubernetesFrontend(),
+    ]
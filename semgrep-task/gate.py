@@ -0,0 +1,143 @@
+import re
+import json
+import operator
+from pathlib import Path
+
+from config import rule_matches
+from coverage import RULE_ID as COVERAGE_RULE
+from publishers import fingerprint
+from stats import MAINTAINABILITY_RULE
+from triage import STATUSES as TRIAGE_STATUSES
+
+SEVERITY_RANK = {'INFO': 0, 'WARNING': 1, 'ERROR': 2}
+
+# Common names for the Semgrep severities, accepted by --severity
+SEVERITY_ALIASES = {'HIGH': 'ERROR', 'CRITICAL': 'ERROR', 'MEDIUM': 'WARNING', 'LOW': 'INFO'}
+
+
+def severity_name(value):
+    name = SEVERITY_ALIASES.get(value.upper(), value.upper())
+    if name not in SEVERITY_RANK:
+        raise ValueError(f"unknown severity {value!r}")
+    return name
+
+
+OPERATORS = {'>': operator.gt, '>=': operator.ge, '<': operator.lt, '<=': operator.le, '==': operator.eq,
+             '!=': operator.ne}
+
+# "<new|total> <selector> <op> <number>", e.g. "new ERROR > 0", "total WARNING+ >= 10", "new category:security > 0",
+# "new complexity > 0"
+CONDITION_RE = re.compile(r'^\s*(new|total)\s+(\S+)\s*(>=|<=|==|!=|>|<)\s*(\d+)\s*$', re.IGNORECASE)
+
+
+# Rules about functions too complex to maintain, selected by the "complexity" selector
+COMPLEXITY_RULES = [MAINTAINABILITY_RULE, 'go-rule-18-long-function', COVERAGE_RULE]
+
+# Finding Status against the baseline or the history's previous scan
+STATUSES = ['new', 'existing', 'fixed']
+
+
+class GateError(ValueError):
+    pass
+
+
+def parse_condition(text):
+    match = CONDITION_RE.match(text)
+    if not match:
+        raise GateError(f"invalid gate condition {text!r}, expected e.g. 'new ERROR > 0'")
+    scope, selector, op, limit = match.groups()
+    selector_matches(selector, {})  # validate the selector up front
+    return {'text': text.strip(), 'scope': scope.lower(), 'selector': selector, 'op': op, 'limit': int(limit)}
+
+
+def selector_matches(selector, finding):
+    """Selectors: ALL, a severity (ERROR), a severity and above (WARNING+), category:<name>, rule:<id>,
+    status:<new|existing|fixed>, triage:<status> (fix-later, the only triaged findings still reported),
+    cvss:<score> (CVSS score at or above it), complexity (findings of the COMPLEXITY_RULES) or complexity:<n>
+    (those about a function of cyclomatic complexity n or more)."""
+    kind, _, value = selector.partition(':')
+    if value:
+        if kind == 'cvss' and re.match(r'^\d+(\.\d)?$', value):
+            return 'CVSS' in finding and finding['CVSS'] >= float(value)
+        if kind == 'complexity' and value.isdigit():
+            return 'Complexity' in finding and finding['Complexity'] >= int(value)
+        if kind == 'status' and value.lower() in STATUSES:
+            return finding.get('Status') == value.lower()
+        if kind == 'triage' and value.lower() in TRIAGE_STATUSES:
+            return finding.get('Triage') == value.lower()
+        if kind == 'category':
+            return finding.get('Category') == value
+        if kind == 'rule':
+            return rule_matches(finding.get('Rule ID', ''), value)
+        raise GateError(f"unknown gate selector {selector!r}")
+    name = selector.upper()
+    if name == 'ALL':
+        return True
+    if name == 'COMPLEXITY':
+        return any(rule_matches(finding.get('Rule ID', ''), rule) for rule in COMPLEXITY_RULES)
+    at_least = name.endswith('+')
+    name = name.rstrip('+')
+    if name not in SEVERITY_RANK:
+        raise GateError(f"unknown gate selector {selector!r}")
+    if not finding:
+        return False
+    rank = SEVERITY_RANK.get(finding.get('Severity'), 0)
+    return rank >= SEVERITY_RANK[name] if at_least else rank == SEVERITY_RANK[name]
+
+
+def report_filter(severity=None, only=(), skip=()):
+    """Predicate for the findings to report; the gate still sees every finding."""
+    def keep(finding):
+        rule_id = finding['Rule ID']
+        if severity and SEVERITY_RANK.get(finding['Severity'], 0) < SEVERITY_RANK[severity]:
+            return False
+        if only and not any(rule_matches(rule_id, r) for r in only):
+            return False
+        return not any(rule_matches(rule_id, r) for r in skip)
+    return keep
+
+
+def load_baseline(path):
+    """fingerprint -> finding of a previous `--format json` run (e.g. of the target branch)."""
+    if not path:
+        return None
+    if not Path(path).is_file():
+        print(f"⚠️ Baseline {path} not found, every finding counts as new")
+        return {}
+    return {fingerprint(f): f for f in json.loads(Path(path).read_text(encoding='utf-8'))
+            if f.get('Status') != 'fixed'}
+
+
+def classify(findings, previous):
+    """Tags findings as new or existing against a previous run's fingerprints."""
+    for f in findings:
+        f['Status'] = 'existing' if fingerprint(f) in previous else 'new'
+
+
+def fixed_findings(findings, previous):
+    """Findings of the previous run that are gone, with Status fixed."""
+    current = {fingerprint(f) for f in findings}
+    return [dict(f, Status='fixed') for key, f in previous.items() if key not in current]
+
+
+def gate_conditions(config, fail_on=None, extra=()):
+    """Conditions from the config gate section, --fail-on and --gate flags."""
+    conditions = list(config['gate'].get('conditions') or []) + list(extra)
+    fail_on = fail_on or config['gate'].get('fail_on')
+    if fail_on:
+        conditions.append(f"total {fail_on.upper()}+ > 0")
+    return [parse_condition(c) for c in conditions]
+
+
+def evaluate_gate(findings, conditions, baseline=None, fixed=()):
+    """Returns the failed conditions as (condition, count, matching findings); the gate passes when empty."""
+    new = findings if baseline is None else [f for f in findings if fingerprint(f) not in baseline]
+    failed = []
+    for condition in conditions:
+        pool = new if condition['scope'] == 'new' else findings
+        if condition['selector'].lower() == 'status:fixed':
+            pool = list(fixed)
+        matching = [f for f in pool if selector_matches(condition['selector'], f)]
+        if OPERATORS[condition['op']](len(matching), condition['limit']):
+            failed.append((condition, len(matching), matching))
+    return failed
@@ -0,0 +1,109 @@
+import os
+import hmac
+import base64
+import hashlib
+import subprocess
+from pathlib import Path
+
+from hooks import changed_files
+from publishers import request_json, gate_result
+
+WORKDIR = Path(os.environ.get('AUTO_REVIEW_WORKDIR', Path.home() / '.cache' / 'auto-review' / 'repos'))
+ANNOTATION_LEVEL = {'ERROR': 'failure', 'WARNING': 'warning', 'INFO': 'notice'}
+PR_ACTIONS = {'opened', 'synchronize', 'reopened'}
+ZERO_SHA = '0' * 40
+
+
+def verify_signature(body, signature):
+    """Checks X-Hub-Signature-256 against GITHUB_WEBHOOK_SECRET; every delivery is rejected without a secret."""
+    secret = os.environ.get('GITHUB_WEBHOOK_SECRET')
+    if not secret:
+        return False
+    expected = 'sha256=' + hmac.new(secret.encode(), body, hashlib.sha256).hexdigest()
+    return hmac.compare_digest(expected, signature or '')
+
+
+def scan_target(event, payload):
+    """Returns (repo full name, base sha, head sha) for events we review, otherwise None."""
+    if event == 'push' and payload.get('after') and payload['after'] != ZERO_SHA:
+        before = payload.get('before')
+        return payload['repository']['full_name'], None if before == ZERO_SHA else before, payload['after']
+    if event == 'pull_request' and payload.get('action') in PR_ACTIONS:
+        pr = payload['pull_request']
+        return payload['repository']['full_name'], pr['base']['sha'], pr['head']['sha']
+    return None
+
+
+def authenticated(token):
+    """Environment passing the installation token to git as an http.extraHeader, which keeps it out of the
+    clone's .git/config and of the command line."""
+    credentials = base64.b64encode(f"x-access-token:{token}".encode()).decode()
+    return dict(os.environ, GIT_CONFIG_COUNT='1', GIT_CONFIG_KEY_0='http.https://github.com/.extraHeader',
+                GIT_CONFIG_VALUE_0=f"Authorization: Basic {credentials}")
+
+
+def fetch(repo_dir, full_name, sha, token):
+    subprocess.run(["git", "fetch", "--quiet", f"https://github.com/{full_name}.git", sha], cwd=repo_dir,
+                   env=authenticated(token), check=True)
+
+
+def checkout(full_name, sha, token):
+    """Clones the repository once, then fetches and checks out the requested commit."""
+    repo_dir = WORKDIR / full_name.replace('/', '__')
+    url = f"https://github.com/{full_name}.git"
+    if not (repo_dir / '.git').exists():
+        repo_dir.parent.mkdir(parents=True, exist_ok=True)
+        subprocess.run(["git", "clone", "--quiet", url, str(repo_dir)], env=authenticated(token), check=True)
+    else:
+        # Clones made by earlier versions kept the token in the remote URL
+        subprocess.run(["git", "remote", "set-url", "origin", url], cwd=repo_dir, check=True)
+    fetch(repo_dir, full_name, sha, token)
+    subprocess.run(["git", "checkout", "--quiet", "--force", sha], cwd=repo_dir, check=True)
+    return repo_dir
+
+
+def publish_check_run(full_name, sha, findings, failed, token):
+    api = f"https://api.github.com/repos/{full_name}/check-runs"
+    headers = {'Authorization': f"Bearer {token}", 'Accept': 'application/vnd.github+json'}
+    passed, description = gate_result(failed)
+    annotations = [{
+        'path': f['Path'],
+        'start_line': f['Line'],
+        'end_line': f['Line'],
+        'annotation_level': ANNOTATION_LEVEL.get(f['Severity'], 'notice'),
+        'title': f['Rule ID'],
+        'message': f['Message'],
+    } for f in findings]
+    output = {
+        'title': f"{len(findings)} finding(s)",
+        'summary': description,
+    }
+
+    # The Checks API accepts at most 50 annotations per request
+    run = request_json('POST', api, headers, {
+        'name': 'auto-review',
+        'head_sha': sha,
+        'status': 'completed',
+        'conclusion': 'success' if passed else 'failure',
+        'output': dict(output, annotations=annotations[:50]),
+    })
+    for i in range(50, len(annotations), 50):
+        request_json('PATCH', f"{api}/{run['id']}", headers, {'output': dict(output, annotations=annotations[i:i + 50])})
+    print(f"🐙 GitHub: check run {'success' if passed else 'failure'} on {full_name}@{sha[:7]}")
+
+
+def review_event(full_name, base, head, review_changes):
+    """Checks out head, reviews the files changed since base and reports a check run with the result of the
+    repository's quality gate."""
+    token = os.environ.get('GITHUB_TOKEN')
+    if not token:
+        raise RuntimeError("GITHUB_TOKEN (a GitHub App installation token with checks:write) is required")
+    repo_dir = checkout(full_name, head, token)
+    if base:
+        fetch(repo_dir, full_name, base, token)
+        files = changed_files(repo_dir, [f"{base}...{head}"])
+    else:
+        files = None  # new branch, review everything
+    findings, failed = review_changes(repo_dir, files)
+    publish_check_run(full_name, head, findings, failed, token)
+    return findings
@@ -0,0 +1,38 @@
+import os
+import re
+from pathlib import Path
+
+MODULE_DIRECTIVE = re.compile(r'^\s*module\s+("?)([^\s"]+)\1', re.MULTILINE)
+
+
+def module_path(go_mod):
+    """The module path declared by a go.mod file, or None when it has no module directive."""
+    match = MODULE_DIRECTIVE.search(Path(go_mod).read_text(encoding='utf-8', errors='ignore'))
+    return match.group(2) if match else None
+
+
+class GoModules:
+    def __init__(self, modules):
+        self.modules = modules  # {module folder: module path}, nested modules included
+
+    @classmethod
+    def discover(cls, base_dir, skip=lambda path: False):
+        """Every go.mod under base_dir, plus the module base_dir itself belongs to when scanning a subfolder."""
+        base_dir = Path(base_dir).resolve()
+        modules = {}
+        for folder in base_dir.parents:
+            if (folder / 'go.mod').is_file():
+                modules[folder] = module_path(folder / 'go.mod')
+                break
+        for root, dirs, files in os.walk(base_dir):
+            dirs[:] = [d for d in dirs if not d.startswith('.') and not skip(Path(root) / d)]
+            if 'go.mod' in files:
+                modules[Path(root)] = module_path(Path(root) / 'go.mod')
+        return cls({folder: path for folder, path in modules.items() if path})
+
+    def module_of(self, file_path):
+        """Module path of the innermost module containing the file; a nested module is not part of its parent."""
+        for folder in Path(file_path).resolve().parents:
+            if folder in self.modules:
+                return self.modules[folder]
+        return None
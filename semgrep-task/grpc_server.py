@@ -0,0 +1,61 @@
+import os
+from concurrent import futures
+from pathlib import Path
+
+import grpc
+
+# Generated from proto/codereview.proto, see the command at the top of that file
+import codereview_pb2
+import codereview_pb2_grpc
+
+from server import allowed_path, authorized
+
+
+class CodeReviewService(codereview_pb2_grpc.CodeReviewServicer):
+    """Scans need the AUTO_REVIEW_API_TOKEN bearer token in the authorization metadata and a path inside the
+    allowed roots, like the REST API."""
+
+    def __init__(self, review, roots):
+        self.review = review  # callable(path, files) -> iterator of per-file findings
+        self.roots = roots  # resolved folders scans may read
+
+    def Scan(self, request, context):
+        if not os.environ.get('AUTO_REVIEW_API_TOKEN'):
+            context.abort(grpc.StatusCode.UNAVAILABLE, 'the API is disabled until AUTO_REVIEW_API_TOKEN is set')
+        if not authorized(dict(context.invocation_metadata()).get('authorization')):
+            context.abort(grpc.StatusCode.UNAUTHENTICATED,
+                          'expected "authorization: Bearer <AUTO_REVIEW_API_TOKEN>" metadata')
+        path = allowed_path(request.path, self.roots)
+        if path is None:
+            context.abort(grpc.StatusCode.PERMISSION_DENIED, f"{request.path} is outside the allowed roots")
+        try:
+            for results in self.review(str(path), list(request.files)):
+                for f in results:
+                    yield codereview_pb2.Finding(
+                        path=f['Path'], line=f['Line'], rule_id=f['Rule ID'], severity=f['Severity'],
+                        category=f.get('Category', ''), message=f['Message'], fix=f.get('Fix', ''))
+        except FileNotFoundError as e:
+            context.abort(grpc.StatusCode.NOT_FOUND, str(e))
+
+
+def serve_grpc(address, review, roots, tls_cert=None, tls_key=None):
+    """Serves the streaming CodeReview service on "host:port" (":50051" listens on 127.0.0.1), over TLS when
+    given a PEM certificate chain and key, scanning only paths inside the roots folders."""
+    host, _, port = address.rpartition(':')
+    host = host or '127.0.0.1'
+    roots = tuple(Path(root).resolve() for root in roots)
+    server = grpc.server(futures.ThreadPoolExecutor(max_workers=4))
+    codereview_pb2_grpc.add_CodeReviewServicer_to_server(CodeReviewService(review, roots), server)
+    if tls_cert:
+        credentials = grpc.ssl_server_credentials([(Path(tls_key).read_bytes(), Path(tls_cert).read_bytes())])
+        server.add_secure_port(f"{host}:{port}", credentials)
+    else:
+        server.add_insecure_port(f"{host}:{port}")
+    server.start()
+    print(f"📡 gRPC API listening on {host}:{port}{' with TLS' if tls_cert else ''}, "
+          f"serving {', '.join(map(str, roots))}")
+    if not tls_cert:
+        print("⚠️ gRPC API without TLS: the token travels in clear text, give --tls-cert and --tls-key")
+    if not os.environ.get('AUTO_REVIEW_API_TOKEN'):
+        print("⚠️ AUTO_REVIEW_API_TOKEN is not set: every scan is refused")
+    server.wait_for_termination()
@@ -0,0 +1,252 @@
+import re
+
+# HCL native syntax (https://github.com/hashicorp/hcl/blob/main/hclsyntax/spec.md), enough of it to read the
+# blocks and attributes of Terraform files. Literal values are converted to Python values; anything computed
+# (references, function calls, templates with interpolation) is kept as an Expression with its source text.
+
+IDENT = re.compile(r'[A-Za-z_][\w-]*')
+NUMBER = re.compile(r'\d+(\.\d+)?([eE][+-]?\d+)?')
+HEREDOC = re.compile(r'<<(-?)([A-Za-z_]\w*)[ \t]*\n')
+PUNCTUATION = ['==', '!=', '<=', '>=', '&&', '||', '=>', '...', '{', '}', '[', ']', '(', ')', '=', ',', ':',
+               '.', '?', '!', '+', '-', '*', '/', '%', '<', '>']
+
+
+class HCLError(ValueError):
+    def __init__(self, message, line):
+        super().__init__(f"line {line}: {message}")
+        self.line = line
+
+
+class Token:
+    def __init__(self, kind, value, line, start, end):
+        self.kind = kind    # ident, number, string, punct, nl or eof
+        self.value = value  # strings: their text, None when interpolated
+        self.line = line
+        self.start = start
+        self.end = end
+
+
+class Expression:
+    """A value only known when Terraform evaluates it, e.g. var.password or "${local.name}-logs"."""
+
+    def __init__(self, text):
+        self.text = text
+
+    def __repr__(self):
+        return f"Expression({self.text!r})"
+
+
+class Attribute:
+    def __init__(self, name, value, line):
+        self.name = name
+        self.value = value
+        self.line = line
+
+
+class Block:
+    def __init__(self, type, labels, line):
+        self.type = type
+        self.labels = labels
+        self.line = line
+        self.attributes = {}  # name -> Attribute
+        self.blocks = []
+
+    def get(self, name, default=None):
+        attribute = self.attributes.get(name)
+        return attribute.value if attribute else default
+
+    def children(self, type):
+        return [b for b in self.blocks if b.type == type]
+
+
+def read_string(text, position, line):
+    """End of the quoted string starting at position, its text and whether it interpolates (${ or %{)."""
+    i, depth, interpolated, chars = position + 1, 0, False, []
+    while i < len(text):
+        char = text[i]
+        if char == '\n' and depth == 0:
+            raise HCLError("unterminated string", line)
+        if depth == 0 and char == '\\':
+            chars.append({'n': '\n', 't': '\t', 'r': '\r'}.get(text[i + 1:i + 2], text[i + 1:i + 2]))
+            i += 2
+            continue
+        if depth == 0 and text.startswith(('$${', '%%{'), i):
+            chars.append(text[i + 1:i + 3])
+            i += 3
+            continue
+        if text.startswith(('${', '%{'), i):
+            depth += 1
+            interpolated = True
+            i += 2
+            continue
+        if depth and char == '}':
+            depth -= 1
+        elif depth and char == '"':
+            i = read_string(text, i, line)[0] - 1  # string inside the interpolation
+        elif depth == 0 and char == '"':
+            return i + 1, ''.join(chars), interpolated
+        elif depth == 0:
+            chars.append(char)
+        i += 1
+    raise HCLError("unterminated string", line)
+
+
+def tokenize(text):
+    tokens, i, line = [], 0, 1
+    while i < len(text):
+        char = text[i]
+        if char == '\n':
+            tokens.append(Token('nl', None, line, i, i + 1))
+            line += 1
+            i += 1
+        elif char in ' \t\r':
+            i += 1
+        elif char == '#' or text.startswith('//', i):
+            end = text.find('\n', i)
+            i = len(text) if end < 0 else end
+        elif text.startswith('/*', i):
+            end = text.find('*/', i + 2)
+            if end < 0:
+                raise HCLError("unterminated comment", line)
+            line += text.count('\n', i, end)
+            i = end + 2
+        elif char == '"':
+            end, value, interpolated = read_string(text, i, line)
+            tokens.append(Token('string', None if interpolated else value, line, i, end))
+            i = end
+        elif HEREDOC.match(text, i):
+            match = HEREDOC.match(text, i)
+            body_start = match.end()
+            closing = re.compile(rf'^[ \t]*{match.group(2)}[ \t]*$', re.MULTILINE).search(text, body_start)
+            if not closing:
+                raise HCLError(f"unterminated heredoc {match.group(2)}", line)
+            body = text[body_start:closing.start()]
+            if match.group(1):  # <<- strips the common indentation
+                lines = body.split('\n')
+                indent = min((len(l) - len(l.lstrip()) for l in lines if l.strip()), default=0)
+                body = '\n'.join(l[indent:] for l in lines)
+            interpolated = '${' in body.replace('$${', '') or '%{' in body.replace('%%{', '')
+            tokens.append(Token('string', None if interpolated else body, line, i, closing.end()))
+            line += text.count('\n', i, closing.end())
+            i = closing.end()
+        elif IDENT.match(text, i):
+            match = IDENT.match(text, i)
+            tokens.append(Token('ident', match.group(0), line, i, match.end()))
+            i = match.end()
+        elif NUMBER.match(text, i):
+            match = NUMBER.match(text, i)
+            tokens.append(Token('number', match.group(0), line, i, match.end()))
+            i = match.end()
+        else:
+            symbol = next((p for p in PUNCTUATION if text.startswith(p, i)), None)
+            if symbol is None:
+                raise HCLError(f"unexpected character {char!r}", line)
+            tokens.append(Token('punct', symbol, line, i, i + len(symbol)))
+            i += len(symbol)
+    tokens.append(Token('eof', None, line, len(text), len(text)))
+    return tokens
+
+
+class Parser:
+    def __init__(self, text):
+        self.text = text
+        self.tokens = tokenize(text)
+        self.position = 0
+
+    def peek(self, offset=0):
+        return self.tokens[min(self.position + offset, len(self.tokens) - 1)]
+
+    def next(self):
+        token = self.peek()
+        self.position += 1
+        return token
+
+    def skip_newlines(self):
+        while self.peek().kind == 'nl':
+            self.position += 1
+
+    def body(self, block, closing):
+        while True:
+            self.skip_newlines()
+            token = self.peek()
+            if token.kind == 'eof' or (token.kind == 'punct' and token.value == '}'):
+                if (token.kind == 'eof') != (closing is None):
+                    raise HCLError("unbalanced braces", token.line)
+                self.next()
+                return block
+            if token.kind != 'ident':
+                raise HCLError("expected an attribute or block", token.line)
+            self.next()
+            if self.peek().kind == 'punct' and self.peek().value == '=':
+                self.next()
+                block.attributes[token.value] = Attribute(token.value, self.expression(), token.line)
+                continue
+            labels = []
+            while self.peek().kind in ('string', 'ident'):
+                labels.append(self.next().value or '')
+            opening = self.next()
+            if opening.kind != 'punct' or opening.value != '{':
+                raise HCLError(f"expected '{{' after block {token.value}", opening.line)
+            block.blocks.append(self.body(Block(token.value, labels, token.line), '}'))
+
+    def expression(self):
+        """The tokens up to the end of the line (or of a one-line block), converted when they are a literal."""
+        start, depth = self.position, 0
+        while True:
+            token = self.peek()
+            if token.kind == 'eof' or (depth == 0 and (token.kind == 'nl' or
+                                                        (token.kind == 'punct' and token.value == '}'))):
+                break
+            if token.kind == 'punct' and token.value in '([{':
+                depth += 1
+            elif token.kind == 'punct' and token.value in ')]}':
+                depth -= 1
+            self.next()
+        tokens = [t for t in self.tokens[start:self.position] if t.kind != 'nl']
+        if not tokens:
+            raise HCLError("missing value", self.peek().line)
+        try:
+            value, end = literal(tokens, 0)
+            if end == len(tokens):
+                return value
+        except (IndexError, ValueError):
+            pass
+        return Expression(self.text[tokens[0].start:tokens[-1].end])
+
+
+def literal(tokens, i):
+    """(value, next index) of the literal at tokens[i]: string, number, bool, null, list or object."""
+    token = tokens[i]
+    if token.kind == 'string' and token.value is not None:
+        return token.value, i + 1
+    if token.kind == 'number':
+        return (float(token.value) if re.search(r'[.eE]', token.value) else int(token.value)), i + 1
+    if token.kind == 'punct' and token.value == '-' and tokens[i + 1].kind == 'number':
+        value, end = literal(tokens, i + 1)
+        return -value, end
+    if token.kind == 'ident' and token.value in ('true', 'false', 'null'):
+        return {'true': True, 'false': False, 'null': None}[token.value], i + 1
+    if token.kind == 'punct' and token.value == '[':
+        values, i = [], i + 1
+        while not (tokens[i].kind == 'punct' and tokens[i].value == ']'):
+            value, i = literal(tokens, i)
+            values.append(value)
+            if tokens[i].kind == 'punct' and tokens[i].value == ',':
+                i += 1
+        return values, i + 1
+    if token.kind == 'punct' and token.value == '{':
+        values, i = {}, i + 1
+        while not (tokens[i].kind == 'punct' and tokens[i].value == '}'):
+            key = tokens[i]
+            if key.kind not in ('ident', 'string') or key.value is None or tokens[i + 1].value not in ('=', ':'):
+                raise ValueError("not a literal object")
+            values[key.value], i = literal(tokens, i + 2)
+            if tokens[i].kind == 'punct' and tokens[i].value == ',':
+                i += 1
+        return values, i + 1
+    raise ValueError("not a literal")
+
+
+def parse(text):
+    """The top-level body of an HCL file as a Block without type; raises HCLError on a syntax error."""
+    return Parser(text).body(Block('', [], 1), None)
@@ -0,0 +1,194 @@
+import sqlite3
+import datetime
+from pathlib import Path
+
+from publishers import fingerprint
+from uploads import current_branch, git_value
+
+HISTORY_FILE = '.codereview-history.db'
+
+SCHEMA = """
+CREATE TABLE IF NOT EXISTS scans (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    started TEXT NOT NULL,
+    commit_sha TEXT NOT NULL,
+    branch TEXT NOT NULL,
+    root TEXT NOT NULL,
+    findings INTEGER NOT NULL,
+    scope TEXT NOT NULL DEFAULT 'full'
+);
+CREATE TABLE IF NOT EXISTS findings (
+    scan_id INTEGER NOT NULL REFERENCES scans(id) ON DELETE CASCADE,
+    fingerprint TEXT NOT NULL,
+    commit_sha TEXT NOT NULL,
+    rule_id TEXT NOT NULL,
+    severity TEXT NOT NULL,
+    category TEXT,
+    path TEXT NOT NULL,
+    line INTEGER,
+    message TEXT
+);
+CREATE INDEX IF NOT EXISTS findings_fingerprint ON findings (fingerprint);
+CREATE INDEX IF NOT EXISTS findings_commit ON findings (commit_sha);
+CREATE TABLE IF NOT EXISTS triage (
+    fingerprint TEXT PRIMARY KEY,
+    status TEXT NOT NULL,
+    rule_id TEXT NOT NULL,
+    path TEXT NOT NULL,
+    reason TEXT,
+    author TEXT,
+    updated TEXT NOT NULL
+);
+"""
+
+
+def history_file(config, base_dir):
+    """The config's `history` file (relative to the config) or .codereview-history.db in the scanned folder."""
+    if config.get('history'):
+        return Path(config['root'] or base_dir) / config['history']
+    return Path(base_dir) / HISTORY_FILE
+
+
+def connect(path):
+    conn = sqlite3.connect(str(path))
+    conn.row_factory = sqlite3.Row
+    conn.execute("PRAGMA foreign_keys = ON")
+    conn.executescript(SCHEMA)
+    if 'scope' not in {row['name'] for row in conn.execute("PRAGMA table_info(scans)")}:
+        conn.execute("ALTER TABLE scans ADD COLUMN scope TEXT NOT NULL DEFAULT 'full'")  # stores of earlier versions
+    return conn
+
+
+def record_scan(path, findings, base_dir, scope='full'):
+    """Stores one scan's findings keyed by fingerprint and commit SHA; returns the scan id. scope is 'full' for
+    scans of the whole folder, else what the scan covered (staged, push-range, file)."""
+    commit = git_value(["rev-parse", "HEAD"], base_dir, 'unknown')
+    with connect(path) as conn:
+        scan_id = conn.execute(
+            "INSERT INTO scans (started, commit_sha, branch, root, findings, scope) VALUES (?, ?, ?, ?, ?, ?)",
+            (datetime.datetime.now().isoformat(timespec='seconds'), commit, current_branch(base_dir),
+             str(Path(base_dir).resolve()), len(findings), scope)).lastrowid
+        conn.executemany(
+            "INSERT INTO findings (scan_id, fingerprint, commit_sha, rule_id, severity, category, path, line, message)"
+            " VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+            [(scan_id, fingerprint(f), commit, f['Rule ID'], f['Severity'], f.get('Category', ''), f['Path'],
+              f['Line'], f['Message']) for f in findings])
+    return scan_id
+
+
+def previous_findings(path, base_dir):
+    """fingerprint -> finding of the latest full scan of the folder recorded on the current branch, or None
+    without one. Partial scans only saw some of the files, the others' findings are not fixed since."""
+    if not Path(path).is_file():
+        return None
+    with connect(path) as conn:
+        scan = conn.execute("SELECT id FROM scans WHERE branch = ? AND root = ? AND scope = 'full'"
+                            " ORDER BY id DESC LIMIT 1",
+                            (current_branch(base_dir), str(Path(base_dir).resolve()))).fetchone()
+        if scan is None:
+            return None
+        return {row['fingerprint']: {'Rule ID': row['rule_id'], 'Severity': row['severity'],
+                                     'Category': row['category'], 'Path': row['path'], 'Line': row['line'],
+                                     'File': Path(row['path']).name, 'Message': row['message'],
+                                     'Fingerprint': row['fingerprint']}
+                for row in conn.execute("SELECT * FROM findings WHERE scan_id = ?", (scan['id'],))}
+
+
+def recent_scans(path, limit=20):
+    with connect(path) as conn:
+        return [dict(row) for row in conn.execute("SELECT * FROM scans ORDER BY id DESC LIMIT ?", (limit,))]
+
+
+def finding_history(path, key):
+    """Every scan a finding (by fingerprint or fingerprint prefix) appeared in, oldest first."""
+    with connect(path) as conn:
+        return [dict(row) for row in conn.execute(
+            "SELECT scans.id AS scan_id, scans.started, scans.branch, findings.* FROM findings"
+            " JOIN scans ON scans.id = findings.scan_id WHERE findings.fingerprint LIKE ? ORDER BY scans.id",
+            (key + '%',))]
+
+
+def load_triage(path):
+    """fingerprint -> {status, rule, path, reason, author, updated} of the triage decisions in the store."""
+    if not Path(path).is_file():
+        return {}
+    with connect(path) as conn:
+        return {row['fingerprint']: {'status': row['status'], 'rule': row['rule_id'], 'path': row['path'],
+                                     'reason': row['reason'], 'author': row['author'], 'updated': row['updated']}
+                for row in conn.execute("SELECT * FROM triage")}
+
+
+def save_triage(path, decisions):
+    """Writes fingerprint -> decision entries to the store; a None decision clears the fingerprint's."""
+    with connect(path) as conn:
+        for key, entry in decisions.items():
+            if entry is None:
+                conn.execute("DELETE FROM triage WHERE fingerprint = ?", (key,))
+                continue
+            conn.execute("INSERT OR REPLACE INTO triage (fingerprint, status, rule_id, path, reason, author, updated)"
+                         " VALUES (?, ?, ?, ?, ?, ?, ?)", (key, entry['status'], entry['rule'], entry['path'],
+                                                           entry.get('reason'), entry.get('author'), entry['updated']))
+
+
+def recorded_finding(path, key):
+    """The latest recorded finding whose fingerprint is or starts with key; raises KeyError when there is
+    none or the prefix is ambiguous."""
+    with connect(path) as conn:
+        rows = conn.execute("SELECT fingerprint, rule_id, severity, category, path, line, message FROM findings"
+                            " WHERE fingerprint LIKE ? ORDER BY scan_id DESC", (key + '%',)).fetchall()
+    if not rows:
+        raise KeyError(f"no recorded finding with fingerprint {key}")
+    if len({row['fingerprint'] for row in rows}) > 1:
+        raise KeyError(f"fingerprint prefix {key} matches several findings, give more characters")
+    row = rows[0]
+    return row['fingerprint'], {'Rule ID': row['rule_id'], 'Severity': row['severity'], 'Category': row['category'],
+                                'Path': row['path'], 'Line': row['line'], 'File': Path(row['path']).name,
+                                'Message': row['message'], 'Fingerprint': row['fingerprint']}
+
+
+def render_scans(scans):
+    lines = [f"{'SCAN':>5}  {'STARTED':<19}  {'COMMIT':<10}  {'BRANCH':<20}  {'SCOPE':<10}  FINDINGS"]
+    lines += [f"{s['id']:>5}  {s['started']:<19}  {s['commit_sha'][:10]:<10}  {s['branch'][:20]:<20}  "
+              f"{s['scope']:<10}  {s['findings']}" for s in scans]
+    return '\n'.join(lines)
+
+
+SPARKS = '▁▂▃▄▅▆▇█'
+
+
+def trend_counts(path, last=10, by='severity'):
+    """The last full scans, oldest first, each with its finding count per severity or rule."""
+    column = {'severity': 'severity', 'rule': 'rule_id'}[by]
+    with connect(path) as conn:
+        scans = [dict(row) for row in conn.execute("SELECT * FROM scans WHERE scope = 'full' ORDER BY id DESC LIMIT ?",
+                                                   (last,))][::-1]
+        for scan in scans:
+            scan['counts'] = {row[0]: row[1] for row in conn.execute(
+                f"SELECT {column}, COUNT(*) FROM findings WHERE scan_id = ? GROUP BY {column}", (scan['id'],))}
+    return scans
+
+
+def sparkline(values):
+    top = max(values, default=0)
+    return ''.join(SPARKS[round(v * (len(SPARKS) - 1) / top)] if top else SPARKS[0] for v in values)
+
+
+def render_trends(scans, by='severity', top=15):
+    if not scans:
+        return "No scans recorded yet"
+    width = max(s['findings'] for s in scans) or 1
+    lines = [f"📈 Findings over the last {len(scans)} scan(s)"]
+    for s in scans:
+        lines.append(f"   #{s['id']:<4} {s['started'][:10]}  {s['commit_sha'][:7]:<7}  {s['findings']:>6}  "
+                     f"{'█' * round(s['findings'] * 40 / width)}")
+    keys = sorted({k for s in scans for k in s['counts']}, key=lambda k: -scans[-1]['counts'].get(k, 0))[:top]
+    spark_width = max(len(scans), len('TREND'))
+    lines += ["", f"   {by.upper():<40} {'TREND':<{spark_width}}  {'FIRST':>6} {'LAST':>6} {'CHANGE':>7}"]
+    for key in keys:
+        values = [s['counts'].get(key, 0) for s in scans]
+        lines.append(f"   {key[:40]:<40} {sparkline(values):<{spark_width}}  {values[0]:>6} {values[-1]:>6}"
+                     f" {values[-1] - values[0]:>+7}")
+    change = scans[-1]['findings'] - scans[0]['findings']
+    icon, verdict = ('📉', "improving") if change < 0 else ('📈', "degrading") if change > 0 else ('➖', "stable")
+    lines += ["", f"{icon} {verdict}: {change:+d} finding(s) since scan #{scans[0]['id']}"]
+    return '\n'.join(lines)
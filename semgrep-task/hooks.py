@@ -0,0 +1,180 @@
+import os
+import sys
+import stat
+import atexit
+import shutil
+import tempfile
+import contextlib
+import subprocess
+from pathlib import Path
+
+HOOK_MARKER = "# Installed by auto-review.py install-hook"
+
+HOOK_TEMPLATES = {
+    'pre-commit': """#!/bin/sh
+{marker}
+exec "{python}" "{script}" scan "{root}" --staged --gate "new {severity}+ > 0" --no-excel
+""",
+    # git feeds "<local ref> <local sha> <remote ref> <remote sha>" lines on stdin
+    'pre-push': """#!/bin/sh
+{marker}
+z40=0000000000000000000000000000000000000000
+empty_tree=4b825dc642cb6eb9a060e54bf8d69288fbee4904
+while read local_ref local_sha remote_ref remote_sha; do
+    [ "$local_sha" = "$z40" ] && continue
+    if [ "$remote_sha" = "$z40" ]; then
+        # New branch: review everything not yet on any remote
+        first=$(git rev-list "$local_sha" --not --remotes | tail -n 1)
+        [ -z "$first" ] && continue
+        if git rev-parse -q --verify "$first^" >/dev/null; then
+            range="$first^..$local_sha"
+        else
+            range="$empty_tree..$local_sha"
+        fi
+    else
+        range="$remote_sha..$local_sha"
+    fi
+    "{python}" "{script}" scan "{root}" --push-range "$range" --gate "new {severity}+ > 0" --no-excel || exit 1
+done
+""",
+}
+
+
+def git_toplevel(path):
+    path = Path(path)
+    res = subprocess.run(["git", "rev-parse", "--show-toplevel"], capture_output=True, text=True,
+                         cwd=path if path.is_dir() else path.parent)
+    if res.returncode != 0:
+        raise SystemExit(f"Error: {path} is not inside a git repository.")
+    return Path(res.stdout.strip())
+
+
+def changed_files(target_path, diff_args):
+    """Files reported by `git diff --name-only <diff_args>` that live under target_path."""
+    top = git_toplevel(target_path)
+    res = subprocess.run(["git", "diff", "--name-only", "--diff-filter=ACMR"] + diff_args,
+                         cwd=top, capture_output=True, text=True, check=True)
+    target = Path(target_path).resolve()
+    files = []
+    for line in res.stdout.splitlines():
+        file_path = (top / line).resolve()
+        if file_path.is_file() and (file_path == target or target in file_path.parents):
+            files.append(file_path)
+    return files
+
+
+def staged_files(target_path):
+    return changed_files(target_path, ["--cached"])
+
+
+def git_env():
+    """The environment without the GIT_INDEX_FILE, GIT_DIR and GIT_WORK_TREE a hook runs with, for git commands
+    on another worktree."""
+    return {k: v for k, v in os.environ.items() if k not in ('GIT_INDEX_FILE', 'GIT_DIR', 'GIT_WORK_TREE')}
+
+
+@contextlib.contextmanager
+def worktree(top, checkout, revision='HEAD'):
+    """A temporary detached worktree of the repository at revision, with its files checked out or not; a plain
+    folder when revision is no commit (before the first one, or the empty tree a new branch is pushed from)."""
+    folder = Path(tempfile.mkdtemp(prefix='auto-review-'))
+    tree = folder / top.name
+    env = git_env()
+    head = subprocess.run(["git", "rev-parse", "-q", "--verify", f"{revision}^{{commit}}"], cwd=top,
+                          capture_output=True, env=env).returncode == 0
+    try:
+        if head:
+            subprocess.run(["git", "worktree", "add", "--quiet", "--detach"] + ["--no-checkout"] * (not checkout)
+                           + [str(tree), revision], cwd=top, env=env, capture_output=True, check=True)
+        else:
+            tree.mkdir()
+        yield tree, head
+    finally:
+        if head:
+            subprocess.run(["git", "worktree", "remove", "--force", str(tree)], cwd=top, env=env, capture_output=True)
+        shutil.rmtree(folder, ignore_errors=True)
+
+
+def staged_snapshot(target_path):
+    """(target, staged files) in a temporary worktree holding the index, what is being committed rather than
+    the working tree with its unstaged edits. The worktree is removed when the process exits."""
+    top = git_toplevel(target_path)
+    names = subprocess.run(["git", "diff", "--cached", "--name-only", "-z", "--diff-filter=ACMR"], cwd=top,
+                           capture_output=True, text=True, check=True).stdout.split('\0')
+    context = worktree(top, checkout=False)
+    tree, _ = context.__enter__()
+    atexit.register(context.__exit__, None, None, None)
+    # Reads the index the hook's commit is being made from (GIT_INDEX_FILE)
+    subprocess.run(["git", "checkout-index", "--all", f"--prefix={tree}/"], cwd=top, check=True)
+    for name in ('GIT_INDEX_FILE', 'GIT_DIR', 'GIT_WORK_TREE'):
+        os.environ.pop(name, None)  # the scan's git commands run in the new worktree
+    target = tree / Path(target_path).resolve().relative_to(top)
+    files = [tree / name for name in names if name]
+    return target, [f for f in files if f.is_file() and (f == target or target in f.parents)]
+
+
+@contextlib.contextmanager
+def head_snapshot(target_path, revision='HEAD'):
+    """Where target_path is in a temporary worktree of HEAD (or revision), None when that is no commit."""
+    top = git_toplevel(target_path)
+    with worktree(top, checkout=True, revision=revision) as (tree, head):
+        yield tree / Path(target_path).resolve().relative_to(top) if head else None
+
+
+def range_ends(commit_range):
+    """(base, tip) of a `base..tip` range; a missing tip is HEAD."""
+    base, _, tip = commit_range.partition('..')
+    return base, tip.lstrip('.') or 'HEAD'
+
+
+def pushed_snapshot(target_path, commit_range):
+    """(target, changed files) in a temporary worktree of the range's last commit, what is being pushed rather
+    than the working tree with its uncommitted edits. The worktree is removed when the process exits."""
+    top = git_toplevel(target_path)
+    names = subprocess.run(["git", "diff", "--name-only", "-z", "--diff-filter=ACMR", commit_range], cwd=top,
+                           capture_output=True, text=True, check=True, env=git_env()).stdout.split('\0')
+    context = worktree(top, checkout=True, revision=range_ends(commit_range)[1])
+    tree, _ = context.__enter__()
+    atexit.register(context.__exit__, None, None, None)
+    target = tree / Path(target_path).resolve().relative_to(top)
+    files = [tree / name for name in names if name]
+    return target, [f for f in files if f.is_file() and (f == target or target in f.parents)]
+
+
+def pushed_files(target_path, commit_range):
+    return changed_files(target_path, [commit_range])
+
+
+def visible_files(target_path):
+    """Files under target_path that git does not ignore (tracked or untracked), or None outside a repo."""
+    res = subprocess.run(["git", "ls-files", "-z", "--cached", "--others", "--exclude-standard"],
+                         cwd=target_path, capture_output=True, text=True)
+    if res.returncode != 0:
+        return None
+    return {(Path(target_path) / name).resolve() for name in res.stdout.split('\0') if name}
+
+
+def install_hook(repo_path, hook='pre-commit', severity='ERROR', force=False):
+    """Writes a git hook that runs a diff-aware scan and blocks on findings at or above severity."""
+    top = git_toplevel(repo_path)
+    hooks_dir = Path(subprocess.run(["git", "rev-parse", "--git-path", "hooks"], cwd=top,
+                                    capture_output=True, text=True, check=True).stdout.strip())
+    if not hooks_dir.is_absolute():
+        hooks_dir = top / hooks_dir
+    hooks_dir.mkdir(parents=True, exist_ok=True)
+    hook_path = hooks_dir / hook
+
+    if hook_path.exists() and HOOK_MARKER not in hook_path.read_text(errors='ignore') and not force:
+        print(f"Error: {hook_path} already exists and was not installed by auto-review. Use --force to replace it.")
+        return False
+
+    hook_path.write_text(HOOK_TEMPLATES[hook].format(
+        marker=HOOK_MARKER,
+        python=Path(sys.executable).as_posix(),
+        script=(Path(__file__).parent.resolve() / "auto-review.py").as_posix(),
+        root=Path(repo_path).resolve().as_posix(),
+        severity=severity,
+    ))
+    hook_path.chmod(hook_path.stat().st_mode | stat.S_IXUSR | stat.S_IXGRP | stat.S_IXOTH)
+    print(f"🪝 Installed {hook} hook: {hook_path} (blocks on {severity} and above)")
+    return True
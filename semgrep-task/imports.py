@@ -0,0 +1,55 @@
+import re
+import fnmatch
+
+from config import matches_globs
+
+RULE_ID = 'IMPORT-POLICY'
+
+IMPORT_LINE = re.compile(r'^\s*import\s+(?:[\w.]+\s+)?"([^"]+)"')
+BLOCK_START = re.compile(r'^\s*import\s*\(')
+BLOCK_ENTRY = re.compile(r'^\s*(?:[\w.]+\s+)?"([^"]+)"')
+
+
+def go_imports(text):
+    """(line, import path) of a Go file's import declarations."""
+    imports, in_block = [], False
+    for number, line in enumerate(text.splitlines(), start=1):
+        if in_block:
+            if line.strip().startswith(')'):
+                in_block = False
+            elif BLOCK_ENTRY.match(line):
+                imports.append((number, BLOCK_ENTRY.match(line).group(1)))
+        elif BLOCK_START.match(line):
+            rest = line[BLOCK_START.match(line).end():]
+            imports += [(number, path) for path in re.findall(r'"([^"]+)"', rest.split(')')[0])]
+            in_block = ')' not in rest
+        elif IMPORT_LINE.match(line):
+            imports.append((number, IMPORT_LINE.match(line).group(1)))
+        elif re.match(r'^\s*(func|type|var|const)\b', line):
+            break  # imports come before any declaration
+    return imports
+
+
+def import_matches(path, pattern):
+    """Go-style patterns: `example.com/legacy/...` is the package and everything below it; globs also work."""
+    if pattern.endswith('/...'):
+        return path == pattern[:-4] or path.startswith(pattern[:-3])
+    return fnmatch.fnmatchcase(path, pattern)
+
+
+def import_violations(file_path, text, policies):
+    """(line, severity, message) of the imports a policy of the config forbids for this file.
+
+    A policy applies under its `paths` globs (everywhere by default) except under its `allow` globs.
+    """
+    active = [p for p in policies if (not p.get('paths') or matches_globs(file_path, p['paths']))
+              and not matches_globs(file_path, p.get('allow') or [])]
+    violations = []
+    for line, path in go_imports(text) if active else []:
+        for policy in active:
+            if import_matches(path, policy['import']):
+                reason = policy.get('message') or "forbidden by the import policy"
+                violations.append((line, str(policy.get('severity') or 'ERROR').upper(),
+                                   f"Import of {path} is not allowed here: {reason}"))
+                break
+    return violations
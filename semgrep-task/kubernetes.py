@@ -0,0 +1,95 @@
+# rule id -> (severity, category)
+RULES = {
+    'K8S-RESOURCE-LIMITS': ('WARNING', 'reliability'),
+    'K8S-PRIVILEGED': ('ERROR', 'security'),
+    'K8S-LATEST-TAG': ('WARNING', 'best-practice'),
+    'K8S-HOSTPATH': ('WARNING', 'security'),
+}
+RULE_IDS = list(RULES)
+
+MANIFEST_SUFFIXES = {'.yaml', '.yml'}
+LIMITS = ['cpu', 'memory']
+
+
+def mapping(node):
+    return {k.value: v for k, v in node.value} if node is not None and node.tag.endswith(':map') else {}
+
+
+def items(node):
+    return list(node.value) if node is not None and node.tag.endswith(':seq') else []
+
+
+def line_of(node):
+    return node.start_mark.line + 1
+
+
+def pod_specs(node):
+    """Mappings with a containers list: the pod spec of a Pod, a workload's template, a CronJob's job..."""
+    if node is None:
+        return
+    if node.tag.endswith(':map'):
+        if items(mapping(node).get('containers')):
+            yield node
+        for _, value in node.value:
+            yield from pod_specs(value)
+    elif node.tag.endswith(':seq'):
+        for item in node.value:
+            yield from pod_specs(item)
+
+
+def unpinned(image):
+    """Whether the image is on latest, explicitly or by having no tag (a digest pins it)."""
+    if '@' in image or '$' in image:
+        return False
+    name = image.rsplit('/', 1)[-1]  # registry:5000/app has no tag
+    return ':' not in name or name.endswith(':latest')
+
+
+def container_problems(container, kind):
+    fields = mapping(container)
+    name = fields['name'].value if 'name' in fields else kind
+    label = f"{kind} {name}"
+    subject = label[:1].upper() + label[1:]
+    limits = mapping(mapping(fields.get('resources')).get('limits'))
+    missing = [key for key in LIMITS if key not in limits]
+    if missing:
+        yield line_of(container), 'K8S-RESOURCE-LIMITS', \
+            f"{subject} has no {' or '.join(missing)} limit. Set resources.limits so it cannot starve the node"
+    privileged = mapping(fields.get('securityContext')).get('privileged')
+    if privileged is not None and str(privileged.value).lower() == 'true':
+        yield line_of(privileged), 'K8S-PRIVILEGED', \
+            f"{subject} is privileged and has full access to the host. Grant the capabilities it needs instead"
+    image = fields.get('image')
+    if image is not None and image.tag.endswith(':str') and unpinned(image.value):
+        yield line_of(image), 'K8S-LATEST-TAG', \
+            f"Image {image.value} of {label} is not pinned. Use a version tag or a digest instead of latest"
+
+
+def manifest_problems(text):
+    """(line, rule id, message) of the Kubernetes objects in a YAML file; documents without apiVersion and
+    kind are not manifests and are left alone. Unparsable files (e.g. Helm templates) give nothing."""
+    import yaml  # manifests are YAML, like .codereview.yaml
+    try:
+        documents = list(yaml.compose_all(text))
+    except yaml.YAMLError:
+        return []
+    problems = []
+    for document in documents:
+        header = mapping(document)
+        if 'apiVersion' not in header or 'kind' not in header:
+            continue
+        for spec in pod_specs(document):
+            fields = mapping(spec)
+            for key, kind in (('initContainers', 'init container'), ('containers', 'container')):
+                for container in items(fields.get(key)):
+                    problems += container_problems(container, kind)
+            for volume in items(fields.get('volumes')):
+                host_path = mapping(volume).get('hostPath')
+                if host_path is not None:
+                    path = mapping(host_path).get('path')
+                    where = f" {path.value}" if path is not None else ''
+                    problems.append((line_of(host_path), 'K8S-HOSTPATH',
+                                     f"Volume mounts the host path{where}, exposing the node's filesystem to the "
+                                     f"pod. Use a PersistentVolumeClaim, configMap or emptyDir"))
+    return sorted(set(problems))
+
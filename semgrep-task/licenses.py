@@ -0,0 +1,109 @@
+import os
+import re
+import fnmatch
+import subprocess
+from pathlib import Path
+
+from vulncheck import json_stream
+
+RULE_ID = 'LICENSE-CHECK'
+
+LICENSE_FILES = re.compile(r'^(LICEN[CS]E|COPYING|UNLICENSE)([.\-_].*)?$', re.IGNORECASE)
+
+# SPDX id -> text that identifies the license; more specific licenses come before the ones they contain
+SIGNATURES = [
+    ('AGPL-3.0', re.compile(r'GNU AFFERO GENERAL PUBLIC LICENSE', re.I)),
+    ('LGPL-3.0', re.compile(r'GNU LESSER GENERAL PUBLIC LICENSE\s+Version 3', re.I)),
+    ('LGPL-2.1', re.compile(r'GNU LESSER GENERAL PUBLIC LICENSE\s+Version 2\.1', re.I)),
+    ('GPL-3.0', re.compile(r'GNU GENERAL PUBLIC LICENSE\s+Version 3', re.I)),
+    ('GPL-2.0', re.compile(r'GNU GENERAL PUBLIC LICENSE\s+Version 2', re.I)),
+    ('MPL-2.0', re.compile(r'Mozilla Public License,?\s+(v\.\s*|version\s+)?2\.0', re.I)),
+    ('Apache-2.0', re.compile(r'Apache License,?\s+Version 2\.0', re.I)),
+    ('MIT', re.compile(r'Permission is hereby granted, free of charge', re.I)),
+    ('ISC', re.compile(r'Permission to use, copy, modify, and(/or)? distribute this software for any\s+purpose', re.I)),
+    ('BSD-3-Clause', re.compile(r'Redistribution and use in source and binary forms[\s\S]*'
+                                r'(Neither the name|names of its\s+contributors)', re.I)),
+    ('BSD-2-Clause', re.compile(r'Redistribution and use in source and binary forms', re.I)),
+    ('Unlicense', re.compile(r'This is free and unencumbered software released into the public domain', re.I)),
+    ('CC0-1.0', re.compile(r'CC0 1\.0 Universal', re.I)),
+]
+SPDX_TAG = re.compile(r'SPDX-License-Identifier:\s*([\w.\-+]+)')
+REQUIRE = re.compile(r'^\s*(?:require\s+)?([^\s()]+)\s+(v[^\s]+)', re.MULTILINE)
+
+
+def detect_license(module_dir):
+    """SPDX ids of the license files at the top of a module's source, empty when none is recognized."""
+    found = []
+    for entry in sorted(Path(module_dir).iterdir()) if Path(module_dir).is_dir() else []:
+        if not entry.is_file() or not LICENSE_FILES.match(entry.name):
+            continue
+        text = entry.read_text(encoding='utf-8', errors='ignore')
+        tag = SPDX_TAG.search(text)
+        spdx = tag.group(1) if tag else next((i for i, signature in SIGNATURES if signature.search(text)), None)
+        if spdx and spdx not in found:
+            found.append(spdx)
+    return found
+
+
+def escape_module_path(path):
+    """The module cache spells upper-case letters as !lower (golang.org/x/mod/module.EscapePath)."""
+    return re.sub(r'[A-Z]', lambda m: '!' + m.group(0).lower(), path)
+
+
+def required_modules(go_mod):
+    """(module path, version) required by a go.mod, direct and // indirect."""
+    text = Path(go_mod).read_text(encoding='utf-8', errors='ignore')
+    requires = []
+    for block in re.findall(r'^require\s*\(([\s\S]*?)^\)', text, re.MULTILINE) + \
+            re.findall(r'^require\s+([^\s(].*)$', text, re.MULTILINE):
+        requires += REQUIRE.findall(block)
+    return requires
+
+
+def dependencies(module_dir):
+    """Every module of the build list with the folder holding its source (None when not downloaded).
+
+    `go list -m -json all` gives the full graph (read-only, go.mod and go.sum are never updated); when it
+    fails, the go.mod requirements are looked up in vendor/ and the module cache.
+    """
+    try:
+        res = subprocess.run(['go', 'list', '-m', '-json', 'all'], cwd=module_dir, capture_output=True, text=True,
+                             encoding='utf-8', timeout=120, env={**os.environ, 'GOFLAGS': '-mod=readonly'})
+        if res.returncode == 0:
+            return [(m['Path'], m.get('Version', ''), m.get('Dir')) for m in json_stream(res.stdout)
+                    if not m.get('Main')]
+    except (OSError, ValueError, subprocess.TimeoutExpired):
+        pass
+    cache = Path(os.environ.get('GOMODCACHE') or Path(os.environ.get('GOPATH') or Path.home() / 'go') / 'pkg' / 'mod')
+    found = []
+    for path, version in required_modules(Path(module_dir) / 'go.mod'):
+        candidates = [Path(module_dir) / 'vendor' / path, cache / f"{escape_module_path(path)}@{version}"]
+        found.append((path, version, next((str(c) for c in candidates if c.is_dir()), None)))
+    return found
+
+
+def require_line(go_mod, path):
+    for number, line in enumerate(Path(go_mod).read_text(encoding='utf-8', errors='ignore').splitlines(), start=1):
+        if re.match(rf'^\s*(require\s+)?{re.escape(path)}\s', line):
+            return number
+    return 1
+
+
+def license_problems(module_dir, options):
+    """(module path, severity, message) for dependencies outside licenses.allow."""
+    allowed = set(options['allow'])
+    problems = []
+    for path, version, source in dependencies(module_dir):
+        if any(fnmatch.fnmatch(path, pattern) for pattern in options['ignore']):
+            continue
+        found = detect_license(source) if source else []
+        if not found:
+            if options['unknown']:
+                reason = "no recognizable license file" if source else "source not downloaded (run go mod download)"
+                problems.append((path, str(options['unknown']).upper(),
+                                 f"License of {path}@{version} unknown: {reason}"))
+        elif not allowed & set(found):
+            problems.append((path, 'ERROR',
+                             f"{path}@{version} is licensed under {' / '.join(found)}, which is not in the "
+                             f"allowed licenses ({', '.join(sorted(allowed))})"))
+    return problems
@@ -0,0 +1,125 @@
+import sys
+import json
+import tempfile
+import urllib.parse
+import urllib.request
+from pathlib import Path
+
+# LSP DiagnosticSeverity: 1 = Error, 2 = Warning, 3 = Information
+LSP_SEVERITY = {'ERROR': 1, 'WARNING': 2, 'INFO': 3}
+
+
+def read_message(stream):
+    """Reads one "Content-Length" framed JSON-RPC message, None on EOF."""
+    length = None
+    while True:
+        line = stream.readline()
+        if not line:
+            return None
+        line = line.strip()
+        if not line:
+            break
+        name, _, value = line.decode('ascii').partition(':')
+        if name.lower() == 'content-length':
+            length = int(value.strip())
+    return json.loads(stream.read(length).decode('utf-8'))
+
+
+def write_message(stream, message):
+    body = json.dumps(message).encode('utf-8')
+    stream.write(f"Content-Length: {len(body)}\r\n\r\n".encode('ascii') + body)
+    stream.flush()
+
+
+def uri_to_path(uri):
+    return Path(urllib.request.url2pathname(urllib.parse.urlparse(uri).path))
+
+
+def to_diagnostic(finding):
+    if finding.get('Range'):
+        start, end = finding['Range']['start'], finding['Range']['end']
+        lsp_range = {'start': {'line': start['line'] - 1, 'character': start['col'] - 1},
+                     'end': {'line': end['line'] - 1, 'character': end['col'] - 1}}
+    else:
+        line = finding['Line'] - 1
+        lsp_range = {'start': {'line': line, 'character': 0}, 'end': {'line': line + 1, 'character': 0}}
+    return {
+        'range': lsp_range,
+        'severity': LSP_SEVERITY.get(finding['Severity'], 3),
+        'code': finding['Rule ID'],
+        'source': 'auto-review',
+        'message': finding['Message'],
+    }
+
+
+class LanguageServer:
+    """Publishes findings as diagnostics for open documents, re-checking the buffer on every change."""
+
+    def __init__(self, review, out):
+        self.review = review  # callable(path, origin) -> findings, shared with the CLI scan
+        self.out = out
+        self.documents = {}
+
+    def diagnose(self, uri):
+        path = uri_to_path(uri)
+        # Check the unsaved buffer through a temp copy that keeps the original name and extension
+        with tempfile.TemporaryDirectory() as tmp:
+            copy = Path(tmp) / path.name
+            copy.write_text(self.documents[uri], encoding='utf-8')
+            findings = self.review(copy, path)
+        self.publish(uri, [to_diagnostic(f) for f in findings])
+
+    def publish(self, uri, diagnostics):
+        write_message(self.out, {'jsonrpc': '2.0', 'method': 'textDocument/publishDiagnostics',
+                                 'params': {'uri': uri, 'diagnostics': diagnostics}})
+
+    def handle(self, msg):
+        method = msg.get('method')
+        params = msg.get('params') or {}
+
+        if method == 'initialize':
+            return {'capabilities': {'textDocumentSync': {'openClose': True, 'change': 1, 'save': True}},
+                    'serverInfo': {'name': 'auto-review'}}
+        if method == 'shutdown':
+            return None
+        if method == 'textDocument/didOpen':
+            doc = params['textDocument']
+            self.documents[doc['uri']] = doc['text']
+            self.diagnose(doc['uri'])
+        elif method == 'textDocument/didChange':
+            uri = params['textDocument']['uri']
+            self.documents[uri] = params['contentChanges'][-1]['text']
+            self.diagnose(uri)
+        elif method == 'textDocument/didSave':
+            uri = params['textDocument']['uri']
+            if 'text' in params:
+                self.documents[uri] = params['text']
+            self.diagnose(uri)
+        elif method == 'textDocument/didClose':
+            uri = params['textDocument']['uri']
+            self.documents.pop(uri, None)
+            self.publish(uri, [])
+        return None
+
+    def serve(self, stream):
+        while True:
+            msg = read_message(stream)
+            if msg is None or msg.get('method') == 'exit':
+                return
+            try:
+                result = self.handle(msg)
+                if 'id' in msg:
+                    write_message(self.out, {'jsonrpc': '2.0', 'id': msg['id'], 'result': result})
+            except Exception as e:
+                if 'id' in msg:
+                    write_message(self.out, {'jsonrpc': '2.0', 'id': msg['id'],
+                                             'error': {'code': -32603, 'message': str(e)}})
+                else:
+                    print(f"auto-review LSP: {e}", file=sys.stderr)
+
+
+def serve_lsp(review):
+    """Runs the language server over stdio. Scanner output is sent to stderr to keep stdout clean."""
+    out = sys.stdout.buffer
+    sys.stdout = sys.stderr
+    LanguageServer(review, out).serve(sys.stdin.buffer)
@@ -0,0 +1,61 @@
+import threading
+
+# Upper bounds (seconds) of the scan duration histogram buckets
+DURATION_BUCKETS = [1, 5, 15, 60, 300, 900]
+
+
+def escape(value):
+    return str(value).replace('\\', '\\\\').replace('"', '\\"').replace('\n', '\\n')
+
+
+class Metrics:
+    """In-process counters rendered in the Prometheus text format, no client library needed."""
+
+    def __init__(self):
+        self.lock = threading.Lock()
+        self.counters = {}  # (name, labels tuple) -> value
+        self.durations = [0] * (len(DURATION_BUCKETS) + 1)
+        self.duration_sum = 0.0
+        self.queue_depth = lambda: 0
+
+    def inc(self, name, amount=1, **labels):
+        key = (name, tuple(sorted(labels.items())))
+        with self.lock:
+            self.counters[key] = self.counters.get(key, 0) + amount
+
+    def observe_scan(self, seconds, findings, status):
+        with self.lock:
+            self.duration_sum += seconds
+            index = next((i for i, bound in enumerate(DURATION_BUCKETS) if seconds <= bound), len(DURATION_BUCKETS))
+            self.durations[index] += 1
+        self.inc('auto_review_scans_total', status=status)
+        for f in findings or []:
+            self.inc('auto_review_findings_total', severity=f['Severity'], rule=f['Rule ID'])
+
+    def render(self):
+        lines = [
+            '# HELP auto_review_queue_depth Scans waiting to run',
+            '# TYPE auto_review_queue_depth gauge',
+            f"auto_review_queue_depth {self.queue_depth()}",
+            '# HELP auto_review_scan_duration_seconds Time spent per scan',
+            '# TYPE auto_review_scan_duration_seconds histogram',
+        ]
+        with self.lock:
+            cumulative = 0
+            for bound, count in zip(DURATION_BUCKETS + ['+Inf'], self.durations):
+                cumulative += count
+                lines.append(f'auto_review_scan_duration_seconds_bucket{{le="{bound}"}} {cumulative}')
+            lines.append(f"auto_review_scan_duration_seconds_sum {self.duration_sum}")
+            lines.append(f"auto_review_scan_duration_seconds_count {cumulative}")
+
+            for name in sorted({name for name, _ in self.counters}):
+                lines.append(f"# TYPE {name} counter")
+                for (counter, labels), value in sorted(self.counters.items()):
+                    if counter != name:
+                        continue
+                    label_text = ','.join(f'{k}="{escape(v)}"' for k, v in labels)
+                    lines.append(f"{name}{{{label_text}}} {value}" if label_text else f"{name} {value}")
+        return '\n'.join(lines) + '\n'
+
+
+METRICS = Metrics()
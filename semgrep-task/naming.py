@@ -0,0 +1,159 @@
+import fnmatch
+import re
+
+from structlayout import strip_comments
+
+# rule id -> (severity, category)
+RULES = {
+    'NAMING-UNDERSCORE': ('INFO', 'code-quality'),
+    'NAMING-ACRONYM': ('INFO', 'code-quality'),
+    'NAMING-PACKAGE': ('WARNING', 'code-quality'),
+    'NAMING-TEST': ('WARNING', 'code-quality'),
+}
+RULE_IDS = list(RULES)
+
+# golint's common initialisms; naming.acronyms adds to them
+ACRONYMS = {'ACL', 'API', 'ASCII', 'CPU', 'CSS', 'DNS', 'EOF', 'GUID', 'HTML', 'HTTP', 'HTTPS', 'ID', 'IP', 'JSON',
+            'LHS', 'QPS', 'RAM', 'RHS', 'RPC', 'SLA', 'SMTP', 'SQL', 'SSH', 'TCP', 'TLS', 'TTL', 'UDP', 'UI', 'UID',
+            'UUID', 'URI', 'URL', 'VM', 'XML', 'XMPP', 'XSRF', 'XSS'}
+
+GENERATED = re.compile(r'^// Code generated .* DO NOT EDIT\.$', re.MULTILINE)
+PACKAGE = re.compile(r'^package\s+(\w+)', re.MULTILINE)
+FUNC = re.compile(r'^func\s+(?:\([^)]*\)\s*)?(\w+)')
+TEST_FUNC = re.compile(r'^func\s+(\w+)\s*\(\s*\w+\s+\*testing\.([TBF])\s*\)')
+SINGLE = re.compile(r'^\s*(?:type|var|const)\s+(\w+(?:\s*,\s*\w+)*)')
+GROUP = re.compile(r'^\s*(?:type|var|const)\s*\(\s*$')
+MEMBER = re.compile(r'^\s*(\w+(?:\s*,\s*\w+)*)(?=\s|$)')
+SHORT = re.compile(r'^\s*(?:(?:if|for|switch)\s+)?(\w+(?:\s*,\s*\w+)*)\s*:=')
+WORD = re.compile(r'[A-Z]+(?![a-z])|[A-Z]?[a-z]+|\d+')
+# the test function kinds go test runs: prefix -> parameter type
+TEST_KINDS = {'Test': 'T', 'Benchmark': 'B', 'Fuzz': 'F'}
+
+
+def mixed_caps(name):
+    """user_name -> userName, MAX_SIZE -> MaxSize, _private -> private."""
+    parts = [p for p in name.split('_') if p]
+    if not parts:
+        return name
+    if all(p.isupper() or p.isdigit() for p in parts):
+        parts = [p.capitalize() for p in parts]
+    return parts[0] + ''.join(p[0].upper() + p[1:] for p in parts[1:])
+
+
+def acronym_fix(name, acronyms):
+    """The name with its initialisms in one case (userId -> userID, HttpUrl -> HTTPURL), None when right."""
+    fixed, changed = [], False
+    for i, match in enumerate(WORD.finditer(name)):
+        word = match.group(0)
+        if word.upper() in acronyms and word != word.upper() and not (i == 0 and word.islower()):
+            word, changed = word.upper(), True
+        fixed.append((match.start(), match.end(), word))
+    if not changed:
+        return None
+    out, last = [], 0
+    for start, end, word in fixed:
+        out += [name[last:start], word]
+        last = end
+    return ''.join(out) + name[last:]
+
+
+def declarations(code):
+    """(line, name) of the functions, methods, types, variables and constants a Go file declares, groups and
+    := included; struct fields and parameters are not."""
+    found, group, depth = [], None, 0
+    for number, line in enumerate(code.splitlines(), start=1):
+        names = []
+        if group is not None and depth == group:
+            if line.strip().startswith(')'):
+                group = None
+            elif MEMBER.match(line):
+                names = MEMBER.match(line).group(1)
+        elif GROUP.match(line):
+            group = depth
+        elif FUNC.match(line):
+            names = FUNC.match(line).group(1)
+        elif SINGLE.match(line) or SHORT.match(line):
+            names = (SINGLE.match(line) or SHORT.match(line)).group(1)
+        found += [(number, name) for name in re.split(r'\s*,\s*', names) if name] if names else []
+        depth += line.count('{') - line.count('}')
+    return found
+
+
+def upper_first(name):
+    return name[:1].upper() + name[1:]
+
+
+def test_problem(name, kind):
+    """(severity, why) for a function taking *testing.T, B or F that is misnamed, None when go test runs it and
+    the name reads well."""
+    prefix = next((p for p in TEST_KINDS if name.startswith(p)), None)
+    expected = next(p for p, k in TEST_KINDS.items() if k == kind)
+    if prefix is None and not re.match(r'(?i)test|bench|fuzz', name):
+        return None  # a helper taking t
+    if prefix is None or name[len(prefix):][:1].islower():
+        rest = re.sub(r'^(?i:test|benchmark|bench|fuzz)_?', '', name) if prefix is None else name[len(prefix):]
+        return 'WARNING', (f"{name} takes *testing.{kind} but go test never runs it: the name must be {expected} "
+                           f"followed by nothing or an uppercase letter ({expected}{upper_first(mixed_caps(rest))})")
+    if TEST_KINDS[prefix] != kind:
+        return 'WARNING', (f"{name} is named like a {prefix} function but takes *testing.{kind}; go test expects "
+                           f"*testing.{TEST_KINDS[prefix]}")
+    rest = name[len(prefix):]
+    if rest.startswith('_') or rest.count('_') > 1:
+        return 'INFO', (f"{name} is snake_case; a test is named {prefix} plus what it tests, with at most one "
+                        f"underscore before a method or scenario ({prefix}{upper_first(mixed_caps(rest))})")
+    return None
+
+
+def package_problem(name, is_test, max_length):
+    base = name[:-len('_test')] if is_test and name.endswith('_test') else name
+    if not re.match(r'^[a-z][a-z0-9]*$', base):
+        return (f"Package name {name} should be lowercase, one word without underscores or mixedCaps "
+                f"({mixed_caps(base).lower()})")
+    if max_length and len(base) > max_length:
+        return f"Package name {name} is {len(base)} characters; package names are short, a single word " \
+               f"(at most {max_length})"
+    return None
+
+
+def naming_problems(file_path, text, options):
+    """(line, rule id, severity, message) of the Go naming conventions a file breaks: underscores and initialisms
+    in the names it declares, its package name (reported in the first Go file of the folder only) and its test
+    functions. Generated files and names matching naming.exceptions are left alone."""
+    if GENERATED.search(text):
+        return []
+    exceptions = options['exceptions']
+    acronyms = ACRONYMS | {a.upper() for a in options['acronyms']}
+    excepted = lambda name: any(fnmatch.fnmatchcase(name, pattern) for pattern in exceptions)  # noqa: E731
+    code = strip_comments(text)
+    problems, tests = [], set()
+    is_test = file_path.name.endswith('_test.go')
+    if is_test:
+        for number, line in enumerate(code.splitlines(), start=1):
+            match = TEST_FUNC.match(line)
+            if match and not excepted(match.group(1)):
+                tests.add(match.group(1))
+                problem = test_problem(*match.groups())
+                if problem:
+                    problems.append((number, 'NAMING-TEST', *problem))
+    for number, name in declarations(code):
+        if name == '_' or excepted(name):
+            continue
+        if '_' in name.strip('_'):
+            if name not in tests:
+                better = mixed_caps(name)
+                problems.append((number, 'NAMING-UNDERSCORE', RULES['NAMING-UNDERSCORE'][0],
+                                 f"{name} has an underscore; Go names are mixedCaps "
+                                 f"({acronym_fix(better, acronyms) or better})"))
+            continue
+        fixed = acronym_fix(name, acronyms)
+        if fixed:
+            problems.append((number, 'NAMING-ACRONYM', RULES['NAMING-ACRONYM'][0],
+                             f"{name} should be {fixed}: initialisms such as ID, URL and HTTP keep one case"))
+    package = PACKAGE.search(code)
+    first = min(p.name for p in file_path.parent.glob('*.go')) if file_path.parent.is_dir() else file_path.name
+    if package and file_path.name == first and package.group(1) != 'main' and not excepted(package.group(1)):
+        message = package_problem(package.group(1), is_test, options['max_package_length'])
+        if message:
+            problems.append((code[:package.start()].count('\n') + 1, 'NAMING-PACKAGE',
+                             RULES['NAMING-PACKAGE'][0], message))
+    return sorted(problems)
@@ -0,0 +1,307 @@
+import os
+import html
+import smtplib
+from email.message import EmailMessage
+
+from publishers import request_json, gate_result, fingerprint
+from uploads import current_branch
+
+SEVERITIES = ['ERROR', 'WARNING', 'INFO']
+
+
+def by_owner(findings):
+    """{team: findings} of the findings with an Owner (CODEOWNERS), by team name with "unowned" last; a finding
+    owned by several teams is listed under each."""
+    teams = {}
+    for f in findings:
+        if 'Owner' in f:
+            for owner in f['Owner'].split() or ['unowned']:
+                teams.setdefault(owner, []).append(f)
+    return dict(sorted(teams.items(), key=lambda item: (item[0] == 'unowned', item[0])))
+
+
+def scan_summary(findings, failed=()):
+    """Counts, gate result and report link shared by every notifier; failed are the conditions of the quality
+    gate that failed. New findings are those the baseline or the last recorded scan doesn't have, every finding
+    without either."""
+    counts = {sev: sum(1 for f in findings if f['Severity'] == sev) for sev in SEVERITIES}
+    new = {sev: sum(1 for f in findings if f['Severity'] == sev and f.get('Status', 'new') == 'new')
+           for sev in SEVERITIES}
+    owners = {owner: len(owned) for owner, owned in by_owner(findings).items()}
+    top = next((sev for sev in SEVERITIES if new[sev]), None)
+    report_url = (os.environ.get('REPORT_URL') or os.environ.get('CI_JOB_URL')
+                  or os.environ.get('BUILD_URL') or '')
+    ref = (os.environ.get('CI_COMMIT_REF_NAME') or os.environ.get('GITHUB_REF_NAME')
+           or os.environ.get('BRANCH_NAME') or '')
+    return {
+        'counts': counts,
+        'new': new,
+        'owners': owners,
+        'total': len(findings),
+        'top_severity': top,
+        'passed': gate_result(failed)[0],
+        'report_url': report_url,
+        'ref': ref,
+    }
+
+
+def summary_text(summary):
+    counts = summary['counts']
+    gate = '✅ passed' if summary['passed'] else '❌ failed'
+    lines = [
+        f"Code review: {summary['total']} finding(s), quality gate {gate}",
+        f"🆕 {summary['new']['ERROR']} new error  {summary['new']['WARNING']} new warning  "
+        f"{summary['new']['INFO']} new info",
+        f"🔴 {counts['ERROR']} error  🟠 {counts['WARNING']} warning  🔵 {counts['INFO']} info",
+    ]
+    if summary['owners']:
+        by_owner = sorted(summary['owners'].items(), key=lambda item: -item[1])
+        lines.append("By owner: " + ', '.join(f"{owner} {count}" for owner, count in by_owner))
+    if summary['report_url']:
+        lines.append(f"Full report: {summary['report_url']}")
+    return '\n'.join(lines)
+
+
+def routed_env(name, severity):
+    """Looks up NAME_<SEVERITY> first so each severity can go to its own channel."""
+    if severity:
+        value = os.environ.get(f"{name}_{severity}")
+        if value:
+            return value
+    return os.environ.get(name)
+
+
+def notify_slack(findings, base_dir, failed=()):
+    """Posts the scan summary to Slack, routed by the highest severity of the new findings."""
+    summary = scan_summary(findings, failed)
+    text = summary_text(summary)
+    severity = summary['top_severity']
+
+    token = os.environ.get('SLACK_BOT_TOKEN')
+    channel = routed_env('SLACK_CHANNEL', severity)
+    webhook = routed_env('SLACK_WEBHOOK_URL', severity)
+
+    if token and channel:
+        res = request_json('POST', 'https://slack.com/api/chat.postMessage',
+                           {'Authorization': f"Bearer {token}"}, {'channel': channel, 'text': text})
+        if not res.get('ok'):
+            print(f"⚠️ Slack API error: {res.get('error')}")
+            return
+    elif webhook:
+        # Incoming webhooks answer with plain "ok" instead of JSON
+        request_json('POST', webhook, payload={'text': text}, raw=True)
+    else:
+        print("⚠️ Slack notifier skipped: set SLACK_WEBHOOK_URL, or SLACK_BOT_TOKEN and SLACK_CHANNEL")
+        return
+
+    print(f"💬 Slack: summary sent ({severity or 'clean'})")
+
+    # Targeted per-team summaries, e.g. SLACK_OWNER_CHANNELS="@org/payments=#payments,@org/web=#web-team"
+    if token:
+        for owner, team_channel in owner_channels().items():
+            owned = [f for f in findings if owner in f.get('Owner', '').split()]
+            if owned:
+                request_json('POST', 'https://slack.com/api/chat.postMessage', {'Authorization': f"Bearer {token}"},
+                             {'channel': team_channel, 'text': f"{owner}\n" + summary_text(scan_summary(owned, failed))})
+                print(f"💬 Slack: {len(owned)} finding(s) for {owner} sent to {team_channel}")
+
+
+def owner_channels(variable='SLACK_OWNER_CHANNELS'):
+    """{owner: destination} of a variable like "@org/payments=#payments,@org/web=#web-team"."""
+    pairs = [p.split('=', 1) for p in os.environ.get(variable, '').split(',') if '=' in p]
+    return {owner.strip(): channel.strip() for owner, channel in pairs}
+
+
+def teams_card(summary, title='Code review'):
+    counts = summary['counts']
+    by_owner = sorted(summary['owners'].items(), key=lambda item: -item[1])
+    card = {
+        '$schema': 'http://adaptivecards.io/schemas/adaptive-card.json',
+        'type': 'AdaptiveCard',
+        'version': '1.4',
+        'body': [
+            {'type': 'TextBlock', 'size': 'Medium', 'weight': 'Bolder', 'text': title},
+            {'type': 'TextBlock', 'wrap': True,
+             'color': 'Good' if summary['passed'] else 'Attention',
+             'text': f"Quality gate {'passed' if summary['passed'] else 'failed'} with {summary['total']} finding(s)"},
+            {'type': 'FactSet', 'facts': [{'title': sev.title(), 'value': str(counts[sev])} for sev in SEVERITIES]},
+            {'type': 'TextBlock', 'weight': 'Bolder', 'text': 'New'},
+            {'type': 'FactSet', 'facts': [{'title': sev.title(), 'value': str(summary['new'][sev])}
+                                          for sev in SEVERITIES]},
+        ],
+    }
+    if by_owner:
+        card['body'] += [
+            {'type': 'TextBlock', 'weight': 'Bolder', 'text': 'By owner'},
+            {'type': 'FactSet', 'facts': [{'title': owner, 'value': str(count)} for owner, count in by_owner]},
+        ]
+    if summary['report_url']:
+        card['actions'] = [{'type': 'Action.OpenUrl', 'title': 'Full report', 'url': summary['report_url']}]
+    return {
+        'type': 'message',
+        'attachments': [{'contentType': 'application/vnd.microsoft.card.adaptive', 'content': card}],
+    }
+
+
+def notify_teams(findings, base_dir, failed=()):
+    """Posts the scan summary to a Teams channel as an Adaptive Card."""
+    summary = scan_summary(findings, failed)
+    severity = summary['top_severity']
+    webhook = routed_env('TEAMS_WEBHOOK_URL', severity)
+    if not webhook:
+        print("⚠️ Teams notifier skipped: set TEAMS_WEBHOOK_URL")
+        return
+
+    request_json('POST', webhook, payload=teams_card(summary), raw=True)
+    print(f"💬 Teams: summary sent ({severity or 'clean'})")
+
+    # Targeted per-team cards, e.g. TEAMS_OWNER_WEBHOOKS="@org/payments=https://...,@org/web=https://..."
+    for owner, team_webhook in owner_channels('TEAMS_OWNER_WEBHOOKS').items():
+        owned = [f for f in findings if owner in f.get('Owner', '').split()]
+        if owned:
+            card = teams_card(scan_summary(owned, failed), f"Code review for {owner}")
+            request_json('POST', team_webhook, payload=card, raw=True)
+            print(f"💬 Teams: {len(owned)} finding(s) for {owner} sent")
+
+
+DISCORD_COLORS = {'ERROR': 0xE74C3C, 'WARNING': 0xE67E22, 'INFO': 0x3498DB, None: 0x2ECC71}
+
+
+def notify_discord(findings, base_dir, failed=()):
+    """Posts the scan summary to a Discord channel webhook as an embed."""
+    summary = scan_summary(findings, failed)
+    webhook = os.environ.get('DISCORD_WEBHOOK_URL')
+    if not webhook:
+        print("⚠️ Discord notifier skipped: set DISCORD_WEBHOOK_URL")
+        return
+
+    title = f"Code review on {summary['ref']}" if summary['ref'] else "Code review"
+    embed = {
+        'title': title,
+        'description': f"Quality gate {'passed ✅' if summary['passed'] else 'failed ❌'} with {summary['total']} finding(s)",
+        'color': DISCORD_COLORS[summary['top_severity']],
+        'fields': [{'name': sev.title(), 'value': str(summary['counts'][sev]), 'inline': True} for sev in SEVERITIES],
+    }
+    if summary['report_url']:
+        embed['url'] = summary['report_url']
+
+    request_json('POST', webhook, payload={'username': 'auto-review', 'embeds': [embed]}, raw=True)
+    print(f"💬 Discord: summary sent ({summary['top_severity'] or 'clean'})")
+
+
+def findings_table(findings):
+    rows = ''.join(
+        f"<tr><td>{html.escape(f['Severity'])}</td><td>{html.escape(f['Path'])}:{f['Line']}</td>"
+        f"<td>{html.escape(f.get('Owner', ''))}</td>"
+        f"<td>{html.escape(f['Rule ID'])}</td><td>{html.escape(f['Message'])}</td></tr>"
+        for f in findings)
+    return ("<table border='1' cellpadding='4' cellspacing='0'>"
+            "<tr><th>Severity</th><th>Location</th><th>Owner</th><th>Rule</th><th>Message</th></tr>"
+            f"{rows}</table>")
+
+
+def html_report(findings, summary):
+    """The summary and a findings table, one section per team when the repository has a CODEOWNERS file."""
+    summary_html = html.escape(summary_text(summary)).replace('\n', '<br>')
+    teams = by_owner(findings)
+    if not teams:
+        return f"<p>{summary_html}</p>" + findings_table(findings)
+    return f"<p>{summary_html}</p>" + ''.join(
+        f"<h3>{html.escape(owner)}: {len(owned)} finding(s)</h3>" + findings_table(owned)
+        for owner, owned in teams.items())
+
+
+def finding_line(f):
+    return f"[{f['Severity']}] {f['Path']}:{f['Line']} {f['Rule ID']}: {f['Message']}"
+
+
+def text_report(findings, summary):
+    teams = by_owner(findings)
+    if not teams:
+        return summary_text(summary) + '\n\n' + '\n'.join(map(finding_line, findings))
+    return summary_text(summary) + ''.join(
+        f"\n\n{owner}: {len(owned)} finding(s)\n" + '\n'.join(map(finding_line, owned)) for owner, owned in teams.items())
+
+
+# SMTP_TLS -> default SMTP_PORT: STARTTLS on the submission port, implicit TLS (SMTPS) or a plain relay
+SMTP_TLS_MODES = {'starttls': 587, 'ssl': 465, 'none': 25}
+
+
+def notify_email(findings, base_dir, failed=()):
+    """Mails the findings report over SMTP, always or only when new errors are found (EMAIL_ON)."""
+    summary = scan_summary(findings, failed)
+    host = os.environ.get('SMTP_HOST')
+    recipients = [r.strip() for r in os.environ.get('EMAIL_TO', '').split(',') if r.strip()]
+    if not host or not recipients:
+        print("⚠️ Email notifier skipped: set SMTP_HOST and EMAIL_TO")
+        return
+    if os.environ.get('EMAIL_ON', 'errors') == 'errors' and not summary['new']['ERROR']:
+        print("📧 Email: no new ERROR findings, nothing sent")
+        return
+    tls = os.environ.get('SMTP_TLS', 'starttls')
+    if tls not in SMTP_TLS_MODES:
+        print(f"⚠️ Email notifier skipped: SMTP_TLS must be one of {', '.join(SMTP_TLS_MODES)}, not {tls}")
+        return
+
+    msg = EmailMessage()
+    msg['Subject'] = (f"[code-review] {summary['counts']['ERROR']} error(s), "
+                      f"quality gate {'passed' if summary['passed'] else 'failed'}")
+    msg['From'] = os.environ.get('EMAIL_FROM', os.environ.get('SMTP_USER', 'auto-review@localhost'))
+    msg['To'] = ', '.join(recipients)
+    msg.set_content(text_report(findings, summary))
+    msg.add_alternative(html_report(findings, summary), subtype='html')
+
+    smtp_class = smtplib.SMTP_SSL if tls == 'ssl' else smtplib.SMTP
+    with smtp_class(host, int(os.environ.get('SMTP_PORT', SMTP_TLS_MODES[tls])), timeout=30) as smtp:
+        if tls == 'starttls':
+            smtp.starttls()
+        if os.environ.get('SMTP_USER'):
+            smtp.login(os.environ['SMTP_USER'], os.environ.get('SMTP_PASSWORD', ''))
+        smtp.send_message(msg)
+
+    print(f"📧 Email: report sent to {len(recipients)} recipient(s)")
+
+
+def notify_pagerduty(findings, base_dir, failed=()):
+    """Triggers a PagerDuty incident per ERROR security finding a scan of a protected branch introduces, that
+    is new against the baseline or the last recorded scan."""
+    routing_key = os.environ.get('PAGERDUTY_ROUTING_KEY')
+    if not routing_key:
+        print("⚠️ PagerDuty alerter skipped: set PAGERDUTY_ROUTING_KEY")
+        return
+    branch = current_branch(base_dir)
+    protected = [b.strip() for b in os.environ.get('PAGERDUTY_BRANCHES', 'main,master').split(',')]
+    if branch not in protected:
+        print(f"🚨 PagerDuty: {branch} is not a protected branch, no alert")
+        return
+
+    if findings and not any('Status' in f for f in findings):
+        print("⚠️ PagerDuty alerter skipped: needs --baseline or --history to tell the findings a scan introduces")
+        return
+    critical = [f for f in findings if f['Severity'] == 'ERROR' and f.get('Category') == 'security'
+                and f['Status'] == 'new']
+    for f in critical:
+        # One dedup key per occurrence, so a rerun over the same commit updates its incident instead of paging
+        request_json('POST', 'https://events.pagerduty.com/v2/enqueue', payload={
+            'routing_key': routing_key,
+            'event_action': 'trigger',
+            'dedup_key': fingerprint(f),
+            'payload': {
+                'summary': f"[{branch}] {f['Rule ID']}: {f['Message']}"[:1024],
+                'source': f"{f['Path']}:{f['Line']}",
+                'severity': 'critical',
+                'component': f['Path'],
+                'class': f.get('Category'),
+                'custom_details': {'rule': f['Rule ID'], 'line': f['Line'], 'branch': branch},
+            },
+        })
+    print(f"🚨 PagerDuty: {len(critical)} new critical security finding(s) alerted on {branch}")
+
+
+NOTIFIERS = {
+    'slack': notify_slack,
+    'teams': notify_teams,
+    'discord': notify_discord,
+    'email': notify_email,
+    'pagerduty': notify_pagerduty,
+}
@@ -0,0 +1,87 @@
+import os
+import json
+import time
+import contextlib
+import threading
+
+PROFILE_MODES = ['cpu', 'mem', 'trace']
+
+
+class Profiler:
+    """Per-span timings of a scan (always collected once started) plus a cpu, mem or trace profile."""
+
+    def __init__(self):
+        self.mode = None
+        self.timings = {}  # span name -> [count, total seconds, max seconds]
+        self.events = []   # Chrome trace events, for --profile trace
+        self.started = None
+
+    def start(self, mode):
+        self.mode = mode
+        self.started = time.perf_counter()
+        if mode == 'cpu':
+            import cProfile
+            self.cpu = cProfile.Profile()
+            self.cpu.enable()
+        elif mode == 'mem':
+            import tracemalloc
+            tracemalloc.start(25)
+
+    @contextlib.contextmanager
+    def timed(self, name, attributes):
+        if self.started is None:
+            yield
+            return
+        key = f"{name} [{attributes['rules']}]" if 'rules' in attributes else name
+        begin = time.perf_counter()
+        try:
+            yield
+        finally:
+            elapsed = time.perf_counter() - begin
+            entry = self.timings.setdefault(key, [0, 0.0, 0.0])
+            entry[0] += 1
+            entry[1] += elapsed
+            entry[2] = max(entry[2], elapsed)
+            if self.mode == 'trace':
+                self.events.append({'name': name, 'ph': 'X', 'pid': os.getpid(), 'tid': threading.get_ident(),
+                                    'ts': (begin - self.started) * 1e6, 'dur': elapsed * 1e6,
+                                    'args': {k: str(v) for k, v in attributes.items()}})
+
+    def breakdown(self):
+        total = time.perf_counter() - self.started
+        lines = [f"⏱️ Scan took {total:.2f}s", f"   {'SPAN':<40} {'CALLS':>6} {'TOTAL':>9} {'MAX':>8}"]
+        for key, (count, spent, longest) in sorted(self.timings.items(), key=lambda t: t[1][1], reverse=True):
+            lines.append(f"   {key:<40} {count:>6} {spent:>8.2f}s {longest:>7.2f}s")
+        return '\n'.join(lines)
+
+    def stop(self, prefix='auto-review'):
+        """Writes the profile next to the working directory and returns the files written."""
+        written = []
+        if self.mode == 'cpu':
+            self.cpu.disable()
+            self.cpu.dump_stats(f"{prefix}-cpu.prof")
+            written.append(f"{prefix}-cpu.prof")
+        elif self.mode == 'mem':
+            import tracemalloc
+            stats = tracemalloc.take_snapshot().statistics('traceback')
+            current, peak = tracemalloc.get_traced_memory()
+            tracemalloc.stop()
+            lines = [f"current {current / 1024:.0f} KiB, peak {peak / 1024:.0f} KiB", ""]
+            for stat in stats[:30]:
+                lines.append(f"{stat.size / 1024:.1f} KiB in {stat.count} blocks")
+                lines += [f"    {line}" for line in stat.traceback.format()]
+            with open(f"{prefix}-mem.txt", 'w', encoding='utf-8') as f:
+                f.write('\n'.join(lines) + '\n')
+            written.append(f"{prefix}-mem.txt")
+        elif self.mode == 'trace':
+            with open(f"{prefix}-trace.json", 'w', encoding='utf-8') as f:
+                json.dump({'traceEvents': self.events}, f)
+            written.append(f"{prefix}-trace.json")
+        with open(f"{prefix}-timings.json", 'w', encoding='utf-8') as f:
+            json.dump({key: {'calls': c, 'total_seconds': round(t, 4), 'max_seconds': round(m, 4)}
+                       for key, (c, t, m) in self.timings.items()}, f, indent=2)
+        written.append(f"{prefix}-timings.json")
+        return written
+
+
+PROFILER = Profiler()
@@ -0,0 +1,532 @@
+import os
+import re
+import json
+import base64
+import hashlib
+import uuid
+import subprocess
+import functools
+import urllib.error
+import urllib.parse
+import urllib.request
+
+# Hidden marker embedded in every comment we post, so later runs can find their own comments
+MARKER = "<!-- code-review:{} -->"
+MARKER_RE = re.compile(r"<!-- code-review:([0-9a-f]{40}) -->")
+
+
+def fingerprint(finding):
+    """Stable id for a finding. Line numbers are left out so moving code does not re-open it; the Fingerprint
+    set by fingerprint_findings also tells the occurrences of one rule in a file apart."""
+    if finding.get('Fingerprint'):
+        return finding['Fingerprint']
+    key = f"{finding['Rule ID']}|{finding['Path']}|{finding['Message']}"
+    return hashlib.sha1(key.encode('utf-8')).hexdigest()
+
+
+def fingerprint_findings(findings, lines):
+    """Sets the Fingerprint of one file's findings from their rule, path and message, the code they matched
+    with its whitespace collapsed, and their rank among identical matches of the file."""
+    seen = {}
+    for f in sorted(findings, key=lambda f: (f['Line'], f['Range']['start']['col'] if f.get('Range') else 0)):
+        first, last = (f['Range']['start']['line'], f['Range']['end']['line']) if f.get('Range') else (f['Line'],) * 2
+        snippet = ' '.join(' '.join(lines[max(first - 1, 0):last]).split())
+        key = f"{f['Rule ID']}|{f['Path']}|{f['Message']}|{snippet}"
+        rank = seen[key] = seen.get(key, -1) + 1
+        f['Fingerprint'] = hashlib.sha1(f"{key}|{rank}".encode('utf-8')).hexdigest()
+
+
+def request_json(method, url, headers=None, payload=None, raw=False):
+    """Small JSON-over-HTTP helper so publishers don't need third-party clients."""
+    data = json.dumps(payload).encode('utf-8') if payload is not None else None
+    req = urllib.request.Request(url, data=data, method=method, headers=dict(headers or {}))
+    if data is not None:
+        req.add_header('Content-Type', 'application/json')
+    with urllib.request.urlopen(req, timeout=30) as res:
+        body = res.read().decode('utf-8')
+    if raw:
+        return body
+    # Gerrit prefixes JSON responses with an XSSI guard
+    if body.startswith(")]}'"):
+        body = body[4:]
+    return json.loads(body) if body.strip() else None
+
+
+def gate_result(failed):
+    """(passed, description) of the quality gate, from the failed conditions evaluate_gate returned."""
+    if not failed:
+        return True, 'Quality gate passed'
+    return False, 'Quality gate failed: ' + ', '.join(f"{c['text']} (found {n})" for c, n, _ in failed)
+
+
+@functools.lru_cache(maxsize=None)
+def repo_root(base_dir):
+    res = subprocess.run(["git", "rev-parse", "--show-toplevel"], cwd=base_dir, capture_output=True, text=True)
+    return res.stdout.strip() if res.returncode == 0 else os.getcwd()
+
+
+def repo_path(base_dir, finding):
+    """Path of the finding relative to the repository root, as code hosts expect it."""
+    return os.path.relpath(os.path.join(base_dir, finding['Path']), repo_root(str(base_dir))).replace(os.sep, '/')
+
+
+def comment_body(finding):
+    return (f"**{finding['Severity']}** `{finding['Rule ID']}`: {finding['Message']}\n\n"
+            + MARKER.format(fingerprint(finding)))
+
+
+HUNK_RE = re.compile(r'^@@ -\d+(?:,\d+)? \+(\d+)(?:,\d+)? @@')
+
+
+def added_lines(diff):
+    """Line numbers a unified diff adds to the new file, the only ones a code host anchors new-side comments on."""
+    lines, number = set(), 0
+    for row in diff.splitlines():
+        match = HUNK_RE.match(row)
+        if match:
+            number = int(match.group(1))
+        elif row.startswith('+'):
+            lines.add(number)
+            number += 1
+        elif not row.startswith(('-', '\\')):
+            number += 1
+    return lines
+
+
+def gitlab_diff_lines(mr_url, headers):
+    """{path: added line numbers} of the merge request diff."""
+    lines, page = {}, 1
+    while True:
+        diffs = request_json('GET', f"{mr_url}/diffs?per_page=100&page={page}", headers)
+        if not diffs:
+            return lines
+        for diff in diffs:
+            if not diff.get('deleted_file'):
+                lines.setdefault(diff['new_path'], set()).update(added_lines(diff.get('diff') or ''))
+        page += 1
+
+
+def publish_gitlab(findings, base_dir, failed=(), resolve=True):
+    """Opens MR discussions for new findings on lines the MR adds, reopens the resolved ones whose finding is
+    back and, with resolve, resolves the ones that are fixed."""
+    api = os.environ.get('CI_API_V4_URL')
+    project = os.environ.get('CI_PROJECT_ID')
+    mr_iid = os.environ.get('CI_MERGE_REQUEST_IID')
+    token = os.environ.get('GITLAB_TOKEN')
+    if not all([api, project, mr_iid, token]):
+        print("⚠️ GitLab publisher skipped: CI_API_V4_URL, CI_PROJECT_ID, CI_MERGE_REQUEST_IID and GITLAB_TOKEN are required")
+        return
+
+    mr_url = f"{api}/projects/{project}/merge_requests/{mr_iid}"
+    headers = {'PRIVATE-TOKEN': token}
+    try:
+        diff_refs = request_json('GET', mr_url, headers)['diff_refs']
+        diff_lines = gitlab_diff_lines(mr_url, headers)
+
+        # Collect the discussions we opened on earlier pipeline runs
+        existing = {}
+        page = 1
+        while True:
+            discussions = request_json('GET', f"{mr_url}/discussions?per_page=100&page={page}", headers)
+            if not discussions:
+                break
+            for discussion in discussions:
+                match = MARKER_RE.search(discussion['notes'][0]['body'])
+                if match:
+                    existing[match.group(1)] = discussion
+            page += 1
+    except urllib.error.HTTPError as e:
+        print(f"❌ GitLab publisher failed: {e.code} {e.reason} from {e.url}")
+        return
+
+    current = set()
+    created = reopened = errors = 0
+    for finding in findings:
+        fp = fingerprint(finding)
+        current.add(fp)
+        if fp in existing:
+            discussion = existing[fp]
+            if discussion['notes'][0].get('resolved'):
+                try:
+                    request_json('PUT', f"{mr_url}/discussions/{discussion['id']}?resolved=false", headers)
+                    reopened += 1
+                except urllib.error.HTTPError as e:
+                    print(f"⚠️ GitLab: could not reopen discussion {discussion['id']}: {e.code} {e.reason}")
+                    errors += 1
+            continue
+        path = repo_path(base_dir, finding)
+        # Findings that were already there, or on lines the MR doesn't touch, are not for this MR to fix
+        if finding.get('Status', 'new') != 'new' or finding['Line'] not in diff_lines.get(path, ()):
+            continue
+        payload = {
+            'body': comment_body(finding),
+            'position': {
+                'position_type': 'text',
+                'base_sha': diff_refs['base_sha'],
+                'start_sha': diff_refs['start_sha'],
+                'head_sha': diff_refs['head_sha'],
+                'new_path': path,
+                'new_line': finding['Line'],
+            },
+        }
+        try:
+            try:
+                request_json('POST', f"{mr_url}/discussions", headers, payload)
+            except urllib.error.HTTPError as e:
+                # 400 is GitLab refusing the position (e.g. the line is not in the diff version it has):
+                # fall back to a plain discussion. Auth, rate-limit and server errors would fail again.
+                if e.code != 400:
+                    raise
+                del payload['position']
+                request_json('POST', f"{mr_url}/discussions", headers, payload)
+            created += 1
+        except urllib.error.HTTPError as e:
+            print(f"⚠️ GitLab: could not open a discussion on {path}:{finding['Line']}: {e.code} {e.reason}")
+            errors += 1
+
+    resolved = 0
+    for fp, discussion in existing.items():
+        if resolve and fp not in current and not discussion['notes'][0].get('resolved'):
+            try:
+                request_json('PUT', f"{mr_url}/discussions/{discussion['id']}?resolved=true", headers)
+                resolved += 1
+            except urllib.error.HTTPError as e:
+                print(f"⚠️ GitLab: could not resolve discussion {discussion['id']}: {e.code} {e.reason}")
+                errors += 1
+
+    print(f"🦊 GitLab: {created} new discussion(s), {reopened} reopened, {resolved} resolved"
+          + (f", {errors} failed request(s)" if errors else ""))
+
+
+BITBUCKET_SEVERITY = {'ERROR': 'HIGH', 'WARNING': 'MEDIUM', 'INFO': 'LOW'}
+
+
+def publish_bitbucket(findings, base_dir, failed=(), resolve=True):
+    """Creates a Code Insights report with inline annotations on the scanned commit."""
+    workspace = os.environ.get('BITBUCKET_WORKSPACE')
+    repo_slug = os.environ.get('BITBUCKET_REPO_SLUG')
+    commit = os.environ.get('BITBUCKET_COMMIT')
+    token = os.environ.get('BITBUCKET_TOKEN')
+    if not all([workspace, repo_slug, commit, token]):
+        print("⚠️ Bitbucket publisher skipped: BITBUCKET_WORKSPACE, BITBUCKET_REPO_SLUG, BITBUCKET_COMMIT and BITBUCKET_TOKEN are required")
+        return
+
+    report_url = (f"https://api.bitbucket.org/2.0/repositories/{workspace}/{repo_slug}"
+                  f"/commit/{commit}/reports/code-review")
+    headers = {'Authorization': f"Bearer {token}"}
+    passed, description = gate_result(failed)
+    counts = {sev: sum(1 for f in findings if f['Severity'] == sev) for sev in BITBUCKET_SEVERITY}
+
+    request_json('PUT', report_url, headers, {
+        'title': 'Code Review',
+        'details': f"{len(findings)} finding(s) from Semgrep and header checks. {description}"[:2000],
+        'report_type': 'BUG',
+        'reporter': 'auto-review',
+        'result': 'PASSED' if passed else 'FAILED',
+        'data': [{'title': f"{sev.title()} findings", 'type': 'NUMBER', 'value': count}
+                 for sev, count in counts.items()],
+    })
+
+    annotations = [{
+        'external_id': f"{fingerprint(f)}-{f['Line']}",
+        'annotation_type': 'VULNERABILITY' if f.get('Category') == 'security' else 'CODE_SMELL',
+        'summary': f"{f['Rule ID']}: {f['Message']}"[:450],
+        'severity': BITBUCKET_SEVERITY.get(f['Severity'], 'LOW'),
+        'path': repo_path(base_dir, f),
+        'line': f['Line'],
+    } for f in findings]
+
+    # The annotations endpoint accepts at most 100 items per request
+    for i in range(0, len(annotations), 100):
+        request_json('POST', f"{report_url}/annotations", headers, annotations[i:i + 100])
+
+    print(f"🪣 Bitbucket: report {'PASSED' if passed else 'FAILED'} with {len(annotations)} annotation(s)")
+
+
+def gerrit_fix_suggestion(finding, path):
+    """Turns a Semgrep autofix into a Gerrit fix suggestion (Gerrit columns are 0-based)."""
+    start, end = finding['Range']['start'], finding['Range']['end']
+    return {
+        'description': f"Apply {finding['Rule ID']} fix",
+        'replacements': [{
+            'path': path,
+            'range': {
+                'start_line': start['line'], 'start_character': start['col'] - 1,
+                'end_line': end['line'], 'end_character': end['col'] - 1,
+            },
+            'replacement': finding['Fix'],
+        }],
+    }
+
+
+def publish_gerrit(findings, base_dir, failed=(), resolve=True):
+    """Posts the new findings on files of the patch set under review as robot comments."""
+    url = os.environ.get('GERRIT_URL', '').rstrip('/')
+    change = os.environ.get('GERRIT_CHANGE_NUMBER')
+    revision = os.environ.get('GERRIT_PATCHSET_REVISION')
+    user = os.environ.get('GERRIT_USER')
+    password = os.environ.get('GERRIT_HTTP_PASSWORD')
+    if not all([url, change, revision, user, password]):
+        print("⚠️ Gerrit publisher skipped: GERRIT_URL, GERRIT_CHANGE_NUMBER, GERRIT_PATCHSET_REVISION, GERRIT_USER and GERRIT_HTTP_PASSWORD are required")
+        return
+
+    revision_url = f"{url}/a/changes/{change}/revisions/{revision}"
+    headers = {'Authorization': 'Basic ' + base64.b64encode(f"{user}:{password}".encode()).decode()}
+    try:
+        # Gerrit rejects the whole review when one comment is on a file the patch set doesn't touch
+        files = set(request_json('GET', f"{revision_url}/files", headers) or ())
+    except urllib.error.HTTPError as e:
+        print(f"❌ Gerrit publisher failed: {e.code} {e.reason} from {e.url}")
+        return
+
+    run_id = os.environ.get('BUILD_URL') or os.environ.get('BUILD_NUMBER') or 'local'
+    robot_comments = {}
+    posted = 0
+    for f in findings:
+        path = repo_path(base_dir, f)
+        if path not in files or f.get('Status', 'new') != 'new':
+            continue
+        comment = {
+            'robot_id': 'auto-review',
+            'robot_run_id': run_id,
+            'line': f['Line'],
+            'message': f"[{f['Severity']}] {f['Message']}",
+            'properties': {'rule': f['Rule ID'], 'category': f.get('Category', '')},
+        }
+        if f.get('Fix') and f.get('Range'):
+            comment['fix_suggestions'] = [gerrit_fix_suggestion(f, path)]
+        robot_comments.setdefault(path, []).append(comment)
+        posted += 1
+
+    try:
+        request_json('POST', f"{revision_url}/review", headers, {
+            'message': f"auto-review found {posted} new issue(s) in this patch set",
+            'robot_comments': robot_comments,
+        })
+    except urllib.error.HTTPError as e:
+        print(f"❌ Gerrit publisher failed: {e.code} {e.reason} from {e.url}")
+        return
+
+    print(f"🤖 Gerrit: {posted} robot comment(s) on change {change}")
+
+
+def publish_azure(findings, base_dir, failed=(), resolve=True):
+    """Creates PR threads at finding locations, marks the threads of fixed findings with resolve and sets the
+    quality-gate PR status."""
+    collection = os.environ.get('SYSTEM_COLLECTIONURI', '').rstrip('/')
+    project = os.environ.get('SYSTEM_TEAMPROJECT')
+    repo_id = os.environ.get('BUILD_REPOSITORY_ID')
+    pr_id = os.environ.get('SYSTEM_PULLREQUEST_PULLREQUESTID')
+    token = os.environ.get('SYSTEM_ACCESSTOKEN')
+    if not all([collection, project, repo_id, pr_id, token]):
+        print("⚠️ Azure DevOps publisher skipped: run in a PR build and map SYSTEM_ACCESSTOKEN into the environment")
+        return
+
+    pr_url = f"{collection}/{project}/_apis/git/repositories/{repo_id}/pullRequests/{pr_id}"
+    headers = {'Authorization': 'Basic ' + base64.b64encode(f":{token}".encode()).decode()}
+
+    existing = {}
+    for thread in request_json('GET', f"{pr_url}/threads?api-version=7.1", headers)['value']:
+        comments = thread.get('comments') or [{}]
+        match = MARKER_RE.search(comments[0].get('content') or '')
+        if match:
+            existing[match.group(1)] = thread
+
+    current = set()
+    created = 0
+    for f in findings:
+        fp = fingerprint(f)
+        current.add(fp)
+        if fp in existing:
+            continue
+        request_json('POST', f"{pr_url}/threads?api-version=7.1", headers, {
+            'comments': [{'parentCommentId': 0, 'content': comment_body(f), 'commentType': 1}],
+            'status': 'active',
+            'threadContext': {
+                'filePath': '/' + repo_path(base_dir, f),
+                'rightFileStart': {'line': f['Line'], 'offset': 1},
+                'rightFileEnd': {'line': f['Line'], 'offset': 1},
+            },
+        })
+        created += 1
+
+    fixed = 0
+    for fp, thread in existing.items():
+        if resolve and fp not in current and thread.get('status') == 'active':
+            request_json('PATCH', f"{pr_url}/threads/{thread['id']}?api-version=7.1", headers, {'status': 'fixed'})
+            fixed += 1
+
+    passed, description = gate_result(failed)
+    request_json('POST', f"{pr_url}/statuses?api-version=7.1-preview.1", headers, {
+        'state': 'succeeded' if passed else 'failed',
+        'description': description,
+        'context': {'name': 'quality-gate', 'genre': 'code-review'},
+    })
+
+    print(f"🔷 Azure DevOps: {created} new thread(s), {fixed} fixed, quality gate {'passed' if passed else 'failed'}")
+
+
+def publish_jira(findings, base_dir, failed=(), resolve=True):
+    """Opens a Jira issue per new ERROR finding and, with resolve, closes issues whose finding is gone."""
+    url = os.environ.get('JIRA_URL', '').rstrip('/')
+    user = os.environ.get('JIRA_USER')
+    token = os.environ.get('JIRA_API_TOKEN')
+    project = os.environ.get('JIRA_PROJECT')
+    if not all([url, user, token, project]):
+        print("⚠️ Jira publisher skipped: JIRA_URL, JIRA_USER, JIRA_API_TOKEN and JIRA_PROJECT are required")
+        return
+
+    headers = {'Authorization': 'Basic ' + base64.b64encode(f"{user}:{token}".encode()).decode()}
+    done_transition = os.environ.get('JIRA_DONE_TRANSITION', 'Done')
+
+    # Open issues we created earlier, keyed by the fingerprint label (cr-<sha1>)
+    jql = f'project = "{project}" AND labels = code-review AND statusCategory != Done'
+    existing = {}
+    start = 0
+    while True:
+        query = urllib.parse.urlencode({'jql': jql, 'fields': 'labels', 'startAt': start, 'maxResults': 100})
+        page = request_json('GET', f"{url}/rest/api/2/search?{query}", headers)
+        for issue in page['issues']:
+            for label in issue['fields']['labels']:
+                if label.startswith('cr-'):
+                    existing[label[3:]] = issue['key']
+        start += len(page['issues'])
+        if not page['issues'] or start >= page['total']:
+            break
+
+    current = set()
+    created = 0
+    for f in findings:
+        if f['Severity'] != 'ERROR':
+            continue
+        fp = fingerprint(f)
+        current.add(fp)
+        if fp in existing:
+            continue
+        path = repo_path(base_dir, f)
+        request_json('POST', f"{url}/rest/api/2/issue", headers, {'fields': {
+            'project': {'key': project},
+            'issuetype': {'name': os.environ.get('JIRA_ISSUE_TYPE', 'Bug')},
+            'summary': f"[{f['Rule ID']}] {f['Message']}"[:250],
+            'description': f"*File:* {path}:{f['Line']}\n*Rule:* {f['Rule ID']}\n*Severity:* {f['Severity']}\n\n{f['Message']}",
+            'labels': ['code-review', f"cr-{fp}"],
+        }})
+        created += 1
+
+    closed = 0
+    for fp, key in existing.items():
+        if fp in current or not resolve:
+            continue
+        transitions = request_json('GET', f"{url}/rest/api/2/issue/{key}/transitions", headers)['transitions']
+        match = next((t for t in transitions if t['name'].lower() == done_transition.lower()), None)
+        if match:
+            request_json('POST', f"{url}/rest/api/2/issue/{key}/transitions", headers, {'transition': {'id': match['id']}})
+            closed += 1
+        else:
+            print(f"⚠️ Jira: {key} has no '{done_transition}' transition, left open")
+
+    print(f"🎫 Jira: {created} issue(s) created, {closed} closed")
+
+
+DOJO_SEVERITY = {'ERROR': 'High', 'WARNING': 'Medium', 'INFO': 'Low'}
+
+
+def multipart(fields, files):
+    """Encodes form fields and {name: (filename, bytes)} files as multipart/form-data."""
+    boundary = uuid.uuid4().hex
+    parts = []
+    for name, value in fields.items():
+        parts.append(f'--{boundary}\r\nContent-Disposition: form-data; name="{name}"\r\n\r\n{value}\r\n'.encode())
+    for name, (filename, content) in files.items():
+        parts.append(f'--{boundary}\r\nContent-Disposition: form-data; name="{name}"; filename="{filename}"\r\n'
+                     f'Content-Type: application/json\r\n\r\n'.encode() + content + b'\r\n')
+    parts.append(f'--{boundary}--\r\n'.encode())
+    return b''.join(parts), f'multipart/form-data; boundary={boundary}'
+
+
+def publish_defectdojo(findings, base_dir, failed=(), resolve=True):
+    """Re-imports the findings into a DefectDojo engagement as a Generic Findings Import, closing the ones
+    missing from it with resolve."""
+    url = os.environ.get('DEFECTDOJO_URL', '').rstrip('/')
+    token = os.environ.get('DEFECTDOJO_TOKEN')
+    product = os.environ.get('DEFECTDOJO_PRODUCT')
+    engagement = os.environ.get('DEFECTDOJO_ENGAGEMENT', 'auto-review')
+    if not all([url, token, product]):
+        print("⚠️ DefectDojo publisher skipped: DEFECTDOJO_URL, DEFECTDOJO_TOKEN and DEFECTDOJO_PRODUCT are required")
+        return
+
+    report = {'findings': [{
+        'title': f"{f['Rule ID']}: {f['Message']}"[:500],
+        'severity': DOJO_SEVERITY.get(f['Severity'], 'Info'),
+        'description': f"{f['Message']}\n\nRule: {f['Rule ID']}\nCategory: {f.get('Category') or 'n/a'}",
+        'file_path': repo_path(base_dir, f),
+        'line': f['Line'],
+        'static_finding': True,
+        'dynamic_finding': False,
+        'vuln_id_from_tool': f['Rule ID'],
+        # Lets DefectDojo deduplicate across re-imports
+        'unique_id_from_tool': fingerprint(f),
+    } for f in findings]}
+
+    body, content_type = multipart({
+        'scan_type': 'Generic Findings Import',
+        'product_name': product,
+        'engagement_name': engagement,
+        'test_title': 'auto-review',
+        'auto_create_context': 'true',
+        'close_old_findings': 'true' if resolve else 'false',
+        'active': 'true',
+        'verified': 'false',
+    }, {'file': ('auto-review.json', json.dumps(report).encode('utf-8'))})
+
+    req = urllib.request.Request(f"{url}/api/v2/reimport-scan/", data=body, method='POST', headers={
+        'Authorization': f"Token {token}",
+        'Content-Type': content_type,
+    })
+    with urllib.request.urlopen(req, timeout=60) as res:
+        result = json.loads(res.read().decode('utf-8'))
+    print(f"🛡️ DefectDojo: {len(findings)} finding(s) imported into test {result.get('test_id', result.get('test'))}")
+
+
+BUILDKITE_SEVERITY_ICON = {'ERROR': '🔴', 'WARNING': '🟠', 'INFO': '🔵'}
+
+
+def buildkite_markdown(findings, base_dir):
+    by_file = {}
+    for f in findings:
+        by_file.setdefault(repo_path(base_dir, f), []).append(f)
+    lines = [f"### Code Review: {len(findings)} finding(s)", ""]
+    for path in sorted(by_file):
+        lines.append(f"<details><summary><code>{path}</code> ({len(by_file[path])})</summary>\n")
+        for f in sorted(by_file[path], key=lambda f: f['Line']):
+            lines.append(f"- {BUILDKITE_SEVERITY_ICON.get(f['Severity'], '')} line {f['Line']} "
+                         f"`{f['Rule ID']}`: {f['Message']}")
+        lines.append("\n</details>")
+    return '\n'.join(lines)
+
+
+def publish_buildkite(findings, base_dir, failed=(), resolve=True):
+    """Creates a build annotation, styled by the highest severity and grouped by file."""
+    if not os.environ.get('BUILDKITE'):
+        print("⚠️ Buildkite publisher skipped: not running inside a Buildkite job")
+        return
+    severities = {f['Severity'] for f in findings}
+    style = next((s for sev, s in [('ERROR', 'error'), ('WARNING', 'warning'), ('INFO', 'info')] if sev in severities),
+                 'success')
+    body = buildkite_markdown(findings, base_dir) if findings else "### Code Review: no findings ✅"
+    # Annotations are limited to 1 MiB
+    body = body[:1000000]
+    subprocess.run(["buildkite-agent", "annotate", "--style", style, "--context", "auto-review"],
+                   input=body, text=True, check=True)
+    print(f"🪁 Buildkite: {style} annotation with {len(findings)} finding(s)")
+
+
+PUBLISHERS = {
+    'gitlab': publish_gitlab,
+    'bitbucket': publish_bitbucket,
+    'gerrit': publish_gerrit,
+    'azure': publish_azure,
+    'jira': publish_jira,
+    'defectdojo': publish_defectdojo,
+    'buildkite': publish_buildkite,
+}
@@ -0,0 +1,68 @@
+import re
+
+from stats import function_spans
+from structlayout import strip_comments
+
+RULE_ID = 'MISSING-PANIC-RECOVERY'
+
+# Where a Go program builds an HTTP server or registers handlers: framework -> pattern
+SERVERS = {
+    'net/http': re.compile(r'\bhttp\.(?:HandleFunc|Handle|ListenAndServe|ListenAndServeTLS|NewServeMux)\(|'
+                           r'\bhttp\.Server\{'),
+    'gin': re.compile(r'\bgin\.New\('),
+    'echo': re.compile(r'\becho\.New\('),
+    'chi': re.compile(r'\bchi\.(?:NewRouter|NewMux)\('),
+    'gorilla/mux': re.compile(r'\bmux\.NewRouter\('),
+    'httprouter': re.compile(r'\bhttprouter\.New\('),
+    'fiber': re.compile(r'\bfiber\.New\('),
+}
+# Recovery middleware of the common frameworks, or one of the project's own named like it: gin.Recovery,
+# gin.Default (which includes it), echo's middleware.Recover, chi's middleware.Recoverer, gorilla's
+# handlers.RecoveryHandler, negroni.NewRecovery, fiber's recover.New, httprouter's PanicHandler
+RECOVERY = re.compile(r'\b\w*Recover\w*\s*\(|\brecover\.New\(|\bgin\.Default\(|\bnegroni\.Classic\(|'
+                      r'\.PanicHandler\s*=')
+# A hand-written middleware: a function calling recover() that deals with handlers
+HANDLER_TYPES = re.compile(r'http\.Handler|ServeHTTP|\*gin\.Context|echo\.(?:Context|HandlerFunc)|\*fiber\.Ctx')
+
+
+def recovers(text, middleware):
+    """Whether a Go file has recovery middleware: a known one, a function named in recovery.middleware, or a
+    function calling recover() around HTTP handlers."""
+    code = strip_comments(text)
+    if RECOVERY.search(code) or any(re.search(rf'\b{re.escape(name)}\b', code) for name in middleware):
+        return True
+    for _, _, lines in function_spans(code, 'go'):
+        body = '\n'.join(lines)
+        if re.search(r'\brecover\(\)', body) and HANDLER_TYPES.search(body):
+            return True
+    return False
+
+
+def server_start(text):
+    """(line, framework) where a Go file first builds a server or registers a handler, None if it doesn't."""
+    code = strip_comments(text)
+    first = None
+    for framework, pattern in SERVERS.items():
+        match = pattern.search(code)
+        if match and (first is None or match.start() < first[0]):
+            first = (match.start(), framework)
+    return (code.count('\n', 0, first[0]) + 1, first[1]) if first else None
+
+
+def recovery_problem(file_path, text, options, package_recovers):
+    """(line, message) when a Go file sets up HTTP handlers and nothing in its package recovers from their
+    panics; package_recovers(folder) tells whether the rest of the package does. None otherwise."""
+    if file_path.name.endswith('_test.go'):
+        return None
+    start = server_start(text)
+    if start is None or recovers(text, options['middleware']) or package_recovers(file_path.parent):
+        return None
+    line, framework = start
+    if framework == 'fiber':
+        consequence = "fiber (fasthttp) doesn't recover panics, so one panicking handler crashes the service"
+    else:
+        consequence = ("net/http only logs a handler's panic and drops the connection: the client gets no "
+                       "response instead of a 500, and the panic never reaches your logs and metrics")
+    return line, (f"HTTP handlers are set up here ({framework}) without panic recovery middleware anywhere in "
+                  f"the package; {consequence}. Add one at the start of the chain (gin.Recovery(), "
+                  f"middleware.Recoverer, recover.New()...) or list yours in recovery.middleware")
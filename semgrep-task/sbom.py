@@ -0,0 +1,100 @@
+import uuid
+import datetime
+
+from gomodules import GoModules
+from licenses import dependencies, detect_license
+
+SBOM_FORMATS = ['cyclonedx', 'spdx']
+
+
+def purl(path, version=''):
+    """Package URL of a Go module (https://github.com/package-url/purl-spec)."""
+    return f"pkg:golang/{path}" + (f"@{version}" if version else '')
+
+
+def module_graph(base_dir, skip=lambda path: False):
+    """[(main module path, [(dependency path, version, licenses)])] for every Go module of the scanned folder."""
+    modules = GoModules.discover(base_dir, skip)
+    graph = []
+    for folder, path in sorted(modules.modules.items(), key=lambda item: item[1]):
+        deps = [(dep, version, detect_license(source) if source else [])
+                for dep, version, source in dependencies(folder)
+                if dep not in modules.modules.values()]  # other modules of the repo are main modules themselves
+        graph.append((path, deps))
+    return graph
+
+
+def cyclonedx(name, graph):
+    """CycloneDX 1.5 JSON BOM: the modules of the repository, and their dependencies as libraries."""
+    components, seen = [], set()
+    for _, deps in graph:
+        for path, version, found in deps:
+            if purl(path, version) in seen:
+                continue
+            seen.add(purl(path, version))
+            component = {'type': 'library', 'bom-ref': purl(path, version), 'name': path, 'version': version,
+                         'purl': purl(path, version)}
+            if found:
+                component['licenses'] = [{'license': {'id': spdx}} for spdx in found]
+            components.append(component)
+    components += [{'type': 'application', 'bom-ref': purl(main), 'name': main, 'purl': purl(main)}
+                   for main, _ in graph[1:]]
+    return {
+        'bomFormat': 'CycloneDX',
+        'specVersion': '1.5',
+        'serialNumber': f"urn:uuid:{uuid.uuid4()}",
+        'version': 1,
+        'metadata': {
+            'timestamp': datetime.datetime.now(datetime.timezone.utc).isoformat(timespec='seconds'),
+            'tools': {'components': [{'type': 'application', 'name': 'auto-review'}]},
+            'component': ({'type': 'application', 'bom-ref': purl(graph[0][0]), 'name': graph[0][0],
+                           'purl': purl(graph[0][0])} if graph else {'type': 'application', 'name': name}),
+        },
+        'components': components,
+        'dependencies': [{'ref': purl(main), 'dependsOn': [purl(p, v) for p, v, _ in deps]} for main, deps in graph],
+    }
+
+
+def spdx(name, graph):
+    """SPDX 2.3 JSON document: one package per module, DEPENDS_ON relationships from the repository's modules."""
+    packages, ids, relationships = [], {}, []
+
+    def package(path, version, found):
+        key = (path, version)
+        if key not in ids:
+            ids[key] = f"SPDXRef-Package-{len(ids) + 1}"
+            packages.append({
+                'name': path,
+                'SPDXID': ids[key],
+                'versionInfo': version or 'NOASSERTION',
+                'downloadLocation': 'NOASSERTION',
+                'filesAnalyzed': False,
+                'licenseConcluded': 'NOASSERTION',
+                'licenseDeclared': ' OR '.join(found) if found else 'NOASSERTION',
+                'externalRefs': [{'referenceCategory': 'PACKAGE-MANAGER', 'referenceType': 'purl',
+                                  'referenceLocator': purl(path, version)}],
+            })
+        return ids[key]
+
+    mains = [package(main, '', []) for main, _ in graph]
+    for main_id, (_, deps) in zip(mains, graph):
+        relationships += [{'spdxElementId': main_id, 'relationshipType': 'DEPENDS_ON',
+                           'relatedSpdxElement': package(path, version, found)} for path, version, found in deps]
+    return {
+        'spdxVersion': 'SPDX-2.3',
+        'dataLicense': 'CC0-1.0',
+        'SPDXID': 'SPDXRef-DOCUMENT',
+        'name': name,
+        'documentNamespace': f"https://spdx.org/spdxdocs/auto-review-{name}-{uuid.uuid4()}",
+        'creationInfo': {'created': datetime.datetime.now(datetime.timezone.utc).strftime('%Y-%m-%dT%H:%M:%SZ'),
+                         'creators': ['Tool: auto-review']},
+        'documentDescribes': mains,
+        'packages': packages,
+        'relationships': relationships,
+    }
+
+
+def build_sbom(base_dir, fmt, skip=lambda path: False):
+    graph = module_graph(base_dir, skip)
+    name = graph[0][0] if graph else base_dir.name
+    return (cyclonedx if fmt == 'cyclonedx' else spdx)(name, graph), graph
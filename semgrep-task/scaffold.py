@@ -0,0 +1,151 @@
+import os
+import json
+from pathlib import Path
+
+from config import CONFIG_NAMES
+
+LANGUAGES = {'.go': 'go', '.py': 'python', '.js': 'javascript', '.java': 'java'}
+
+# folder name -> why it is excluded by default when present
+EXCLUDE_DIRS = {
+    'vendor': 'vendored Go modules',
+    'node_modules': 'npm dependencies',
+    'third_party': 'third-party code',
+    'build': 'build output',
+    'dist': 'build output',
+    'target': 'Maven/Gradle output',
+    'testdata': 'Go test fixtures',
+    '.venv': 'Python virtualenv',
+    'venv': 'Python virtualenv',
+}
+
+GENERATED_GLOBS = {'go': ['*.pb.go', '*_gen.go', '*_string.go'], 'javascript': ['*.min.js'], 'python': ['*_pb2.py']}
+
+# manifest -> {dependency marker: framework}
+FRAMEWORKS = {
+    'go.mod': {'github.com/gin-gonic/gin': 'gin', 'github.com/labstack/echo': 'echo',
+               'google.golang.org/grpc': 'grpc', 'gorm.io/gorm': 'gorm', 'github.com/gorilla/mux': 'gorilla/mux'},
+    'requirements.txt': {'django': 'django', 'flask': 'flask', 'fastapi': 'fastapi'},
+    'pom.xml': {'spring-boot': 'spring-boot'},
+    'build.gradle': {'spring-boot': 'spring-boot'},
+}
+
+# profile -> (gate condition, description); relaxed also turns the INFO rules off
+PROFILES = {
+    'strict': ('total WARNING+ > 0', 'fail on any warning or error'),
+    'recommended': ('new ERROR > 0', 'fail on new errors only'),
+    'relaxed': ('total ERROR+ > 10', 'tolerate existing errors, skip INFO rules'),
+}
+
+SKIP_WALK = {'.git', '.hg', '.svn'}
+
+
+def inspect_repo(root):
+    """Languages (by file count), Go modules, frameworks and excludable folders found under root."""
+    root = Path(root).resolve()
+    counts, modules, excludes, frameworks = {}, [], set(), set()
+    for folder, dirs, files in os.walk(root):
+        rel = Path(folder).relative_to(root)
+        for d in list(dirs):
+            if d in SKIP_WALK:
+                dirs.remove(d)
+            elif d in EXCLUDE_DIRS:
+                excludes.add((rel / d).as_posix())
+                dirs.remove(d)
+        for name in files:
+            language = LANGUAGES.get(Path(name).suffix)
+            if language:
+                counts[language] = counts.get(language, 0) + 1
+            if name in FRAMEWORKS:
+                text = (Path(folder) / name).read_text(encoding='utf-8', errors='ignore').lower()
+                frameworks.update(fw for marker, fw in FRAMEWORKS[name].items() if marker in text)
+            if name == 'go.mod':
+                modules.append(rel.as_posix())
+            if name == 'package.json':
+                try:
+                    deps = json.loads((Path(folder) / name).read_text(encoding='utf-8'))
+                except ValueError:
+                    deps = {}
+                names = {**deps.get('dependencies', {}), **deps.get('devDependencies', {})}
+                frameworks.update(fw for fw in ('react', 'express', 'vue', 'next') if fw in names)
+    return {
+        'languages': sorted(counts, key=counts.get, reverse=True),
+        'counts': counts,
+        'modules': sorted(modules),
+        'frameworks': sorted(frameworks),
+        'excludes': sorted(excludes),
+    }
+
+
+def render_config(info, profile, info_rules=()):
+    """Starter .codereview.yaml text, keeping comments so it reads as documentation."""
+    condition, description = PROFILES[profile]
+    languages = ', '.join(f"{lang} ({info['counts'][lang]} files)" for lang in info['languages']) or 'none found'
+    lines = [
+        "# Generated by `auto-review.py init`",
+        f"# Languages: {languages}",
+    ]
+    if info['modules']:
+        lines.append(f"# Go modules: {', '.join(m if m != '.' else '(root)' for m in info['modules'])}")
+    if info['frameworks']:
+        lines.append(f"# Frameworks: {', '.join(info['frameworks'])}")
+    lines += ["", "rules:"]
+    disabled = sorted(info_rules) if profile == 'relaxed' else []
+    if disabled:
+        lines.append("  disable:")
+        lines += [f"    - {rule_id}" for rule_id in disabled]
+    else:
+        lines.append("  disable: []")
+    lines += ["", "exclude:"]
+    patterns = [(d, EXCLUDE_DIRS[Path(d).name]) for d in info['excludes']]
+    for lang in info['languages']:
+        patterns += [(glob, 'generated code') for glob in GENERATED_GLOBS.get(lang, [])]
+    if patterns:
+        lines += [f'  - "{pattern}"   # {why}' for pattern, why in patterns]
+    else:
+        lines[-1] = "exclude: []"
+    lines += [
+        "",
+        "output:",
+        "  excel: true",
+        "",
+        f"gate:   # {profile} profile: {description}",
+        "  conditions:",
+        f"    - {condition}",
+        "",
+    ]
+    return '\n'.join(lines)
+
+
+def ask(question, default, choices=None):
+    suffix = f" [{'/'.join(choices)}]" if choices else ""
+    while True:
+        try:
+            answer = input(f"{question}{suffix} ({default}): ").strip() or default
+        except EOFError:
+            return default
+        if not choices or answer in choices:
+            return answer
+        print(f"Please answer one of: {', '.join(choices)}")
+
+
+def init_config(root, profile=None, assume_yes=False, force=False, info_rules=()):
+    """Inspects root and writes a starter .codereview.yaml there; returns the path or None when skipped."""
+    path = Path(root) / CONFIG_NAMES[0]
+    existing = next((Path(root) / n for n in CONFIG_NAMES if (Path(root) / n).exists()), None)
+    if existing and not force:
+        print(f"⚠️ {existing} already exists, use --force to replace it")
+        return None
+
+    info = inspect_repo(root)
+    print(f"🔍 Languages: {', '.join(info['languages']) or 'none'}"
+          + (f" · Go modules: {len(info['modules'])}" if info['modules'] else '')
+          + (f" · Frameworks: {', '.join(info['frameworks'])}" if info['frameworks'] else ''))
+    if info['excludes']:
+        print(f"🙈 Will exclude: {', '.join(info['excludes'])}")
+    if not profile:
+        profile = 'recommended' if assume_yes else ask("Rule profile", 'recommended', list(PROFILES))
+
+    path.write_text(render_config(info, profile, info_rules), encoding='utf-8')
+    print(f"✨ Wrote {path} ({profile} profile)")
+    return path
@@ -0,0 +1,188 @@
+import os
+import math
+import re
+import subprocess
+import urllib.error
+
+from publishers import request_json
+
+# (rule id, what it is, regex) for token formats with a recognizable prefix
+PROVIDER_TOKENS = [
+    ('SECRET-AWS-ACCESS-KEY', "AWS access key id", re.compile(r'\b(?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\b')),
+    ('SECRET-GITHUB-TOKEN', "GitHub token",
+     re.compile(r'\b(?:gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})\b')),
+    ('SECRET-SLACK-TOKEN', "Slack token", re.compile(r'\bxox[abposr]-[A-Za-z0-9-]{10,250}')),
+    ('SECRET-SLACK-WEBHOOK', "Slack webhook URL",
+     re.compile(r'https://hooks\.slack\.com/services/T[A-Za-z0-9_]+/B[A-Za-z0-9_]+/[A-Za-z0-9_]+')),
+]
+ENTROPY_RULE = 'SECRET-HIGH-ENTROPY'
+RULE_IDS = [rule_id for rule_id, _, _ in PROVIDER_TOKENS] + [ENTROPY_RULE]
+# Highest severity each rule reports: provider tokens drop when their verification fails, the entropy rule
+# unless a secret-like name is on the same line
+SEVERITIES = {**{rule_id: 'ERROR' for rule_id, _, _ in PROVIDER_TOKENS}, ENTROPY_RULE: 'WARNING'}
+
+STRING_LITERAL = re.compile(r'"((?:[^"\\\n]|\\.)*)"|\'((?:[^\'\\\n]|\\.)*)\'|`([^`]*)`')
+CANDIDATE = re.compile(r'[A-Za-z0-9+/=_\-.]+')
+HEX = re.compile(r'[0-9a-fA-F]+')
+KEYWORDS = re.compile(r'(?i)secret|token|passw(?:or)?d|\bpwd\b|api[_-]?key|access[_-]?key|private[_-]?key|'
+                      r'credential|\bauth|bearer|\baws\b|signing[_-]?key')
+
+
+def shannon_entropy(value):
+    """Bits per character of the value's own character distribution."""
+    counts = {}
+    for char in value:
+        counts[char] = counts.get(char, 0) + 1
+    return -sum(n / len(value) * math.log2(n / len(value)) for n in counts.values())
+
+
+def keyword_distance(lines, index, options):
+    """0 when a secret-like name is on the line, n when it is n lines above (up to secrets.proximity), else None."""
+    for distance in range(options['proximity'] + 1):
+        if index - distance >= 0 and KEYWORDS.search(lines[index - distance]):
+            return distance
+    return None
+
+
+def secret_score(value, distance, options):
+    """0..1: how far the entropy is above the threshold (hex strings have a lower one), plus keyword proximity."""
+    threshold = options['entropy_hex'] if HEX.fullmatch(value) else options['entropy']
+    excess = shannon_entropy(value) - threshold
+    if excess < 0:
+        return 0.0
+    nearness = 0.6 if distance == 0 else 0.3 if distance is not None else 0.0
+    return round(min(1.0, excess / 1.5 + nearness), 2)
+
+
+def masked(value):
+    return value[:4] + '…' if len(value) > 8 else '…'
+
+
+def verify_github(token):
+    try:
+        request_json('GET', 'https://api.github.com/user', {'Authorization': f"token {token}",
+                                                            'User-Agent': 'auto-review'})
+        return True
+    except urllib.error.HTTPError as e:
+        return False if e.code == 401 else None
+
+
+def verify_slack(token):
+    res = request_json('POST', 'https://slack.com/api/auth.test', {'Authorization': f"Bearer {token}"}, {})
+    return bool(res and res.get('ok'))
+
+
+# rule id -> callable(token) -> True (live), False (rejected) or None (can't tell)
+VERIFIERS = {
+    'SECRET-GITHUB-TOKEN': verify_github,
+    'SECRET-SLACK-TOKEN': verify_slack,
+}
+
+
+def verify(rule_id, token, options):
+    """Asks secrets.verify_command (token on stdin, rule id in $SECRET_RULE_ID; exit 0 live, 1 rejected) or
+    the built-in verifier whether a credential is live."""
+    try:
+        if options['verify_command']:
+            res = subprocess.run(options['verify_command'], shell=True, input=token, capture_output=True, text=True,
+                                 timeout=30, env={**os.environ, 'SECRET_RULE_ID': rule_id})
+            return {0: True, 1: False}.get(res.returncode)
+        verifier = VERIFIERS.get(rule_id)
+        return verifier(token) if verifier else None
+    except (OSError, ValueError, subprocess.TimeoutExpired):
+        return None
+
+
+def scan_secrets(text, options):
+    """(line, rule id, severity, message) of the credentials found in a file's text.
+
+    Known token formats are reported as ERROR. With secrets.verify on they are checked against their
+    provider first: live ones stay ERROR, rejected ones drop to INFO and unverifiable ones to WARNING.
+    Other string literals are scored on entropy and the distance to a secret-like name.
+    """
+    found = []
+    lines = text.splitlines()
+    for index, line in enumerate(lines):
+        matched = []
+        for rule_id, name, pattern in PROVIDER_TOKENS:
+            for match in pattern.finditer(line):
+                matched.append(match.span())
+                severity, note = 'ERROR', ''
+                if options['verify']:
+                    live = verify(rule_id, match.group(0), options)
+                    severity, note = {True: ('ERROR', ', verified live'), False: ('INFO', ', rejected by the provider'),
+                                      None: ('WARNING', ', not verified')}[live]
+                found.append((index + 1, rule_id, severity, f"Hardcoded {name} ({masked(match.group(0))}{note}). "
+                                                          "Revoke it and load it from the environment or a secret store"))
+        for literal in STRING_LITERAL.finditer(line):
+            value = next(g for g in literal.groups() if g is not None)
+            if len(value) < options['min_length'] or not CANDIDATE.fullmatch(value):
+                continue
+            if any(start <= literal.start() < end or literal.start() <= start < literal.end() for start, end in matched):
+                continue
+            distance = keyword_distance(lines, index, options)
+            score = secret_score(value, distance, options)
+            if score and score >= options['min_score']:
+                found.append((index + 1, ENTROPY_RULE, 'WARNING' if distance == 0 else 'INFO',
+                              f"Possible hardcoded secret ({masked(value)}, entropy {shannon_entropy(value):.1f} bits/char, "
+                              f"score {score}). Load it from the environment or a secret store"))
+    return found
+
+
+HUNK = re.compile(r'^@@ -\d+(?:,\d+)? \+(\d+)(?:,\d+)? @@')
+
+
+def added_lines(repo_dir, depth):
+    """(sha, author, date, path, [(line, text)]) of what each of the last depth commits added under repo_dir,
+    oldest first, with paths relative to repo_dir; None outside a repository."""
+    res = subprocess.run(['git', '-c', 'core.quotePath=false', 'log', '-n', str(depth), '-p', '-U0', '--no-color',
+                          '--no-ext-diff', '--relative', '--format=%x00%H%x00%an <%ae>%x00%as', '--', '.'],
+                         cwd=repo_dir, capture_output=True, text=True, encoding='utf-8', errors='replace')
+    if res.returncode != 0:
+        return None
+    changes, commit, current, number, header = [], None, None, 0, False
+    for row in res.stdout.splitlines():
+        if row.startswith('\0'):
+            commit = row[1:].split('\0')
+        elif row.startswith('diff --git '):
+            header = True  # until the first hunk; an added "++ x" line also reads "+++ x"
+        elif header and row.startswith('+++ '):
+            path = row[4:].strip('"')
+            current = None if path == '/dev/null' else (*commit, path[2:], [])
+            if current:
+                changes.append(current)
+        elif row.startswith('@@'):
+            header = False
+            match = HUNK.match(row)
+            number = int(match.group(1)) if match else 0
+        elif row.startswith('+') and current:
+            current[4].append((number, row[1:]))
+            number += 1
+    return [change for change in reversed(changes) if change[4]]
+
+
+def history_secrets(repo_dir, depth, options_of, still_present):
+    """(sha, author, date, path, line, rule id, severity, message) of secrets added by the last depth commits
+    that are no longer in the working tree, each reported at the commit that introduced it.
+
+    options_of(path) gives the secrets options of a path, None to skip it; still_present(path, text) tells
+    whether the added line is still there (the working-tree scan reports those).
+    """
+    changes = added_lines(repo_dir, depth)
+    if changes is None:
+        return None
+    found, seen = [], set()
+    for sha, author, date, path, lines in changes:
+        options = options_of(path)
+        if not options:
+            continue
+        # Hunks are scanned as one text, so a secret-like name on the line above still counts
+        for index, rule_id, severity, message in scan_secrets('\n'.join(text for _, text in lines), options):
+            number, text = lines[index - 1]
+            if (rule_id, path, text.strip()) in seen or still_present(path, text):
+                continue
+            seen.add((rule_id, path, text.strip()))
+            found.append((sha, author, date, path, number, rule_id, severity,
+                          f"{message.split('. ')[0]} added in {sha[:12]} and removed since, but still in the git "
+                          f"history. Rotate it"))
+    return found
@@ -0,0 +1,239 @@
+import os
+import collections
+import hmac
+import json
+import time
+import queue
+import uuid
+import threading
+from pathlib import Path
+from urllib.parse import urlparse, parse_qs
+from http.server import BaseHTTPRequestHandler, ThreadingHTTPServer
+
+from formats import to_sarif
+from metrics import METRICS
+from github_webhook import verify_signature, scan_target, review_event
+from config import load_config, find_config
+from history import history_file
+from triage import load_decisions, mark
+
+
+class ScanQueue:
+    """Runs submitted scans one at a time on a background worker. The last max_finished done or failed jobs
+    are kept for their results, older ones are forgotten."""
+
+    def __init__(self, scan, max_finished=1000):
+        self.scan = scan  # callable(path) -> findings
+        self.jobs = {}  # id -> job, in submission order
+        self.finished = collections.deque()  # ids of done and failed jobs, oldest first
+        self.max_finished = max_finished
+        self.lock = threading.Lock()
+        self.pending = queue.Queue()
+        METRICS.queue_depth = self.pending.qsize
+        threading.Thread(target=self.work, daemon=True).start()
+
+    def submit(self, path, run=None):
+        """Queues a scan of path, or a custom run() callable such as a webhook review."""
+        job = {'id': uuid.uuid4().hex, 'path': path, 'status': 'queued', 'findings': None, 'error': None,
+               'run': run or (lambda: self.scan(path))}
+        with self.lock:
+            self.jobs[job['id']] = job
+        self.pending.put(job['id'])
+        return job
+
+    def get(self, job_id):
+        with self.lock:
+            return self.jobs.get(job_id)
+
+    def work(self):
+        while True:
+            job = self.get(self.pending.get())
+            job['status'] = 'running'
+            started = time.monotonic()
+            try:
+                job['findings'] = job['run']()
+                job['status'] = 'done'
+            except Exception as e:
+                job['error'] = str(e)
+                job['status'] = 'failed'
+            METRICS.observe_scan(time.monotonic() - started, job['findings'], job['status'])
+            job['run'] = None  # the webhook's closure holds its payload
+            with self.lock:
+                self.finished.append(job['id'])
+                while len(self.finished) > self.max_finished:
+                    self.jobs.pop(self.finished.popleft(), None)
+
+
+def triage_store(path):
+    """Config and history store of the folder a triage request is about."""
+    base_dir = Path(path).resolve()
+    config = load_config(find_config(base_dir))
+    return config, base_dir, history_file(config, base_dir)
+
+
+def job_status(job):
+    status = {k: job[k] for k in ('id', 'path', 'status', 'error')}
+    if job['findings'] is not None:
+        status['findings'] = len(job['findings'])
+    return status
+
+
+def allowed_path(path, roots):
+    """path resolved, when it is one of roots or inside one of them; None otherwise. Symlinks and .. are
+    resolved first so they can't lead out."""
+    if not isinstance(path, str) or not path:
+        return None
+    resolved = Path(path).resolve()
+    return resolved if any(resolved == root or root in resolved.parents for root in roots) else None
+
+
+def authorized(header):
+    """Whether the Authorization header carries the AUTO_REVIEW_API_TOKEN bearer token."""
+    token = os.environ.get('AUTO_REVIEW_API_TOKEN')
+    scheme, _, value = (header or '').partition(' ')
+    return bool(token) and scheme.lower() == 'bearer' and hmac.compare_digest(value.strip().encode(), token.encode())
+
+
+class ApiHandler(BaseHTTPRequestHandler):
+    """POST /scans, GET /scans/<id>, GET /scans/<id>/findings[?format=sarif], GET|POST /triage,
+    POST /webhooks/github, GET /metrics
+
+    Every endpoint but /metrics and the signed webhook needs the AUTO_REVIEW_API_TOKEN bearer token, and the
+    paths of /scans and /triage must be inside the allowed roots."""
+
+    scans = None  # ScanQueue, set by serve_http
+    review_changes = None  # callable(repo_dir, files) -> (findings, failed gate conditions), set by serve_http
+    roots = ()  # resolved folders scans and triage may read, set by serve_http
+
+    def send_json(self, code, body):
+        data = json.dumps(body, default=str).encode('utf-8')
+        self.send_response(code)
+        self.send_header('Content-Type', 'application/json')
+        self.send_header('Content-Length', str(len(data)))
+        self.end_headers()
+        self.wfile.write(data)
+
+    def check_token(self):
+        """Sends the error and returns False unless the request carries the API token."""
+        if not os.environ.get('AUTO_REVIEW_API_TOKEN'):
+            self.send_json(503, {'error': 'the API is disabled until AUTO_REVIEW_API_TOKEN is set'})
+            return False
+        if not authorized(self.headers.get('Authorization')):
+            self.send_json(401, {'error': 'expected an "Authorization: Bearer <AUTO_REVIEW_API_TOKEN>" header'})
+            return False
+        return True
+
+    def check_path(self, path):
+        """path resolved inside the allowed roots, or None after sending the error."""
+        resolved = allowed_path(path, self.roots)
+        if resolved is None:
+            self.send_json(403, {'error': f"{path} is outside the allowed roots"})
+        return resolved
+
+    def github_webhook(self):
+        body = self.rfile.read(int(self.headers.get('Content-Length', 0)))
+        if not os.environ.get('GITHUB_WEBHOOK_SECRET'):
+            return self.send_json(503, {'error': 'webhooks are disabled until GITHUB_WEBHOOK_SECRET is set'})
+        if not verify_signature(body, self.headers.get('X-Hub-Signature-256')):
+            return self.send_json(401, {'error': 'invalid signature'})
+        event = self.headers.get('X-GitHub-Event')
+        target = scan_target(event, json.loads(body or b'{}'))
+        if not target:
+            return self.send_json(200, {'status': 'ignored', 'event': event})
+        full_name, base, head = target
+        review = ApiHandler.review_changes
+        job = self.scans.submit(f"{full_name}@{head}", run=lambda: review_event(full_name, base, head, review))
+        self.send_json(202, job_status(job))
+
+    def triage(self):
+        try:
+            body = json.loads(self.rfile.read(int(self.headers.get('Content-Length', 0))) or b'{}')
+            path, key, status = body['path'], body['fingerprint'], body['status']
+        except (ValueError, KeyError):
+            return self.send_json(400, {'error': 'expected a JSON body like {"path": "/repo", "fingerprint": "3fa2c1", '
+                                                 '"status": "accepted-risk", "reason": "..."}'})
+        path = self.check_path(path)
+        if path is None:
+            return
+        _, _, store = triage_store(path)
+        if not store.is_file():
+            return self.send_json(404, {'error': f"no history at {store}, record the findings with scan --history"})
+        try:
+            key, entry = mark(store, key, status, body.get('reason'), body.get('author'))
+        except KeyError as e:
+            return self.send_json(404, {'error': e.args[0]})
+        except ValueError as e:
+            return self.send_json(400, {'error': e.args[0]})
+        self.send_json(200, {'fingerprint': key, 'decision': entry})
+
+    def do_POST(self):
+        if urlparse(self.path).path == '/webhooks/github':
+            return self.github_webhook()
+        if not self.check_token():
+            return
+        if urlparse(self.path).path == '/triage':
+            return self.triage()
+        if urlparse(self.path).path != '/scans':
+            return self.send_json(404, {'error': 'not found'})
+        try:
+            length = int(self.headers.get('Content-Length', 0))
+            path = json.loads(self.rfile.read(length) or b'{}')['path']
+        except (ValueError, KeyError):
+            return self.send_json(400, {'error': 'expected a JSON body like {"path": "/repo/src"}'})
+        path = self.check_path(path)
+        if path is None:
+            return
+        self.send_json(202, job_status(self.scans.submit(str(path))))
+
+    def do_GET(self):
+        url = urlparse(self.path)
+        if url.path == '/metrics':
+            data = METRICS.render().encode('utf-8')
+            self.send_response(200)
+            self.send_header('Content-Type', 'text/plain; version=0.0.4')
+            self.send_header('Content-Length', str(len(data)))
+            self.end_headers()
+            return self.wfile.write(data)
+        if not self.check_token():
+            return
+        if url.path == '/triage':
+            path = parse_qs(url.query).get('path')
+            if not path:
+                return self.send_json(400, {'error': 'expected ?path=/repo'})
+            path = self.check_path(path[0])
+            if path is None:
+                return
+            return self.send_json(200, load_decisions(*triage_store(path)))
+        parts = url.path.strip('/').split('/')
+        if len(parts) not in (2, 3) or parts[0] != 'scans' or (len(parts) == 3 and parts[2] != 'findings'):
+            return self.send_json(404, {'error': 'not found'})
+        job = self.scans.get(parts[1])
+        if not job:
+            return self.send_json(404, {'error': 'unknown scan id'})
+        if len(parts) == 2:
+            return self.send_json(200, job_status(job))
+        if job['status'] != 'done':
+            return self.send_json(409, {'error': f"scan is {job['status']}"})
+        if parse_qs(url.query).get('format') == ['sarif']:
+            # Paths from the repository root; webhook jobs are named after the repository, not a folder
+            scanned = Path(job['path'])
+            base_dir = scanned if scanned.is_dir() else scanned.parent if scanned.is_file() else None
+            return self.send_json(200, to_sarif(job['findings'], base_dir))
+        self.send_json(200, job['findings'])
+
+
+def serve_http(address, scan, review_changes, roots):
+    """Serves the REST API on "host:port" (":8080" listens on 127.0.0.1, "0.0.0.0:8080" on all interfaces),
+    scanning and triaging only paths inside the roots folders."""
+    host, _, port = address.rpartition(':')
+    host = host or '127.0.0.1'
+    ApiHandler.scans = ScanQueue(scan)
+    ApiHandler.review_changes = staticmethod(review_changes)
+    ApiHandler.roots = tuple(Path(root).resolve() for root in roots)
+    server = ThreadingHTTPServer((host, int(port)), ApiHandler)
+    print(f"🌐 REST API listening on {host}:{port}, serving {', '.join(map(str, ApiHandler.roots))}")
+    if not os.environ.get('AUTO_REVIEW_API_TOKEN'):
+        print("⚠️ AUTO_REVIEW_API_TOKEN is not set: every endpoint but /metrics and /webhooks/github answers 503")
+    if not os.environ.get('GITHUB_WEBHOOK_SECRET'):
+        print("⚠️ GITHUB_WEBHOOK_SECRET is not set: /webhooks/github rejects every delivery")
+    server.serve_forever()
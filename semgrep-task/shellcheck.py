@@ -0,0 +1,87 @@
+import re
+import json
+import shlex
+import shutil
+import subprocess
+
+SHELL_SUFFIXES = {'.sh', '.bash', '.ksh'}
+# shellcheck level -> severity, and the category its comments are filed under
+LEVELS = {
+    'error': ('ERROR', 'reliability'),
+    'warning': ('WARNING', 'reliability'),
+    'info': ('INFO', 'best-practice'),
+    'style': ('INFO', 'best-practice'),
+}
+# Ids of shellcheck findings (SC2086); there are too many to list them in BUILTIN_CHECKS
+RULE_ID = re.compile(r'^SC\d{4}$')
+WIKI_URL = "https://www.shellcheck.net/wiki/SC{}"
+
+
+def run_shellcheck(file_path, options):
+    """shellcheck's comments for one script; None when it is not installed or its output cannot be read."""
+    command = shlex.split(options['command'])
+    if not shutil.which(command[0]):
+        return None
+    res = subprocess.run(command + ['--format', 'json1', '--severity', options['severity'], str(file_path)],
+                         capture_output=True, text=True, encoding='utf-8')
+    if res.returncode not in (0, 1):
+        reason = (res.stderr.strip().splitlines() or [f"exit code {res.returncode}"])[-1]
+        print(f"⚠️ shellcheck failed on {file_path.name}: {reason}")
+        return []
+    try:
+        return json.loads(res.stdout or '{}').get('comments', [])
+    except ValueError:
+        return None
+
+
+def offset(lines, line, column):
+    """Byte offset of a 1-based line and column, None where shellcheck's columns can't be trusted (tabs)."""
+    if line > len(lines) or b'\t' in lines[line - 1]:
+        return None  # shellcheck counts a tab as 8 columns
+    text = lines[line - 1].decode('utf-8', errors='replace')
+    return sum(len(l) for l in lines[:line - 1]) + len(text[:column - 1].encode('utf-8'))
+
+
+def comment_fix(source, comment):
+    """shellcheck's replacements folded into one (Fix, Range) edit, like a Semgrep fix; ('', None) without one."""
+    replacements = (comment.get('fix') or {}).get('replacements') or []
+    lines = source.splitlines(keepends=True)
+    edits = []
+    for r in replacements:
+        start, end = offset(lines, r['line'], r['column']), offset(lines, r['endLine'], r['endColumn'])
+        if start is None or end is None:
+            return '', None
+        edits.append((start, end, -r.get('precedence', 0), r['replacement']))
+    if not edits:
+        return '', None
+    edits.sort()
+    if any(a[1] > b[0] for a, b in zip(edits, edits[1:])):
+        return '', None
+    first, last = edits[0][0], max(e[1] for e in edits)
+    fixed, position = b'', first
+    for start, end, _, replacement in edits:
+        fixed += source[position:start] + replacement.encode('utf-8')
+        position = end
+    fixed += source[position:last]
+
+    def point(at):
+        line_start = source.rfind(b'\n', 0, at) + 1
+        return {'line': source[:at].count(b'\n') + 1,
+                'col': len(source[line_start:at].decode('utf-8', errors='replace')) + 1, 'offset': at}
+    return fixed.decode('utf-8', errors='replace'), {'start': point(first), 'end': point(last)}
+
+
+def shell_findings(file_path, options):
+    """(line, rule id, severity, category, message, fix, range) of shellcheck's comments on a script,
+    None when shellcheck is not available."""
+    comments = run_shellcheck(file_path, options)
+    if comments is None:
+        return None
+    source = file_path.read_bytes()
+    findings = []
+    for comment in comments:
+        severity, category = LEVELS.get(comment.get('level'), ('INFO', 'best-practice'))
+        fix, span = comment_fix(source, comment)
+        findings.append((comment['line'], f"SC{comment['code']}", severity, category,
+                         f"{comment['message']} ({WIKI_URL.format(comment['code'])})", fix, span))
+    return findings
@@ -0,0 +1,507 @@
+import re
+import math
+import hashlib
+from pathlib import Path
+
+from config import rule_matches
+from coupling import import_graph, coupling_metrics, import_cycles
+from coverage import covered_lines, risky_functions, RULE_ID as COVERAGE_RULE
+
+LANGUAGES = {'.go': 'go', '.py': 'python', '.js': 'javascript', '.java': 'java'}
+
+# Line starting a function body, per language; a function runs until the next one starts
+FUNCTION_START = {
+    'go': re.compile(r'^func\b'),
+    'python': re.compile(r'^\s*(?:async\s+)?def\s'),
+    'javascript': re.compile(r'\bfunction\b|=>\s*\{'),
+    'java': re.compile(r'^\s*(?:(?:public|private|protected|static|final|synchronized|abstract)\s+)+'
+                       r'[\w<>\[\], ]+\s+\w+\s*\([^;]*$'),
+}
+
+# Function name in its first line (Go methods as Type.Method)
+FUNCTION_NAME = {
+    'go': re.compile(r'^func\s+(?:\(\s*(?:\w+\s+)?\*?(\w+)[^)]*\)\s*)?(\w+)'),
+    'python': re.compile(r'def\s+(\w+)'),
+    'javascript': re.compile(r'function\s*\*?\s*(\w+)|(\w+)\s*[:=]\s*(?:async\s+)?(?:function\b|\()'),
+    'java': re.compile(r'(\w+)\s*\('),
+}
+
+# Branch points counted for (approximate) cyclomatic complexity
+DECISION = re.compile(r'\b(?:if|for|while|case|catch|except|elif)\b|&&|\|\|')
+PYTHON_DECISION = re.compile(r'\b(?:and|or)\b')
+
+COMPLEXITY_BUCKETS = [(5, '1-5'), (10, '6-10'), (20, '11-20'), (float('inf'), '21+')]
+
+# Halstead tokens: keywords and symbols are operators, identifiers and literals operands. Closing brackets
+# are not counted, a pair is one operator
+COMMENTS = {'python': r'#[^\n]*', None: r'//[^\n]*|/\*.*?\*/'}
+TOKEN = (r'""".*?"""|\'\'\'.*?\'\'\'|"(?:\\.|[^"\\\n])*"|\'(?:\\.|[^\'\\\n])*\'|`[^`]*`|\d[\w.]*|[A-Za-z_$][\w$]*'
+         r'|<<=|>>=|&\^=|\.\.\.|:=|[-+*/%&|^<>=!]=|&&|\|\||<-|<<|>>|\+\+|--|->|=>|::|[^\s\w)\]}]')
+KEYWORDS = {'if', 'else', 'elif', 'for', 'while', 'do', 'switch', 'case', 'default', 'break', 'continue', 'return',
+            'go', 'defer', 'select', 'func', 'def', 'lambda', 'function', 'var', 'const', 'let', 'new', 'delete',
+            'typeof', 'instanceof', 'in', 'not', 'and', 'or', 'is', 'try', 'catch', 'except', 'finally', 'throw',
+            'throws', 'raise', 'with', 'yield', 'await', 'async', 'range', 'goto', 'fallthrough', 'assert', 'pass',
+            'del', 'global', 'nonlocal', 'static', 'final', 'struct', 'interface', 'chan', 'map', 'class'}
+
+# Maintainability index (0-100) below which a function is hard to maintain, and the buckets of its distribution
+MAINTAINABILITY_BUCKETS = [(10, '0-9'), (20, '10-19'), (40, '20-39'), (float('inf'), '40-100')]
+MAINTAINABILITY_RULE = 'MAINTAINABILITY-INDEX'
+
+# Public declarations whose documentation is counted: Go exported names (methods of exported types), Python
+# names without a leading underscore, Java public declarations and JavaScript exports
+GO_EXPORTED = re.compile(r'^(?:func\s+(?:\(\s*(?:\w+\s+)?\*?(\w+)[^)]*\)\s*)?|(?:type|var|const)\s+)([A-Z]\w*)')
+GO_GROUP = re.compile(r'^(?:type|var|const)\s*\(\s*$')
+GO_MEMBER = re.compile(r'^\s+([A-Z]\w*)\b')
+PYTHON_PUBLIC = re.compile(r'^(\s*)(?:async\s+def|def|class)\s+([A-Za-z]\w*)')
+JAVA_PUBLIC = re.compile(r'^\s*public\s+(?:(?:static|final|abstract|synchronized|default|sealed)\s+)*'
+                         r'(?:(?:class|interface|enum|record)\s+(\w+)|[\w<>\[\], ?]+?\s+(\w+)\s*[(;=]|(\w+)\s*\()')
+JS_EXPORT = re.compile(r'^export\s+(?:default\s+)?(?:async\s+)?(?:function\s*\*?|class|const|let|var)\s*(\w+)')
+
+DUPLICATE_WINDOW = 6  # identical runs of this many code lines count as duplication
+
+# Minutes to fix one finding, per category; the config's debt.effort overrides them per rule id or category
+DEFAULT_EFFORT = {'security': 60, 'concurrency': 45, 'memory-leak': 45, 'resource-management': 30,
+                  'reliability': 30, 'correctness': 30, 'performance': 30, 'error-handling': 15, 'null-safety': 15,
+                  'type-safety': 15, 'async-patterns': 15, 'encapsulation': 15, 'code-quality': 10,
+                  'best-practice': 10, 'documentation': 5}
+FALLBACK_EFFORT = 10
+
+
+def code_lines(text):
+    """(line number, stripped text) of the lines that are neither blank nor comments."""
+    lines = []
+    for number, line in enumerate(text.splitlines(), start=1):
+        stripped = line.strip()
+        if stripped and not stripped.startswith(('//', '#', '/*', '*', '*/')):
+            lines.append((number, stripped))
+    return lines
+
+
+def function_spans(text, language):
+    """(line, name, lines) of each function. A Go function ends at its closing brace in the first column and a
+    Python one where the code dedents; in the other languages a function runs until the next one starts."""
+    start = FUNCTION_START.get(language)
+    if not start:
+        return []
+    functions, current, indent = [], None, 0
+    for number, line in enumerate(text.splitlines(), start=1):
+        stripped = line.strip()
+        if start.search(line):
+            match = FUNCTION_NAME[language].search(line)
+            name = '.'.join(g for g in match.groups() if g) if match else '<anonymous>'
+            current = (number, name, [line])
+            functions.append(current)
+            indent = len(line) - len(line.lstrip())
+            continue
+        if current is None:
+            continue
+        if language == 'python' and stripped and not stripped.startswith('#') \
+                and len(line) - len(line.lstrip()) <= indent:
+            current = None
+            continue
+        current[2].append(line)
+        if language == 'go' and line.rstrip() == '}':
+            current = None
+    return functions
+
+
+def complexity(lines, language):
+    """1 plus the branch points of a function's lines after the first."""
+    branches = 1
+    for line in lines[1:]:
+        if not line.strip().startswith(('//', '#')):
+            branches += len(DECISION.findall(line))
+            if language == 'python':
+                branches += len(PYTHON_DECISION.findall(line))
+    return branches
+
+
+def function_complexities(text, language):
+    return [complexity(lines, language) for _, _, lines in function_spans(text, language)]
+
+
+def halstead(text, language):
+    """Halstead volume, difficulty and effort of some code, from its operator and operand tokens."""
+    pattern = re.compile(f"(?P<comment>{COMMENTS.get(language, COMMENTS[None])})|{TOKEN}", re.S)
+    operators, operands = {}, {}
+    for match in pattern.finditer(text):
+        token = match.group(0)
+        if match.group('comment'):
+            continue
+        counts = operators if token in KEYWORDS or not re.match(r'[\w$"\'`]', token) else operands
+        counts[token] = counts.get(token, 0) + 1
+    vocabulary = len(operators) + len(operands)
+    length = sum(operators.values()) + sum(operands.values())
+    volume = length * math.log2(vocabulary) if vocabulary > 1 else 0.0
+    difficulty = len(operators) / 2 * sum(operands.values()) / len(operands) if operands else 0.0
+    return {'volume': round(volume, 1), 'difficulty': round(difficulty, 1), 'effort': round(volume * difficulty)}
+
+
+def maintainability_index(volume, branches, lines):
+    """The maintainability index rescaled to 0-100 (as Visual Studio does): 171 - 5.2 ln(Halstead volume)
+    - 0.23 cyclomatic complexity - 16.2 ln(code lines)."""
+    if lines == 0:
+        return 100.0
+    raw = 171 - 5.2 * math.log(max(volume, 1)) - 0.23 * branches - 16.2 * math.log(lines)
+    return round(min(100.0, max(0.0, raw * 100 / 171)), 1)
+
+
+def function_metrics(text, language):
+    """Line, name, code lines, complexity, Halstead volume and maintainability index of each function."""
+    functions = []
+    for line, name, lines in function_spans(text, language):
+        body = '\n'.join(lines)
+        size = len(code_lines(body))
+        branches = complexity(lines, language)
+        volume = halstead(body, language)['volume']
+        functions.append({'line': line, 'end': line + len(lines) - 1, 'name': name, 'lines': size,
+                          'complexity': branches, 'volume': volume,
+                          'maintainability': maintainability_index(volume, branches, size)})
+    return functions
+
+
+def file_metrics(text, language, functions):
+    """Code lines, complexity and Halstead volume of a whole file; its maintainability index is that of its
+    functions averaged by their size, as the formula alone would only rank files by length."""
+    volume = halstead(text, language)['volume']
+    branches = sum(f['complexity'] for f in functions) - len(functions) + 1
+    size = len(code_lines(text))
+    weight = sum(max(f['lines'], 1) for f in functions)
+    index = round(sum(f['maintainability'] * max(f['lines'], 1) for f in functions) / weight, 1) if functions \
+        else maintainability_index(volume, branches, size)
+    return {'lines': size, 'complexity': branches, 'volume': volume, 'maintainability': index}
+
+
+def low_maintainability(text, language, minimum):
+    """(line, message, complexity) of the functions whose maintainability index is below minimum."""
+    return [(f['line'], f"{f['name']} has a maintainability index of {f['maintainability']} (complexity "
+                        f"{f['complexity']}, Halstead volume {f['volume']:.0f}, {f['lines']} code lines), below "
+                        f"{minimum}. Split it into smaller functions", f['complexity'])
+            for f in function_metrics(text, language) if f['maintainability'] < minimum]
+
+
+def comment_line_count(text, language):
+    """Lines holding a comment, own-line or trailing, and for Python the lines of docstrings."""
+    pattern = re.compile(f"(?P<comment>{COMMENTS.get(language, COMMENTS[None])})|{TOKEN}", re.S)
+    numbers = set()
+    for match in pattern.finditer(text):
+        token = match.group(0)
+        line_start = text.rfind('\n', 0, match.start()) + 1
+        docstring = language == 'python' and token.startswith(('"""', "'''")) \
+            and not text[line_start:match.start()].strip()
+        if match.group('comment') or docstring:
+            first = text.count('\n', 0, match.start()) + 1
+            numbers.update(range(first, first + token.count('\n') + 1))
+    return len(numbers)
+
+
+def documented_above(lines, index):
+    """Whether a comment ends right above line index, annotations and decorators aside."""
+    index -= 1
+    while index >= 0 and lines[index].strip().startswith('@'):
+        index -= 1
+    above = lines[index].strip() if index >= 0 else ''
+    return above.startswith('//') or above.endswith('*/')
+
+
+def python_docstring(lines, index):
+    """Whether the def or class at line index starts its body with a docstring."""
+    for i in range(index, len(lines)):
+        if re.sub(r'#.*$', '', lines[i]).rstrip().endswith(':'):
+            body = next((line.strip() for line in lines[i + 1:] if line.strip()), '')
+            return re.match(r'^[rRuUbB]?("|\')', body) is not None
+    return False
+
+
+def public_symbols(text, language):
+    """(line, name, documented) of the declarations of a file that are part of its package's API."""
+    lines = text.splitlines()
+    symbols = []
+    if language == 'go':
+        group, group_documented, depth = False, False, 0
+        for i, line in enumerate(lines):
+            if group:
+                if line.startswith(')'):
+                    group = False
+                    continue
+                member = GO_MEMBER.match(line)
+                if depth == 0 and member:
+                    symbols.append((i + 1, member.group(1), group_documented or documented_above(lines, i)))
+                depth += line.count('{') - line.count('}')
+            elif GO_GROUP.match(line):
+                group, group_documented, depth = True, documented_above(lines, i), 0
+            else:
+                match = GO_EXPORTED.match(line)
+                if match and (match.group(1) is None or match.group(1)[0].isupper()):
+                    name = '.'.join(g for g in match.groups() if g)
+                    symbols.append((i + 1, name, documented_above(lines, i)))
+    elif language == 'python':
+        scopes = []  # (indent, is a public class) of the enclosing defs and classes
+        for i, line in enumerate(lines):
+            stripped = line.strip()
+            if not stripped or stripped.startswith('#'):
+                continue
+            indent = len(line) - len(line.lstrip())
+            while scopes and scopes[-1][0] >= indent:
+                scopes.pop()
+            match = PYTHON_PUBLIC.match(line)
+            if not match:
+                continue
+            public = all(is_class for _, is_class in scopes)
+            if public:
+                symbols.append((i + 1, match.group(2), python_docstring(lines, i)))
+            scopes.append((indent, public and stripped.startswith('class')))
+    else:
+        pattern = JAVA_PUBLIC if language == 'java' else JS_EXPORT
+        for i, line in enumerate(lines):
+            match = pattern.match(line)
+            if match:
+                symbols.append((i + 1, next(g for g in match.groups() if g), documented_above(lines, i)))
+    return symbols
+
+
+def documentation_stats(docs):
+    """Comment-to-code ratio and documented share of the public symbols, per package (folder) and overall,
+    from {path: (code lines, comment lines, public symbols)}."""
+    packages = {}
+    for path, (code, comments, symbols) in docs.items():
+        entry = packages.setdefault(Path(path).parent.as_posix(), {'files': 0, 'code_lines': 0, 'comment_lines': 0,
+                                                                   'public': 0, 'documented': 0, 'undocumented': []})
+        entry['files'] += 1
+        entry['code_lines'] += code
+        entry['comment_lines'] += comments
+        entry['public'] += len(symbols)
+        entry['documented'] += sum(1 for _, _, documented in symbols if documented)
+        entry['undocumented'] += [f"{path}:{line} {name}" for line, name, documented in symbols if not documented]
+
+    def ratios(entry):
+        return {'comment_ratio': round(entry['comment_lines'] / entry['code_lines'], 2) if entry['code_lines'] else 0,
+                'doc_coverage': round(entry['documented'] * 100 / entry['public'], 1) if entry['public'] else None}
+    total = {key: sum(e[key] for e in packages.values()) for key in ('code_lines', 'comment_lines', 'public',
+                                                                     'documented')}
+    ranked = sorted(packages.items(), key=lambda item: (ratios(item[1])['doc_coverage'] is None,
+                                                        ratios(item[1])['doc_coverage'] or 0))
+    return {
+        **total, **ratios(total),
+        'packages': [dict(package=name, **{k: v for k, v in entry.items() if k != 'undocumented'}, **ratios(entry),
+                          undocumented=entry['undocumented'][:20]) for name, entry in ranked],
+    }
+
+
+def duplicated_lines(sources):
+    """{path: line numbers} that belong to a window of code lines found more than once."""
+    windows = {}
+    for path, lines in sources.items():
+        lines = [(n, t) for n, t in lines if len(t) > 2]  # braces and the like repeat everywhere
+        for i in range(len(lines) - DUPLICATE_WINDOW + 1):
+            window = lines[i:i + DUPLICATE_WINDOW]
+            key = hashlib.sha1('\n'.join(t for _, t in window).encode('utf-8')).digest()
+            windows.setdefault(key, []).append((path, [n for n, _ in window]))
+    duplicated = {}
+    for places in windows.values():
+        if len(places) > 1:
+            for path, numbers in places:
+                duplicated.setdefault(path, set()).update(numbers)
+    return duplicated
+
+
+def coupling_stats(files, modules):
+    """Coupling of each Go package of the scanned files, most unstable first, and the import cycles."""
+    graph = import_graph(files, modules)
+    metrics = coupling_metrics(graph)
+    ranked = sorted(metrics.items(), key=lambda item: (-item[1]['instability'], -item[1]['afferent'], item[0]))
+    return {'packages': [dict(package=name, **entry) for name, entry in ranked], 'cycles': import_cycles(graph)}
+
+
+def coverage_stats(findings, functions, coverage, base_dir):
+    """Statement coverage of the profiled files, the functions riskiest to change (highest CRAP score) with
+    the findings inside them, and the findings on lines tests never ran."""
+    by_path = {Path(local).relative_to(base_dir).as_posix(): blocks for local, blocks in coverage.items()
+               if base_dir in Path(local).parents}
+    lines = {path: covered_lines(blocks) for path, blocks in by_path.items()}
+    risky = []
+    for path, blocks in by_path.items():
+        risky += risky_functions([f for f in functions if f['path'] == path], blocks)
+    risky.sort(key=lambda f: -f['crap'])
+    findings = [f for f in findings if f['Rule ID'] != COVERAGE_RULE]
+    for f in risky[:10]:
+        f['findings'] = sum(1 for finding in findings if finding['Path'] == f['path']
+                            and f['line'] <= finding['Line'] <= f['end'])
+    statements = sum(len(s) for _, s in lines.values())
+    executed = sum(len(e) for e, _ in lines.values())
+    uncovered = [f for f in findings if f['Path'] in lines and f['Line'] in lines[f['Path']][1]
+                 and f['Line'] not in lines[f['Path']][0]]
+    return {
+        'files': len(by_path),
+        'statement_lines': statements,
+        'percent': round(executed * 100 / statements, 1) if statements else None,
+        'riskiest': risky[:10],
+        'findings_uncovered': len(uncovered),
+    }
+
+
+def codebase_stats(findings, files, base_dir, debt_weights=None, modules=None, coverage=None):
+    """Aggregate health metrics of the scanned files, without the individual findings."""
+    sources, functions, file_entries, docs = {}, [], [], {}
+    for file_path in files:
+        text = Path(file_path).read_text(encoding='utf-8', errors='ignore')
+        path = Path(file_path).relative_to(base_dir).as_posix()
+        sources[path] = code_lines(text)
+        language = LANGUAGES.get(Path(file_path).suffix)
+        if language:
+            metrics = function_metrics(text, language)
+            functions += [dict(path=path, **f) for f in metrics]
+            file_entries.append(dict(path=path, **file_metrics(text, language, metrics)))
+            docs[path] = (len(sources[path]), comment_line_count(text, language), public_symbols(text, language))
+    complexities = [f['complexity'] for f in functions]
+    indexes = [f['maintainability'] for f in functions]
+    maintainability = {label: 0 for _, label in MAINTAINABILITY_BUCKETS}
+    for value in indexes:
+        maintainability[next(label for limit, label in MAINTAINABILITY_BUCKETS if value < limit)] += 1
+
+    total_lines = sum(len(lines) for lines in sources.values())
+    duplicated = sum(len(numbers) for numbers in duplicated_lines(sources).values())
+    distribution = {label: 0 for _, label in COMPLEXITY_BUCKETS}
+    for value in complexities:
+        distribution[next(label for limit, label in COMPLEXITY_BUCKETS if value <= limit)] += 1
+
+    per_file = {}
+    for f in findings:
+        entry = per_file.setdefault(f['Path'], {'path': f['Path'], 'findings': 0, 'ERROR': 0, 'WARNING': 0,
+                                                'INFO': 0})
+        entry['findings'] += 1
+        entry[f['Severity']] = entry.get(f['Severity'], 0) + 1
+    for entry in per_file.values():
+        lines = len(sources.get(entry['path'], []))
+        entry['per_kloc'] = round(entry['findings'] * 1000 / lines, 1) if lines else None
+    worst = sorted(per_file.values(), key=lambda e: (e['ERROR'], e['WARNING'], e['findings']), reverse=True)
+
+    by_severity = {}
+    for f in findings:
+        by_severity[f['Severity']] = by_severity.get(f['Severity'], 0) + 1
+    return {
+        'files': len(sources),
+        'code_lines': total_lines,
+        'findings': len(findings),
+        'by_severity': by_severity,
+        'findings_per_kloc': round(len(findings) * 1000 / total_lines, 2) if total_lines else 0,
+        'functions': len(complexities),
+        'complexity': {
+            'average': round(sum(complexities) / len(complexities), 1) if complexities else 0,
+            'max': max(complexities, default=0),
+            'distribution': distribution,
+        },
+        'maintainability': {
+            'average': round(sum(indexes) / len(indexes), 1) if indexes else None,
+            'min': min(indexes, default=None),
+            'halstead_volume': round(sum(f['volume'] for f in file_entries)),
+            'distribution': maintainability,
+            'functions': sorted(functions, key=lambda f: f['maintainability'])[:10],
+            'files': sorted(file_entries, key=lambda f: f['maintainability'])[:10],
+        },
+        'documentation': documentation_stats(docs),
+        'coupling': coupling_stats(files, modules) if modules and modules.modules else None,
+        'coverage': coverage_stats(findings, functions, coverage, Path(base_dir).resolve()) if coverage else None,
+        'duplication_percent': round(duplicated * 100 / total_lines, 1) if total_lines else 0,
+        'worst_files': worst[:10],
+        'debt': debt_scores(findings, debt_weights) if debt_weights else None,
+    }
+
+
+def effort_minutes(finding, effort):
+    for rule_id, minutes in effort.items():
+        if rule_matches(finding['Rule ID'], rule_id):
+            return minutes
+    category = finding.get('Category', '')
+    return effort.get(category, DEFAULT_EFFORT.get(category, FALLBACK_EFFORT))
+
+
+def debt_scores(findings, weights):
+    """Debt score (severity weight x minutes to fix, summed) per package, i.e. folder, and overall."""
+    packages = {}
+    for f in findings:
+        minutes = effort_minutes(f, weights['effort'])
+        entry = packages.setdefault(Path(f['Path']).parent.as_posix(), {'findings': 0, 'score': 0, 'minutes': 0})
+        entry['findings'] += 1
+        entry['minutes'] += minutes
+        entry['score'] += weights['severity'].get(f['Severity'], 1) * minutes
+    ranked = sorted(packages.items(), key=lambda item: item[1]['score'], reverse=True)
+    return {
+        'score': sum(e['score'] for e in packages.values()),
+        'remediation_hours': round(sum(e['minutes'] for e in packages.values()) / 60, 1),
+        'packages': [dict(package=name, **entry, remediation_hours=round(entry['minutes'] / 60, 1))
+                     for name, entry in ranked],
+    }
+
+
+def render_stats(stats):
+    severities = ', '.join(f"{s} {n}" for s, n in sorted(stats['by_severity'].items())) or 'none'
+    lines = [
+        "📊 Codebase health",
+        f"   Files:            {stats['files']} ({stats['code_lines']} code lines)",
+        f"   Findings:         {stats['findings']} ({severities})",
+        f"   Findings / KLOC:  {stats['findings_per_kloc']}",
+        f"   Duplication:      {stats['duplication_percent']}% of code lines",
+        f"   Functions:        {stats['functions']} (complexity avg {stats['complexity']['average']},"
+        f" max {stats['complexity']['max']})",
+        "",
+        "🧮 Complexity distribution",
+    ]
+    for label, count in stats['complexity']['distribution'].items():
+        share = count * 100 // stats['functions'] if stats['functions'] else 0
+        lines.append(f"   {label:>6}  {count:>5}  {'█' * (share // 4)}")
+    maintainability = stats['maintainability']
+    if maintainability['functions']:
+        lines += ["", f"🛠️ Maintainability index (0-100): avg {maintainability['average']},"
+                      f" min {maintainability['min']}, Halstead volume {maintainability['halstead_volume']}"]
+        for label, count in maintainability['distribution'].items():
+            share = count * 100 // stats['functions']
+            lines.append(f"   {label:>6}  {count:>5}  {'█' * (share // 4)}")
+        lines.append("   Least maintainable functions:")
+        for f in maintainability['functions'][:5]:
+            lines.append(f"   {f['path']}:{f['line']} {f['name']}: {f['maintainability']}"
+                         f" (complexity {f['complexity']}, volume {f['volume']:.0f}, {f['lines']} lines)")
+        lines.append("   Least maintainable files:")
+        for f in maintainability['files'][:5]:
+            lines.append(f"   {f['path']}: {f['maintainability']}")
+    docs = stats['documentation']
+    if docs['packages']:
+        coverage = f"{docs['doc_coverage']}%" if docs['doc_coverage'] is not None else "n/a"
+        lines += ["", f"📝 Documentation: {docs['comment_ratio']} comment line(s) per code line,"
+                      f" {coverage} of {docs['public']} public symbol(s) documented"]
+        for entry in docs['packages'][:10]:
+            coverage = f"{entry['doc_coverage']}%" if entry['doc_coverage'] is not None else "n/a"
+            lines.append(f"   {entry['package']:<40} {coverage:>6} of {entry['public']:>4} documented"
+                         f"  {entry['comment_ratio']:>5} comments/line")
+    coupling = stats.get('coupling')
+    if coupling and coupling['packages']:
+        lines += ["", f"🔗 Package coupling: {len(coupling['packages'])} package(s), "
+                      f"{len(coupling['cycles'])} import cycle(s)",
+                  f"   {'PACKAGE':<50} {'CA':>4} {'CE':>4} {'I':>5}"]
+        for entry in coupling['packages'][:10]:
+            lines.append(f"   {entry['package'][-50:]:<50} {entry['afferent']:>4} {entry['efferent']:>4}"
+                         f" {entry['instability']:>5}")
+        for cycle in coupling['cycles']:
+            lines.append(f"   ⚠️ import cycle between {', '.join(cycle)}")
+    coverage = stats.get('coverage')
+    if coverage:
+        lines += ["", f"🎯 Test coverage: {coverage['percent']}% of the lines with statements in {coverage['files']}"
+                      f" profiled file(s), {coverage['findings_uncovered']} finding(s) on lines no test runs"]
+        if coverage['riskiest']:
+            lines.append("   Riskiest functions (CRAP = complexity² x uncovered³ + complexity):")
+        for f in coverage['riskiest']:
+            lines.append(f"   {f['path']}:{f['line']} {f['name']}: CRAP {f['crap']} (complexity {f['complexity']},"
+                         f" {f['coverage']}% covered, {f['findings']} finding(s))")
+    if stats['worst_files']:
+        lines += ["", "🔥 Top files"]
+        for entry in stats['worst_files']:
+            kloc = f", {entry['per_kloc']}/KLOC" if entry['per_kloc'] is not None else ''
+            lines.append(f"   {entry['path']}: {entry['findings']} finding(s)"
+                         f" ({entry['ERROR']} error, {entry['WARNING']} warning{kloc})")
+    debt = stats.get('debt')
+    if debt:
+        lines += ["", f"💸 Technical debt: score {debt['score']} (about {debt['remediation_hours']}h to fix)"]
+        for entry in debt['packages'][:10]:
+            lines.append(f"   {entry['package']:<40} {entry['score']:>7}  {entry['findings']:>4} finding(s)"
+                         f"  {entry['remediation_hours']}h")
+    return '\n'.join(lines)
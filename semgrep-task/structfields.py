@@ -0,0 +1,44 @@
+import fnmatch
+import re
+
+from naming import GENERATED
+from structlayout import Layouts, split_fields
+
+RULE_ID = 'STRUCT-TOO-MANY-FIELDS'
+
+TAGGED = re.compile(r'`[^`]*`\s*$')
+
+
+def field_count(body):
+    """(fields, tagged declarations, declarations) of a struct body; grouped names count once each, an
+    embedded type as one. None when the body can't be read."""
+    fields = split_fields(body)
+    if fields is None:
+        return None
+    tagged = sum(1 for line in body.splitlines() if TAGGED.search(line))
+    return sum(len(names) for names, _ in fields), tagged, len(fields)
+
+
+def large_structs(text, options):
+    """(line, message) of the structs of a Go file with more than struct_fields.max_fields fields. Generated
+    files, names matching struct_fields.exclude and, with struct_fields.skip_tagged, data transfer types whose
+    fields are mostly tagged (json, db, yaml...) are left out."""
+    if GENERATED.search(text):
+        return []
+    layouts = Layouts(text)
+    problems = []
+    for name, (line, start, end) in layouts.structs.items():
+        if any(fnmatch.fnmatchcase(name, pattern) for pattern in options['exclude']):
+            continue
+        counted = field_count(layouts.text[start:end])  # tags are blanked there but keep their backticks
+        if counted is None:
+            continue
+        fields, tagged, declarations = counted
+        if fields <= options['max_fields'] or options['skip_tagged'] and tagged >= 0.8 * declarations:
+            continue
+        methods = len(re.findall(rf'^func\s*\(\s*\w*\s*\*?{re.escape(name)}(?:\[[^\]]*\])?\s*\)', layouts.text,
+                                 re.MULTILINE))
+        problems.append((line, f"struct {name} has {fields} fields{f' and {methods} methods' if methods else ''}, "
+                               f"more than {options['max_fields']}: it likely does several jobs. Group the fields "
+                               f"that change together into their own types and move the methods using them along"))
+    return sorted(problems)
@@ -0,0 +1,198 @@
+import re
+
+RULE_ID = 'STRUCT-ALIGNMENT'
+
+# (size, alignment) in bytes on 64-bit platforms (amd64, arm64)
+WORD = 8
+BASIC = {
+    'bool': (1, 1), 'int8': (1, 1), 'uint8': (1, 1), 'byte': (1, 1),
+    'int16': (2, 2), 'uint16': (2, 2),
+    'int32': (4, 4), 'uint32': (4, 4), 'rune': (4, 4), 'float32': (4, 4),
+    'int64': (8, 8), 'uint64': (8, 8), 'float64': (8, 8), 'complex64': (8, 4), 'complex128': (16, 8),
+    'int': (WORD, WORD), 'uint': (WORD, WORD), 'uintptr': (WORD, WORD), 'unsafe.Pointer': (WORD, WORD),
+    'string': (2 * WORD, WORD), 'error': (2 * WORD, WORD), 'any': (2 * WORD, WORD),
+}
+# Standard library types that often sit in structs
+KNOWN = {
+    'time.Time': (24, 8), 'time.Duration': (8, 8), 'time.Month': (8, 8), 'time.Weekday': (8, 8),
+    'sync.Mutex': (8, 4), 'sync.RWMutex': (24, 8), 'sync.WaitGroup': (16, 8), 'sync.Once': (12, 4),
+    'atomic.Bool': (4, 4), 'atomic.Int32': (4, 4), 'atomic.Uint32': (4, 4), 'atomic.Int64': (8, 8),
+    'atomic.Uint64': (8, 8), 'atomic.Uintptr': (8, 8), 'atomic.Value': (16, 8),
+    'context.Context': (16, 8), 'io.Reader': (16, 8), 'io.Writer': (16, 8), 'http.Handler': (16, 8),
+}
+
+STRUCT_START = re.compile(r'^\s*(?:type\s+)?(\w+)\s+struct\s*\{')
+TYPE_DECL = re.compile(r'^\s*(?:type\s+)?(?!type\b)(\w+)\s+(?!struct\b|interface\b)(=\s*)?(\S.*?)\s*$')
+# field names, then the type; embedded fields have the type only
+FIELD = re.compile(r'^([A-Za-z_]\w*(?:\s*,\s*[A-Za-z_]\w*)*)\s+(\S.*)$', re.S)
+EMBEDDED = re.compile(r'^\*?[A-Za-z_][\w.]*$')
+
+
+def strip_comments(text):
+    """The source with comments, string and rune contents blanked, keeping offsets and line breaks."""
+    out, i = [], 0
+    pattern = re.compile(r'//[^\n]*|/\*.*?\*/|"(?:\\.|[^"\\\n])*"|`[^`]*`|\'(?:\\.|[^\'\\\n])*\'', re.S)
+    for match in pattern.finditer(text):
+        out.append(text[i:match.start()])
+        token = match.group(0)
+        blank = re.sub(r'[^\n]', ' ', token)
+        out.append(blank if token.startswith('/') else token[0] + blank[1:-1] + token[-1])
+        i = match.end()
+    out.append(text[i:])
+    return ''.join(out)
+
+
+def closing_brace(text, start):
+    """Index of the } matching the { at start."""
+    depth = 0
+    for i in range(start, len(text)):
+        if text[i] == '{':
+            depth += 1
+        elif text[i] == '}':
+            depth -= 1
+            if depth == 0:
+                return i
+    return None
+
+
+def split_fields(body):
+    """(names, type text) of each field declaration of a struct body, embedded fields named after their type;
+    None when a declaration can't be read."""
+    fields, depth, start = [], 0, 0
+    for i, char in enumerate(body + '\n'):
+        if char in '{[(':
+            depth += 1
+        elif char in '}])':
+            depth -= 1
+        elif char in '\n;' and depth == 0:
+            declaration = body[start:i]
+            start = i + 1
+            text = re.sub(r'\s*`[^`]*`\s*$|\s*"[^"]*"\s*$', '', declaration.strip())  # field tag
+            if not text:
+                continue
+            match = FIELD.match(text)
+            if EMBEDDED.match(text):
+                fields.append(([text.lstrip('*').split('.')[-1]], text))
+            elif match:
+                fields.append((re.split(r'\s*,\s*', match.group(1)), match.group(2).strip()))
+            else:
+                return None
+    return fields
+
+
+def align_to(offset, alignment):
+    return (offset + alignment - 1) // alignment * alignment
+
+
+def struct_size(layout):
+    """(size, alignment) of a struct of (size, alignment) fields in this order, as the Go compiler lays it out."""
+    offset, alignment = 0, 1
+    for size, field_alignment in layout:
+        offset = align_to(offset, field_alignment) + size
+        alignment = max(alignment, field_alignment)
+    if layout and layout[-1][0] == 0 and offset > 0:
+        offset += 1  # a trailing zero-size field gets a byte so its address stays inside the struct
+    return align_to(offset, alignment), alignment
+
+
+class Layouts:
+    """Sizes of the types of one Go file: builtins, known library types and the file's own declarations."""
+
+    def __init__(self, text):
+        self.text = strip_comments(text)
+        self.structs = {}   # name -> (line, body start, body end)
+        self.defined = {}   # name -> underlying type text
+        self.sizes = {}
+        self.collect()
+
+    def collect(self):
+        position = 0
+        for line in self.text.splitlines(keepends=True):
+            match = STRUCT_START.match(line)
+            if match and re.match(r'^\s*type\s', line) or match and self.in_type_group(position):
+                brace = position + match.end() - 1
+                end = closing_brace(self.text, brace)
+                if end is not None:
+                    self.structs[match.group(1)] = (self.text.count('\n', 0, position) + 1, brace + 1, end)
+            elif re.match(r'^\s*type\s', line) or self.in_type_group(position):
+                declared = TYPE_DECL.match(line)
+                if declared and '[' not in declared.group(1) and not declared.group(3).startswith('('):
+                    self.defined[declared.group(1)] = declared.group(3).rstrip('{').strip()
+            position += len(line)
+
+    def in_type_group(self, position):
+        """Whether position is directly inside a `type ( ... )` group."""
+        group = self.text.rfind('type (', 0, position)
+        if group < 0:
+            return False
+        inside = self.text[group + 6:position]
+        return inside.count('(') == inside.count(')') and inside.count('{') == inside.count('}')
+
+    def size_of(self, type_text, seen=()):
+        """(size, alignment) of a type, None when it can't be known from this file."""
+        t = type_text.strip()
+        if t in BASIC:
+            return BASIC[t]
+        if t in KNOWN:
+            return KNOWN[t]
+        if t.startswith(('*', 'map[', 'chan ', 'chan<-', '<-chan', 'func(', 'func (')) or t == 'func':
+            return WORD, WORD
+        if t.startswith('[]'):
+            return 3 * WORD, WORD
+        if re.match(r'^interface\s*\{', t):
+            return 2 * WORD, WORD
+        array = re.match(r'^\[(\d+)\](.+)$', t)
+        if array:
+            element = self.size_of(array.group(2), seen)
+            return (int(array.group(1)) * element[0], element[1]) if element else None
+        if re.match(r'^struct\s*\{', t):
+            layout = self.field_layout(t[t.index('{') + 1:t.rindex('}')], seen)
+            return struct_size([size for _, size in layout]) if layout is not None else None
+        if t in seen:
+            return None
+        if t in self.structs:
+            if t not in self.sizes:
+                line, start, end = self.structs[t]
+                layout = self.field_layout(self.text[start:end], seen + (t,))
+                self.sizes[t] = struct_size([size for _, size in layout]) if layout is not None else None
+            return self.sizes[t]
+        if t in self.defined:
+            return self.size_of(self.defined[t], seen + (t,))
+        return None
+
+    def field_layout(self, body, seen=()):
+        """[(name, (size, alignment))] of a struct body in declaration order, None if a size is unknown."""
+        fields = split_fields(body)
+        if fields is None:
+            return None
+        layout = []
+        for names, type_text in fields:
+            size = self.size_of(type_text, seen)
+            if size is None:
+                return None
+            layout += [(name, size) for name in names]
+        return layout
+
+
+def optimal_order(layout):
+    """The fields sorted for the least padding: zero-size first, then by alignment and size, largest first."""
+    return sorted(layout, key=lambda f: (f[1][0] != 0, -f[1][1], -f[1][0]))
+
+
+def padding_problems(text, options):
+    """(line, message) of the structs of a Go file whose field order wastes at least options['min_saving']
+    bytes per instance."""
+    layouts = Layouts(text)
+    problems = []
+    for name, (line, start, end) in layouts.structs.items():
+        layout = layouts.field_layout(layouts.text[start:end], (name,))
+        if not layout or len(layout) < 2:
+            continue
+        current = struct_size([size for _, size in layout])[0]
+        ordered = optimal_order(layout)
+        best = struct_size([size for _, size in ordered])[0]
+        if current - best >= max(options['min_saving'], 1):
+            problems.append((line, f"struct {name} takes {current} bytes, {best} with its fields ordered "
+                                   f"{', '.join(field for field, _ in ordered)}: {current - best} bytes saved "
+                                   f"per instance (64-bit)"))
+    return sorted(problems)
@@ -0,0 +1,134 @@
+import re
+
+from stats import function_spans
+
+RULE_ID = 'TABLE-DRIVEN-TEST'
+
+LITERAL = re.compile(r'"(?:\\.|[^"\\\n])*"|`[^`]*`|\'(?:\\.|[^\'\\\n])+\'|(?:(?<=[(\[{,:=\s])-)?\b\d[\w.]*'
+                     r'|\b(?:true|false)\b')
+# Calls reporting a failure: their messages differ per case but belong to the loop body, not the table
+REPORT_CALL = re.compile(r'\bt\.(?:Error|Errorf|Fatal|Fatalf|Log|Logf)\(')
+ASSERTION = re.compile(r'\bt\.(?:Error|Errorf|Fatal|Fatalf)\(|\b(?:assert|require)\.\w+\(')
+TESTIFY = re.compile(r'\b(?:assert|require)\.\w+\(')
+
+
+def expected(line):
+    """Indexes of the literals of a line that are the expected values: right of == or !=, the first argument
+    of a testify assertion (expected before actual), everything compared by reflect.DeepEqual or cmp.Diff."""
+    literals = list(LITERAL.finditer(line))
+    comparison = re.search(r'[!=]=', LITERAL.sub(lambda m: ' ' * len(m.group(0)), line))
+    if comparison:
+        return {i for i, m in enumerate(literals) if m.start() > comparison.start()}
+    if TESTIFY.search(line):
+        return {0} if literals else set()
+    if re.search(r'DeepEqual|cmp\.Diff', line):
+        return set(range(len(literals)))
+    return set()
+
+
+def literal_type(value):
+    if value[0] in '"`':
+        return 'string'
+    if value[0] == "'":
+        return 'rune'
+    if value in ('true', 'false'):
+        return 'bool'
+    return 'float64' if re.match(r'^\d+(\.\d*)?[eE]|^\d+\.\d', value) else 'int'
+
+
+def statements(lines, first_line):
+    """(line, shape, (literal, in a failure message) values, code, end line) of the top-level statements of a
+    function body; the shape is the code with its literals replaced by placeholders. A statement runs until its
+    braces and parentheses are closed."""
+    found, current, depth = [], None, 0
+    for number, line in enumerate(lines, start=first_line):
+        blank = LITERAL.sub(lambda m: ' ' * len(m.group(0)), line)
+        cut = blank.find('//')
+        raw, code = (line[:cut], blank[:cut]) if cut >= 0 else (line, blank)
+        if not code.strip():
+            continue
+        reporting = REPORT_CALL.search(raw) is not None
+        if current is None:
+            current = [number, [], [], '', number]
+            found.append(current)
+        current[1].append(re.sub(r'\s+', ' ', LITERAL.sub('$', raw)).strip().replace(':=', '='))
+        current[2] += [(m.group(0), reporting) for m in LITERAL.finditer(raw)]
+        current[3] += raw.strip() + '\n'
+        current[4] = number
+        depth += code.count('{') - code.count('}') + code.count('(') - code.count(')')
+        if depth <= 0:
+            current, depth = None, 0
+    return [(line, '\n'.join(shape), values, code, end) for line, shape, values, code, end in found]
+
+
+def repeated_blocks(body, min_repeats):
+    """(start, size, repeats) of the runs of statement windows repeated with the same shape at least
+    min_repeats times in a row, largest first and without overlaps."""
+    runs, i = [], 0
+    while i < len(body):
+        best = None
+        for size in range(1, 5):
+            repeats = 1
+            window = [s[1] for s in body[i:i + size]]
+            while len(window) == size and [s[1] for s in body[i + repeats * size:i + (repeats + 1) * size]] == window:
+                repeats += 1
+            if repeats >= min_repeats and (best is None or size * repeats > best[1] * best[2]):
+                best = (i, size, repeats)
+        if best and any(ASSERTION.search(s[3]) for s in body[i:i + best[1]]):
+            runs.append(best)
+            i += best[1] * best[2]
+        else:
+            i += 1
+    return runs
+
+
+def table_sketch(name, cases, comparison_fields):
+    """A Go sketch of the table: one field per literal that varies between the cases, `want` for those
+    compared against."""
+    columns = [i for i in range(len(cases[0])) if len({case[i] for case in cases}) > 1]
+    names, args, wants = [], 0, 0
+    for i in columns:
+        if i in comparison_fields:
+            wants += 1
+            names.append('want' if wants == 1 else f"want{wants}")
+        else:
+            args += 1
+            names.append(f"arg{args}")
+    width = max([len('name')] + [len(n) for n in names])
+    fields = [f"\t\t{'name':<{width}} string"] + [f"\t\t{n:<{width}} {literal_type(cases[0][i])}"
+                                                    for n, i in zip(names, columns)]
+    rows = [f"\t\t{{\"case {number}\", {', '.join(case[i] for i in columns)}}},"
+            for number, case in enumerate(cases, start=1)]
+    return '\n'.join([f"func {name}(t *testing.T) {{", "\ttests := []struct {"] + fields + ["\t}{"] + rows +
+                     ["\t}", "\tfor _, tt := range tests {", "\t\tt.Run(tt.name, func(t *testing.T) {",
+                      f"\t\t\t// the repeated check, using {', '.join('tt.' + n for n in names) or 'tt'}",
+                      "\t\t})", "\t}", "}"])
+
+
+def table_test_suggestions(text, options):
+    """(line, message, sketch) of the Test functions of a _test.go file that repeat an assertion block
+    with different literals instead of looping over a table."""
+    suggestions = []
+    for first, name, lines in function_spans(text, 'go'):
+        if not re.match(r'^Test[A-Z_]', name):
+            continue
+        body = statements(lines[1:], first + 1)
+        for start, size, repeats in repeated_blocks(body, options['min_repeats']):
+            blocks = [body[start + r * size:start + (r + 1) * size] for r in range(repeats)]
+            cases = [[value for s in block for value, reporting in s[2] if not reporting] for block in blocks]
+            if len({tuple(case) for case in cases}) < repeats or len({len(case) for case in cases}) != 1:
+                continue  # the same values twice, or a check that is not the same after all
+            position, compared = 0, set()
+            for s in blocks[0]:
+                for line in s[3].splitlines():
+                    if not REPORT_CALL.search(line):
+                        compared.update(position + i for i in expected(line))
+                        position += len(LITERAL.findall(line))
+            end = blocks[-1][-1][4]
+            suggestions.append((blocks[0][0][0],
+                                f"{name} repeats the same {f'{size}-statement ' if size > 1 else ''}check {repeats} "
+                                f"times with different "
+                                f"values (lines {blocks[0][0][0]}-{end}). A table-driven test names each case and "
+                                f"makes adding one a single line; see the Example for a sketch",
+                                table_sketch(name, cases, compared)))
+    return suggestions
@@ -0,0 +1,154 @@
+import re
+
+from hcl import parse
+
+# rule id -> (severity, category)
+RULES = {
+    'TF-OPEN-SECURITY-GROUP': ('ERROR', 'security'),
+    'TF-UNENCRYPTED-STORAGE': ('ERROR', 'security'),
+    'TF-HARDCODED-CREDENTIAL': ('ERROR', 'security'),
+}
+RULE_IDS = list(RULES)
+
+OPEN_CIDRS = {'0.0.0.0/0', '::/0'}
+OPEN_PREFIXES = {'*', '0.0.0.0/0', '::/0', 'Internet', 'Any'}  # azurerm source_address_prefix
+WEB_PORTS = {80, 443}  # open to the world on purpose more often than not
+
+# resource type -> attribute that turns encryption at rest on (off by default)
+ENCRYPTION = {
+    'aws_ebs_volume': 'encrypted',
+    'aws_efs_file_system': 'encrypted',
+    'aws_db_instance': 'storage_encrypted',
+    'aws_rds_cluster': 'storage_encrypted',
+    'aws_docdb_cluster': 'storage_encrypted',
+    'aws_neptune_cluster': 'storage_encrypted',
+    'aws_redshift_cluster': 'encrypted',
+    'aws_elasticache_replication_group': 'at_rest_encryption_enabled',
+}
+# resource type -> nested disk blocks needing encrypted = true
+BLOCK_ENCRYPTION = {
+    'aws_instance': ['root_block_device', 'ebs_block_device'],
+    'aws_launch_configuration': ['root_block_device', 'ebs_block_device'],
+}
+
+CREDENTIAL_NAME = re.compile(r'(?i)(^|_)(password|passwd|secret|secret_key|access_key|token|api_key|'
+                             r'client_secret|private_key|auth_token|connection_string)$')
+
+
+def is_literal_string(value):
+    return isinstance(value, str) and value != ''
+
+
+def is_false(value):
+    """Explicitly or implicitly (unset) off; a computed value gets the benefit of the doubt."""
+    return value is None or value is False or (isinstance(value, str) and value.lower() == 'false')
+
+
+def open_cidrs(value):
+    values = value if isinstance(value, list) else [value]
+    return sorted({v for v in values if isinstance(v, str) and v in OPEN_CIDRS})
+
+
+def port_range(rule):
+    start, end = rule.get('from_port'), rule.get('to_port')
+    if not isinstance(start, int) or not isinstance(end, int):
+        return None, "some ports"
+    if start == end:
+        return {start}, f"port {start}"
+    if (start, end) in ((0, 0), (0, 65535), (-1, -1)):
+        return None, "all ports"
+    return set(range(start, end + 1)) if end - start < 10 else None, f"ports {start}-{end}"
+
+
+def open_ingress(resource):
+    """(line, ports, sources) of ingress rules of a resource open to any address."""
+    kind = resource.labels[0]
+    rules = []
+    if kind == 'aws_security_group':
+        inline = resource.get('ingress')
+        rules += [(resource.attributes['ingress'].line, rule) for rule in inline if isinstance(rule, dict)] \
+            if isinstance(inline, list) else []
+        rules += [(block.line, {name: a.value for name, a in block.attributes.items()})
+                  for block in resource.children('ingress')]
+    elif kind == 'aws_security_group_rule' and resource.get('type') == 'ingress':
+        rules.append((resource.line, {name: a.value for name, a in resource.attributes.items()}))
+    found = []
+    for line, rule in rules:
+        sources = open_cidrs(rule.get('cidr_blocks')) + open_cidrs(rule.get('ipv6_cidr_blocks'))
+        if sources:
+            found.append((line, port_range(rule), sources))
+    if kind == 'aws_vpc_security_group_ingress_rule':
+        sources = open_cidrs(resource.get('cidr_ipv4')) + open_cidrs(resource.get('cidr_ipv6'))
+        if sources:
+            found.append((resource.line, port_range({'from_port': resource.get('from_port'),
+                                                     'to_port': resource.get('to_port')}), sources))
+    if kind == 'google_compute_firewall' and str(resource.get('direction', 'INGRESS')).upper() == 'INGRESS':
+        sources = open_cidrs(resource.get('source_ranges'))
+        if sources:
+            found.append((resource.line, (None, "the allowed ports"), sources))
+    azure_rules = [resource] if kind == 'azurerm_network_security_rule' else \
+        resource.children('security_rule') if kind == 'azurerm_network_security_group' else []
+    for rule in azure_rules:
+        prefix = rule.get('source_address_prefix')
+        if str(rule.get('direction', '')).lower() == 'inbound' and str(rule.get('access', '')).lower() == 'allow' \
+                and isinstance(prefix, str) and prefix in OPEN_PREFIXES:
+            ports = rule.get('destination_port_range')
+            ports = {int(ports)} if isinstance(ports, str) and ports.isdigit() else None
+            found.append((rule.line, (ports, f"port {rule.get('destination_port_range')}" if ports else "some ports"),
+                          [prefix]))
+    return found
+
+
+def unencrypted(resource):
+    """(line, what) of the storage of a resource left unencrypted."""
+    kind = resource.labels[0]
+    found = []
+    if kind in ENCRYPTION and is_false(resource.get(ENCRYPTION[kind])):
+        attribute = resource.attributes.get(ENCRYPTION[kind])
+        found.append((attribute.line if attribute else resource.line, ENCRYPTION[kind]))
+    for block_type in BLOCK_ENCRYPTION.get(kind, []):
+        for block in resource.children(block_type):
+            if is_false(block.get('encrypted')):
+                found.append((block.line, f"{block_type}.encrypted"))
+    return found
+
+
+def credentials(block, trail=()):
+    """(line, attribute path) of credential-named attributes holding a literal, in the block and its children."""
+    found = []
+    for name, attribute in block.attributes.items():
+        if CREDENTIAL_NAME.search(name) and is_literal_string(attribute.value):
+            found.append((attribute.line, '.'.join(trail + (name,))))
+    for child in block.blocks:
+        found += credentials(child, trail + (child.type,))
+    return found
+
+
+def address(block):
+    return '.'.join(block.labels) if block.type == 'resource' else ' '.join([block.type] + block.labels)
+
+
+def terraform_problems(text):
+    """(line, rule id, severity, message) of a Terraform file; raises hcl.HCLError when it does not parse."""
+    problems = []
+    for block in parse(text).blocks:
+        name = address(block)
+        if block.type == 'resource' and len(block.labels) == 2:
+            for line, (ports, described), sources in open_ingress(block):
+                severity = 'WARNING' if ports and ports <= WEB_PORTS else 'ERROR'
+                problems.append((line, 'TF-OPEN-SECURITY-GROUP', severity,
+                                 f"{name} allows inbound traffic on {described} from {', '.join(sources)}. "
+                                 f"Restrict the source to the networks that need it"))
+            for line, attribute in unencrypted(block):
+                problems.append((line, 'TF-UNENCRYPTED-STORAGE', 'ERROR',
+                                 f"{name} stores data unencrypted. Set {attribute} = true (and a KMS key)"))
+        for line, attribute in credentials(block):
+            problems.append((line, 'TF-HARDCODED-CREDENTIAL', 'ERROR',
+                             f"{attribute} of {name} is hardcoded. Use a sensitive variable or a secret manager "
+                             f"data source"))
+        if block.type == 'variable' and block.labels and CREDENTIAL_NAME.search(block.labels[0]) \
+                and is_literal_string(block.get('default')):
+            problems.append((block.attributes['default'].line, 'TF-HARDCODED-CREDENTIAL', 'ERROR',
+                             f"Variable {block.labels[0]} has a hardcoded default. Leave it unset and pass it at apply time, "
+                             f"marked sensitive"))
+    return sorted(problems)
@@ -0,0 +1,78 @@
+import importlib.util
+import json
+import os
+import sys
+import tempfile
+import unittest
+from pathlib import Path
+from unittest import mock
+
+SCRIPT_DIR = Path(__file__).resolve().parent.parent
+sys.path.insert(0, str(SCRIPT_DIR))
+
+
+def load_auto_review():
+    spec = importlib.util.spec_from_file_location('auto_review', SCRIPT_DIR / 'auto-review.py')
+    module = importlib.util.module_from_spec(spec)
+    spec.loader.exec_module(module)
+    return module
+
+
+auto_review = load_auto_review()
+
+SOURCE = 'package app\n\nfunc a() {\n\tpanic("a")\n}\n\nfunc b() {\n\tpanic("b")\n}\n'
+RULES = ['rules.go-rule-3-avoid-panic', 'rules.go-rule-26-goroutine-panic']
+
+
+def semgrep_output(reverse):
+    """Semgrep JSON with two rules on lines 4 and 8, in file order or reversed like a concurrent run could."""
+    results = [{'check_id': rule, 'start': {'line': line, 'col': 2, 'offset': 0},
+                'end': {'line': line, 'col': 12, 'offset': 0},
+                'extra': {'severity': 'WARNING', 'message': f"{rule} at {line}", 'metadata': {}}}
+               for line in (4, 8) for rule in RULES]
+    return json.dumps({'results': results[::-1] if reverse else results})
+
+
+def reversed_walk(walk):
+    """os.walk listing the folders and files of each level in reverse order."""
+    def walk_backwards(*args, **kwargs):
+        for root, dirs, files in walk(*args, **kwargs):
+            dirs.reverse()
+            files.reverse()
+            yield root, dirs, files
+    return walk_backwards
+
+
+class OrderingTest(unittest.TestCase):
+    """Findings come out in the same order whatever order the files are walked and analyzers report in."""
+
+    def setUp(self):
+        self.tmp = tempfile.TemporaryDirectory()
+        root = Path(self.tmp.name)
+        for name in ['b/z.go', 'b/a.go', 'a/m.go', 'main.go']:
+            (root / name).parent.mkdir(parents=True, exist_ok=True)
+            (root / name).write_text(SOURCE, encoding='utf-8')
+
+    def tearDown(self):
+        self.tmp.cleanup()
+
+    def scan(self, reverse):
+        walk = reversed_walk(os.walk) if reverse else os.walk
+        with mock.patch.object(auto_review.os, 'walk', walk), \
+                mock.patch.object(auto_review.CodeReviewer, 'run_semgrep', lambda *_: semgrep_output(reverse)):
+            findings = auto_review.CodeReviewer(self.tmp.name, excel=False, cache=False).run()
+        return [{k: v for k, v in f.items() if k != 'Timestamp'} for f in findings]
+
+    def test_same_order_between_runs(self):
+        forward, backward = self.scan(reverse=False), self.scan(reverse=True)
+        self.assertTrue(forward)
+        self.assertEqual(forward, backward)
+
+    def test_ordered_by_path_line_and_rule(self):
+        findings = self.scan(reverse=True)
+        keys = [(f['Path'], f['Line'], f['Rule ID']) for f in findings]
+        self.assertEqual(keys, sorted(keys))
+
+
+if __name__ == '__main__':
+    unittest.main()
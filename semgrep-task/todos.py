@@ -0,0 +1,66 @@
+import datetime
+import re
+
+from blame import blame_lines, UNCOMMITTED
+
+RULE_ID = 'TODO-COMMENT'
+
+# a marker right after the comment token, optionally with an owner: // TODO(alice): ..., # FIXME ..., /* HACK */
+COMMENT = r'(?:^|\s|[;{}])(?://+|#+|/\*+|\*|<!--|--)\s*'
+
+
+def marker_pattern(markers):
+    return re.compile(COMMENT + r'(' + '|'.join(re.escape(m) for m in markers) + r')\b(?:\(([^)]*)\))?[:\s]*(.*)')
+
+
+def todo_comments(text, markers):
+    """(line, marker, owner, text) of the TODO-style comments of a file."""
+    pattern = marker_pattern(markers)
+    found = []
+    for number, line in enumerate(text.splitlines(), start=1):
+        match = pattern.search(line)
+        if match:
+            marker, owner, note = match.groups()
+            found.append((number, marker, owner or '', re.sub(r'\s*(\*/|-->)\s*$', '', note).strip()))
+    return found
+
+
+def age_text(days):
+    if days < 1:
+        return "added today"
+    if days < 60:
+        return f"{days} day{'s' * (days != 1)} old"
+    if days < 730:
+        return f"{days // 30} months old"
+    return f"{days // 365} years old"
+
+
+def todo_findings(file_path, text, options, today=None):
+    """(line, severity, message, fields) of the TODO, FIXME and HACK comments of a file, aged with git blame. They
+    are INFO, WARNING when older than todos.max_age_days or, with todos.require_issue, without an issue reference.
+    The age, author and reasons go in the fields (Age, Author, Introduced, Escalated): the message, which the
+    finding's fingerprint hashes, stays the same from one day to the next."""
+    comments = todo_comments(text, options['markers'])
+    if not comments:
+        return []
+    blame = blame_lines(file_path)
+    today = today or datetime.date.today()
+    issue = re.compile(options['issue_pattern']) if options['issue_pattern'] else None
+    findings = []
+    for line, marker, owner, note in comments:
+        entry = blame.get(line)
+        committed = entry and entry['commit'] != UNCOMMITTED and entry.get('date')
+        days = (today - datetime.date.fromisoformat(entry['date'])).days if committed else 0
+        problems = []
+        if options['max_age_days'] and days > options['max_age_days']:
+            problems.append(f"older than {options['max_age_days']} days")
+        if options['require_issue'] and issue and not issue.search(f"{owner} {note}"):
+            problems.append("no issue reference")
+        fields = {'Age': age_text(days) if committed else "not committed yet"}
+        if committed:
+            fields.update({'Author': entry.get('author', ''), 'Introduced': entry['date']})
+        if problems:
+            fields['Escalated'] = f"{', '.join(problems)}: do it, drop it or reference the issue tracking it"
+        message = f"{marker}{f'({owner})' if owner else ''}: {note or '(no description)'}"
+        findings.append((line, 'WARNING' if problems else 'INFO', message, fields))
+    return findings
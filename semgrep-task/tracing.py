@@ -0,0 +1,42 @@
+import os
+import atexit
+import contextlib
+
+from profiling import PROFILER
+
+# OpenTelemetry is optional: spans are only recorded when the SDK is installed
+# and OTEL_EXPORTER_OTLP_ENDPOINT is set, otherwise span() only feeds the --profile timings.
+try:
+    from opentelemetry import trace
+    from opentelemetry.sdk.resources import Resource
+    from opentelemetry.sdk.trace import TracerProvider
+    from opentelemetry.sdk.trace.export import BatchSpanProcessor
+    from opentelemetry.exporter.otlp.proto.http.trace_exporter import OTLPSpanExporter
+except ImportError:
+    trace = None
+
+_tracer = None
+
+
+def setup_tracing():
+    global _tracer
+    if trace is None or not os.environ.get('OTEL_EXPORTER_OTLP_ENDPOINT'):
+        return
+    provider = TracerProvider(resource=Resource.create({
+        'service.name': os.environ.get('OTEL_SERVICE_NAME', 'auto-review'),
+    }))
+    provider.add_span_processor(BatchSpanProcessor(OTLPSpanExporter()))
+    trace.set_tracer_provider(provider)
+    atexit.register(provider.shutdown)  # flush spans before the CLI exits
+    _tracer = trace.get_tracer('auto-review')
+
+
+@contextlib.contextmanager
+def span(name, **attributes):
+    """OpenTelemetry span when tracing is set up; also timed by --profile."""
+    with PROFILER.timed(name, attributes):
+        if _tracer is None:
+            yield None
+            return
+        with _tracer.start_as_current_span(name, attributes=attributes) as current:
+            yield current
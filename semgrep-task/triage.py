@@ -0,0 +1,174 @@
+import json
+import datetime
+from pathlib import Path
+
+from publishers import fingerprint
+from history import history_file, load_triage, save_triage, recorded_finding
+
+SUPPRESSIONS_FILE = '.codereview-suppressions.json'
+
+# status -> (key in the TUI, marker in the list, still reported by scans)
+STATUSES = {
+    'accepted-risk': ('a', 'A', False),
+    'false-positive': ('f', 'F', False),
+    'fix-later': ('l', 'L', True),
+    'suppressed': ('s', 'S', False),
+}
+# Names used by older suppressions files
+ALIASES = {'accepted': 'accepted-risk'}
+
+
+def suppressions_file(config, base_dir):
+    """The config's `suppressions` file (relative to the config) or .codereview-suppressions.json in the scanned folder."""
+    if config.get('suppressions'):
+        return Path(config['root'] or base_dir) / config['suppressions']
+    return Path(base_dir) / SUPPRESSIONS_FILE
+
+
+def load_suppressions(path):
+    """fingerprint -> {status, rule, path, updated}"""
+    path = Path(path)
+    entries = json.loads(path.read_text(encoding='utf-8')) if path.is_file() else {}
+    for entry in entries.values():
+        entry['status'] = ALIASES.get(entry['status'], entry['status'])
+    return entries
+
+
+def save_suppressions(path, suppressions):
+    Path(path).write_text(json.dumps(suppressions, indent=2, sort_keys=True) + "\n", encoding='utf-8')
+
+
+def load_decisions(config, base_dir, store=None):
+    """Triage decisions of a folder: the suppressions file, overridden by the history store (the config's
+    `history` file unless given)."""
+    decisions = load_suppressions(suppressions_file(config, base_dir))
+    decisions.update(load_triage(store or history_file(config, base_dir)))
+    return decisions
+
+
+def apply_suppressions(findings, suppressions, counts=None):
+    """Drops findings triaged as accepted-risk, false-positive or suppressed, counting them per status in
+    counts; fix-later ones stay, tagged with a Triage field."""
+    kept = []
+    for f in findings:
+        entry = suppressions.get(fingerprint(f))
+        if entry and entry['status'] in STATUSES:
+            if not STATUSES[entry['status']][2]:
+                if counts is not None:
+                    counts[entry['status']] = counts.get(entry['status'], 0) + 1
+                continue
+            f['Triage'] = entry['status']
+        kept.append(f)
+    return kept
+
+
+def decision(finding, status, reason=None, author=None):
+    return {'status': status, 'rule': finding['Rule ID'], 'path': finding['Path'], 'reason': reason,
+            'author': author, 'updated': datetime.date.today().isoformat()}
+
+
+def set_status(suppressions, finding, status, reason=None, author=None):
+    key = fingerprint(finding)
+    if status is None:
+        suppressions.pop(key, None)
+        return
+    suppressions[key] = decision(finding, status, reason, author)
+
+
+def mark(store, key, status, reason=None, author=None):
+    """Records a decision for the finding recorded in the history store under fingerprint (prefix) key, or
+    clears it when status is None; returns (fingerprint, decision). Raises KeyError for an unknown finding
+    and ValueError for an unknown status."""
+    if status is not None and status not in STATUSES:
+        raise ValueError(f"unknown triage status {status!r} (expected one of: {', '.join(STATUSES)})")
+    if status is None:
+        decided = [k for k in load_triage(store) if k.startswith(key)]
+        if len(decided) == 1:
+            save_triage(store, {decided[0]: None})
+            return decided[0], None
+    key, finding = recorded_finding(store, key)
+    entry = decision(finding, status, reason, author) if status else None
+    save_triage(store, {key: entry})
+    return key, entry
+
+
+def render_decisions(decisions):
+    if not decisions:
+        return "No triage decisions"
+    lines = [f"{'FINGERPRINT':<12}  {'STATUS':<14}  {'UPDATED':<10}  FINDING"]
+    for key, entry in sorted(decisions.items(), key=lambda item: (item[1]['path'], item[1]['rule'])):
+        reason = f" ({entry['reason']})" if entry.get('reason') else ''
+        lines.append(f"{key[:12]:<12}  {entry['status']:<14}  {entry['updated']:<10}  "
+                     f"{entry['path']} {entry['rule']}{reason}")
+    return '\n'.join(lines)
+
+
+def code_context(base_dir, finding, radius=4):
+    try:
+        lines = (Path(base_dir) / finding['Path']).read_text(encoding='utf-8', errors='ignore').splitlines()
+    except OSError:
+        return []
+    first = max(finding['Line'] - 1 - radius, 0)
+    return [(n + 1, lines[n]) for n in range(first, min(finding['Line'] + radius, len(lines)))]
+
+
+def triage_tui(findings, base_dir, suppressions, store, author=None):
+    """Pages through findings in a terminal UI, starting from the current decisions, and writes the changed
+    ones to the history store on quit."""
+    import curses
+
+    if not findings:
+        print("✅ Nothing to triage")
+        return
+    changed = {}
+    keys = {v[0]: status for status, v in STATUSES.items()}
+    help_line = "↑/↓ move  a accepted-risk  f false-positive  l fix-later  s suppress  u clear  " \
+                "q save & quit  x quit without saving"
+
+    def draw(screen, selected, top):
+        screen.erase()
+        height, width = screen.getmaxyx()
+        list_height = max(height // 2 - 1, 3)
+        for row, f in enumerate(findings[top:top + list_height]):
+            entry = suppressions.get(fingerprint(f))
+            marker = STATUSES[entry['status']][1] if entry else ' '
+            text = f"[{marker}] {f['Severity']:<7} {f['Path']}:{f['Line']}  {f['Rule ID']}: {f['Message']}"
+            attr = curses.A_REVERSE if top + row == selected else curses.A_NORMAL
+            screen.addnstr(row, 0, text, width - 1, attr)
+        screen.hline(list_height, 0, '-', width - 1)
+        current = findings[selected]
+        screen.addnstr(list_height, 2, f" {fingerprint(current)[:12]} ", width - 3)  # for triage --mark
+        for row, (number, line) in enumerate(code_context(base_dir, current), start=list_height + 1):
+            if row >= height - 1:
+                break
+            attr = curses.A_BOLD if number == current['Line'] else curses.A_NORMAL
+            screen.addnstr(row, 0, f"{number:>5} {line}", width - 1, attr)
+        screen.addnstr(height - 1, 0, help_line, width - 1, curses.A_DIM)
+        screen.refresh()
+        return list_height
+
+    def loop(screen):
+        curses.curs_set(0)
+        selected, top = 0, 0
+        while True:
+            list_height = draw(screen, selected, top)
+            key = screen.getkey()
+            if key in ('q', 'x'):
+                return key == 'q'
+            if key in ('KEY_DOWN', 'j'):
+                selected = min(selected + 1, len(findings) - 1)
+            elif key in ('KEY_UP', 'k'):
+                selected = max(selected - 1, 0)
+            elif key == 'KEY_NPAGE':
+                selected = min(selected + list_height, len(findings) - 1)
+            elif key == 'KEY_PPAGE':
+                selected = max(selected - list_height, 0)
+            elif key in keys or key == 'u':
+                set_status(suppressions, findings[selected], keys.get(key), author=author)
+                changed[fingerprint(findings[selected])] = suppressions.get(fingerprint(findings[selected]))
+                selected = min(selected + 1, len(findings) - 1)
+            top = min(max(top, selected - list_height + 1), selected)
+
+    if curses.wrapper(loop):
+        save_triage(store, changed)
+        print(f"📝 Saved {len(changed)} triage decision(s) to {store}")
@@ -0,0 +1,56 @@
+import os
+import subprocess
+from pathlib import Path
+
+# CLI used per URL scheme; both are expected to be authenticated already (CI credentials, instance roles...)
+UPLOAD_COMMANDS = {
+    's3://': ['aws', 's3', 'cp'],
+    'gs://': ['gsutil', 'cp'],
+}
+
+
+def git_value(args, cwd, default):
+    res = subprocess.run(["git"] + args, cwd=cwd, capture_output=True, text=True)
+    return res.stdout.strip() if res.returncode == 0 and res.stdout.strip() else default
+
+
+def current_branch(cwd):
+    """Branch being scanned, preferring CI variables since CI checkouts are often detached."""
+    return (os.environ.get('CI_COMMIT_REF_NAME') or os.environ.get('GITHUB_REF_NAME')
+            or os.environ.get('BUILDKITE_BRANCH') or os.environ.get('BRANCH_NAME')
+            or git_value(["rev-parse", "--abbrev-ref", "HEAD"], cwd, 'unknown'))
+
+
+def key_values(base_dir):
+    """Values for the {repo}, {branch} and {commit} placeholders of an upload destination."""
+    top = git_value(["rev-parse", "--show-toplevel"], base_dir, str(base_dir))
+    return {
+        'repo': Path(top).name,
+        'branch': current_branch(base_dir).replace('/', '-'),
+        'commit': git_value(["rev-parse", "HEAD"], base_dir, 'unknown'),
+    }
+
+
+def upload_reports(reports, destination, base_dir):
+    """Copies the report files to an s3:// or gs:// destination such as s3://bucket/{repo}/{branch}/{commit};
+    returns whether every copy worked."""
+    command = next((cmd for scheme, cmd in UPLOAD_COMMANDS.items() if destination.startswith(scheme)), None)
+    if command is None:
+        print(f"⚠️ Upload skipped: {destination} must start with s3:// or gs://")
+        return False
+
+    prefix = destination.format(**key_values(base_dir)).rstrip('/')
+    failures = 0
+    for report in reports:
+        try:
+            res = subprocess.run(command + [str(report), f"{prefix}/{Path(report).name}"], capture_output=True,
+                                 text=True)
+        except FileNotFoundError:
+            print(f"⚠️ Upload failed: {command[0]} not found")
+            return False
+        if res.returncode != 0:
+            print(f"⚠️ Upload failed for {report}: {res.stderr.strip()}")
+            failures += 1
+    if failures < len(reports):
+        print(f"☁️ Uploaded {len(reports) - failures} of {len(reports)} file(s) to {prefix}")
+    return failures == 0
@@ -0,0 +1,198 @@
+import re
+from pathlib import Path
+
+from catalog import load_rules, BUILTIN_CHECKS
+from config import DEFAULT_CONFIG, rule_matches
+from gate import GateError, SEVERITY_RANK, parse_condition
+from shellcheck import RULE_ID as SHELLCHECK_RULE
+
+PATTERN_KEYS = {'pattern', 'patterns', 'pattern-either', 'pattern-regex', 'pattern-sources', 'match'}
+REQUIRED_RULE_KEYS = ['id', 'message', 'languages', 'severity']
+IMPORT_POLICY_KEYS = {'import', 'paths', 'allow', 'severity', 'message'}
+
+
+def compose(path):
+    import yaml
+    with open(path, encoding='utf-8') as f:
+        return yaml.compose(f)
+
+
+def line_of(node):
+    return node.start_mark.line + 1
+
+
+def pairs(node):
+    return [(k.value, k, v) for k, v in node.value] if node is not None and node.tag.endswith(':map') else []
+
+
+def mapping(node):
+    return {k: v for k, _, v in pairs(node)}
+
+
+def items(node):
+    return list(node.value) if node is not None and node.tag.endswith(':seq') else []
+
+
+def glob_problem(pattern):
+    if not pattern:
+        return "empty glob"
+    if pattern.count('[') > pattern.count(']'):
+        return f"invalid glob {pattern!r}: unclosed '['"
+    if Path(pattern).is_absolute() or '..' in Path(pattern).parts:
+        return f"glob {pattern!r} must be relative to the config file's folder"
+    return None
+
+
+class Validator:
+    def __init__(self, path, choices):
+        self.path = Path(path)
+        self.choices = choices  # dotted key -> allowed values, e.g. output.format -> FORMATS
+        self.problems = []      # (file, line, message)
+
+    def problem(self, node, message, path=None):
+        self.problems.append((path or self.path, line_of(node) if node is not None else 1, message))
+
+    def check_keys(self, node, schema, prefix=''):
+        """Unknown keys and wrong value types, against the shape of DEFAULT_CONFIG."""
+        for key, key_node, value in pairs(node):
+            dotted = prefix + key
+            if key not in schema:
+                self.problem(key_node, f"unknown key '{dotted}'")
+                continue
+            expected = schema.get(key)
+            if isinstance(expected, dict) and expected and not value.tag.endswith(':map'):
+                self.problem(value, f"'{dotted}' must be a mapping")
+            elif isinstance(expected, dict) and expected:
+                self.check_keys(value, expected, dotted + '.')
+            elif isinstance(expected, list) and not value.tag.endswith(':seq'):
+                self.problem(value, f"'{dotted}' must be a list")
+            elif isinstance(expected, bool) and value.tag.endswith(':seq'):
+                self.problem(value, f"'{dotted}' must be true or false")
+            allowed = self.choices.get(dotted)
+            if allowed:
+                for item in (items(value) or [value]):
+                    if item.tag.endswith(':str') and item.value and item.value not in allowed:
+                        self.problem(item, f"unknown value {item.value!r} for '{dotted}'"
+                                           f" (expected one of: {', '.join(sorted(allowed))})")
+
+    def check_custom_rules(self, node, known):
+        """Custom rule files must exist, parse, and define Semgrep rules with new ids."""
+        builtin_ids = {r['id'] for r in known if r['origin'] == 'builtin'}
+        for item in items(node):
+            rule_file = self.path.parent / item.value
+            if not rule_file.is_file():
+                self.problem(item, f"custom rule file {item.value} not found")
+                continue
+            try:
+                root = compose(rule_file)
+            except Exception as e:
+                mark = getattr(e, 'problem_mark', None)
+                self.problems.append((rule_file, mark.line + 1 if mark else 1, f"invalid YAML: {e}"))
+                continue
+            rules = mapping(root).get('rules')
+            if rules is None:
+                self.problem(root, "missing top-level 'rules' list", rule_file)
+                continue
+            for rule in items(rules):
+                fields = mapping(rule)
+                for key in REQUIRED_RULE_KEYS:
+                    if key not in fields:
+                        self.problem(rule, f"rule is missing '{key}'", rule_file)
+                if not PATTERN_KEYS & set(fields):
+                    self.problem(rule, "rule has no pattern", rule_file)
+                if 'id' in fields and fields['id'].value in builtin_ids:
+                    self.problem(fields['id'], f"rule id {fields['id'].value} shadows a builtin rule", rule_file)
+                if 'severity' in fields and str(fields['severity'].value).upper() not in SEVERITY_RANK:
+                    self.problem(fields['severity'], f"invalid severity {fields['severity'].value!r}", rule_file)
+
+    def check_rules(self, root, rule_ids):
+        """Unknown rule ids in rules/severity and overrides that contradict each other."""
+        sections = mapping(root)
+        rules = mapping(sections.get('rules'))
+        only = {n.value: n for n in items(rules.get('only'))}
+        disable = {n.value: n for n in items(rules.get('disable'))}
+
+        def known(rule_id):
+            if SHELLCHECK_RULE.match(rule_id):
+                return True
+            return any(rule_matches(candidate, rule_id) or rule_matches(rule_id, candidate) for candidate in rule_ids)
+
+        for rule_id, node in list(only.items()) + list(disable.items()):
+            if not known(rule_id):
+                self.problem(node, f"unknown rule id {rule_id}")
+        for rule_id, node in only.items():
+            if rule_id in disable:
+                self.problem(disable[rule_id], f"rule {rule_id} is both in rules.only and rules.disable")
+        for rule_id, key_node, value in pairs(sections.get('severity')):
+            if not known(rule_id):
+                self.problem(key_node, f"unknown rule id {rule_id} in severity")
+            if str(value.value).upper() not in SEVERITY_RANK:
+                self.problem(value, f"invalid severity {value.value!r} for {rule_id}")
+            if rule_id in disable:
+                self.problem(key_node, f"severity override for {rule_id}, which rules.disable turns off")
+            elif only and rule_id not in only:
+                self.problem(key_node, f"severity override for {rule_id}, which is not in rules.only")
+
+    def check_gate_and_globs(self, root):
+        sections = mapping(root)
+        for item in items(sections.get('exclude')) + items(mapping(sections.get('kubernetes')).get('paths')) \
+                + items(mapping(sections.get('struct_layout')).get('paths')):
+            problem = glob_problem(str(item.value))
+            if problem:
+                self.problem(item, problem)
+        for policy in items(sections.get('imports')):
+            fields = mapping(policy)
+            if 'import' not in fields:
+                self.problem(policy, "import policy is missing 'import'")
+            for key in fields:
+                if key not in IMPORT_POLICY_KEYS:
+                    self.problem(fields[key], f"unknown import policy key '{key}'")
+            for item in items(fields.get('paths')) + items(fields.get('allow')):
+                problem = glob_problem(str(item.value))
+                if problem:
+                    self.problem(item, problem)
+            if 'severity' in fields and str(fields['severity'].value).upper() not in SEVERITY_RANK:
+                self.problem(fields['severity'], f"invalid severity {fields['severity'].value!r}")
+        for section in ('commits', 'todos'):
+            pattern = mapping(sections.get(section)).get('issue_pattern')
+            if pattern is not None and pattern.value:
+                try:
+                    re.compile(str(pattern.value))
+                except re.error as e:
+                    self.problem(pattern, f"invalid regex for {section}.issue_pattern: {e}")
+        gate = mapping(sections.get('gate'))
+        for item in items(gate.get('conditions')):
+            try:
+                parse_condition(str(item.value))
+            except GateError as e:
+                self.problem(item, str(e))
+        if gate.get('fail_on') is not None and gate['fail_on'].value and \
+                str(gate['fail_on'].value).upper() not in SEVERITY_RANK:
+            self.problem(gate['fail_on'], f"invalid severity {gate['fail_on'].value!r} for gate.fail_on")
+
+
+def validate_config(path, rules_dir, choices):
+    """Problems of a .codereview.yaml and its custom rule files, as (file, line, message)."""
+    validator = Validator(path, choices)
+    try:
+        root = compose(path)
+    except Exception as e:
+        mark = getattr(e, 'problem_mark', None)
+        return [(Path(path), mark.line + 1 if mark else 1, f"invalid YAML: {e}")]
+    if root is None:
+        return []
+    if not root.tag.endswith(':map'):
+        return [(Path(path), line_of(root), "config must be a mapping")]
+
+    validator.check_keys(root, DEFAULT_CONFIG)
+    sections = mapping(root)
+    custom = items(mapping(sections.get('rules')).get('custom'))
+    custom_files = [validator.path.parent / n.value for n in custom if (validator.path.parent / n.value).is_file()]
+    try:
+        known = load_rules(rules_dir, custom_files)
+    except Exception:
+        known = load_rules(rules_dir)  # broken custom files are reported below
+    validator.check_custom_rules(mapping(sections.get('rules')).get('custom'), known)
+    validator.check_rules(root, [r['id'] for r in known] + list(BUILTIN_CHECKS))
+    validator.check_gate_and_globs(root)
+    return sorted(validator.problems, key=lambda p: (str(p[0]), p[1]))
@@ -0,0 +1,102 @@
+import json
+import shlex
+import shutil
+import subprocess
+from pathlib import Path
+
+# vulncheck.scan: how precise govulncheck is, and the severity of what it reports at that level
+SCAN_LEVELS = {'symbol': 'ERROR', 'package': 'WARNING', 'module': 'INFO'}
+RULE_ID = 'VULN-CHECK'
+
+
+def json_stream(text):
+    """The JSON values of govulncheck -json output, which are concatenated rather than one per line."""
+    decoder, values, position = json.JSONDecoder(), [], 0
+    text = text.strip()
+    while position < len(text):
+        value, position = decoder.raw_decode(text, position)
+        values.append(value)
+        while position < len(text) and text[position].isspace():
+            position += 1
+    return values
+
+
+def finding_level(trace):
+    """symbol when govulncheck found a call path to the vulnerable function, else package or module."""
+    frame = trace[0] if trace else {}
+    return 'symbol' if frame.get('function') else 'package' if frame.get('package') else 'module'
+
+
+def run_govulncheck(module_dir, options):
+    """(osv entries by id, findings) of one module; None when govulncheck is not installed or fails."""
+    command = shlex.split(options['command'])
+    if not shutil.which(command[0]):
+        return None
+    res = subprocess.run(command + ['-json', '-scan', options['scan'], './...'], cwd=module_dir,
+                         capture_output=True, text=True, encoding='utf-8')
+    try:
+        messages = json_stream(res.stdout)
+    except ValueError:
+        return None
+    if res.returncode not in (0, 3) and not any('finding' in m for m in messages):
+        reason = (res.stderr.strip().splitlines() or [f"exit code {res.returncode}"])[-1]
+        print(f"⚠️ govulncheck failed in {module_dir}: {reason}")
+        return None
+    osvs = {m['osv']['id']: m['osv'] for m in messages if 'osv' in m}
+    return osvs, [m['finding'] for m in messages if 'finding' in m]
+
+
+def vulnerabilities(module_dir, options):
+    """One entry per vulnerability and call site, at the most precise level govulncheck reached."""
+    result = run_govulncheck(module_dir, options)
+    if result is None:
+        return None
+    osvs, findings = result
+    levels = list(SCAN_LEVELS)
+    # govulncheck reports a vulnerability at every level it reaches; keep the most precise one
+    precision = {}
+    for finding in findings:
+        rank = levels.index(finding_level(finding.get('trace') or []))
+        precision[finding['osv']] = min(precision.get(finding['osv'], rank), rank)
+    entries, seen = [], set()
+    for finding in findings:
+        trace = finding.get('trace') or []
+        level = finding_level(trace)
+        if levels.index(level) != precision[finding['osv']]:
+            continue
+        vulnerable = trace[0] if trace else {}
+        caller = next((f for f in reversed(trace) if f.get('position')), None)
+        position = caller['position'] if caller else {}
+        filename = position.get('filename')
+        if filename and not Path(filename).is_absolute():
+            filename = str(Path(module_dir) / filename)
+        key = (finding['osv'], filename, position.get('line'))
+        if key in seen:
+            continue
+        seen.add(key)
+        osv = osvs.get(finding['osv'], {})
+        entries.append({
+            'id': finding['osv'],
+            'aliases': [a for a in osv.get('aliases', []) if a.startswith('CVE-')],
+            'summary': osv.get('summary') or osv.get('details', '').split('\n')[0],
+            'module': vulnerable.get('module', ''),
+            'version': vulnerable.get('version', ''),
+            'fixed': finding.get('fixed_version', ''),
+            'symbol': '.'.join(p for p in (vulnerable.get('package'), vulnerable.get('receiver'),
+                                           vulnerable.get('function')) if p),
+            'level': level,
+            'file': filename,
+            'line': position.get('line') or 1,
+            'cvss': next((s['score'] for s in osv.get('severity', []) if s.get('type') == 'CVSS_V3'), None),
+        })
+    return entries
+
+
+def vuln_message(entry):
+    ids = ', '.join([entry['id']] + entry['aliases'])
+    message = f"{ids}: {entry['summary']} in {entry['module']}@{entry['version']}"
+    if entry['level'] == 'symbol':
+        message += f"; vulnerable {entry['symbol']} is reachable from here"
+    elif entry['level'] == 'package':
+        message += f"; package {entry['symbol']} is imported"
+    return message + (f". Upgrade to {entry['fixed']}" if entry['fixed'] else ". No fixed version yet")
@@ -0,0 +1,73 @@
+import hashlib
+import threading
+from pathlib import Path
+
+from metrics import METRICS
+
+
+def file_signature(paths):
+    """(path, mtime, size) of the files, which changes when one of them is edited, added or removed."""
+    signature = []
+    for path in sorted(Path(p) for p in paths):
+        try:
+            stat = path.stat()
+        except OSError:
+            continue
+        signature.append((str(path), stat.st_mtime_ns, stat.st_size))
+    return tuple(signature)
+
+
+def content_hash(path):
+    try:
+        return hashlib.sha256(Path(path).read_bytes()).hexdigest()
+    except OSError:
+        return None
+
+
+class WarmCache:
+    """What `serve --daemon` keeps in memory between scans: Semgrep's output per file and rule file, the rule
+    catalog, the Go packages read for deprecations and the go command's environment. Entries are checked
+    against the signature of the files they were read from, so an edit is picked up by the next scan without
+    re-reading or hashing the files that didn't change."""
+
+    def __init__(self, max_entries=50000):
+        self.lock = threading.Lock()
+        self.max_entries = max_entries  # Semgrep outputs kept, the oldest go first
+        self.semgrep = {}       # (file or document, rule file) -> (signature, Semgrep JSON output)
+        self.catalogs = {}      # (rules dir, custom rule files) -> (signature, rules)
+        self.packages = {}      # Go package folder -> (signature, (name, notice, symbols)), see deprecated.py
+        self.environments = {}  # go command -> (GOROOT, GOMODCACHE)
+
+    def semgrep_output(self, file_path, rule_file, run, origin=None):
+        """Semgrep's output for the file and rule file, from memory while neither changed, else run(). A
+        temporary copy of an editor buffer is kept under the document it holds (origin) and its content, as
+        every LSP request writes a new copy: one entry per open document, reused until the buffer changes."""
+        if origin is None:
+            key, signature = (str(file_path), str(rule_file)), file_signature([file_path, rule_file])
+        else:
+            key, signature = (str(origin), str(rule_file)), (content_hash(file_path), file_signature([rule_file]))
+        with self.lock:
+            entry = self.semgrep.get(key)
+        if entry and entry[0] == signature:
+            METRICS.inc('auto_review_cache_requests_total', result='memory')
+            return entry[1]
+        output = run()
+        if output:
+            with self.lock:
+                self.semgrep.pop(key, None)
+                while len(self.semgrep) >= self.max_entries:
+                    self.semgrep.pop(next(iter(self.semgrep)))
+                self.semgrep[key] = (signature, output)
+        return output
+
+    def catalog(self, rules_dir, custom, load):
+        """The rules of the rules folder and custom rule files, reloaded when one of them changes."""
+        files = list(Path(rules_dir).glob('*.yml')) + [Path(f) for f in custom]
+        key, signature = (str(rules_dir), tuple(custom)), file_signature(files)
+        with self.lock:
+            entry = self.catalogs.get(key)
+        if not entry or entry[0] != signature:
+            entry = (signature, load(rules_dir, custom))
+            with self.lock:
+                self.catalogs[key] = entry
+        return entry[1]